@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/agent"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	// Load configuration
+	cfg, err := agent.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid agent configuration: %v", err)
+	}
+
+	// Stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("🚀 Espaze node agent reporting to %s\n", cfg.ServerURL)
+	log.Printf("💓 Heartbeat every %s, resources every %s\n", cfg.HeartbeatInterval, cfg.ResourceInterval)
+
+	if err := agent.New(cfg).Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("Agent stopped: %v", err)
+	}
+	log.Println("Agent stopped")
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,8 +14,10 @@ import (
 	"github.com/espazeindia/espazeNodeDeployer/internal/config"
 	"github.com/espazeindia/espazeNodeDeployer/internal/github"
 	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/registry"
 	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
 	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/encryption"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -57,23 +60,95 @@ func main() {
 		log.Fatalf("Failed to initialize Kubernetes client: %v", err)
 	}
 	log.Println("✅ Connected to Kubernetes cluster successfully")
+	k8sClient.SetHelmBinary(cfg.HelmBinary)
+	k8sClient.SetKubectlBinary(cfg.KubectlBinary)
 
 	// Initialize GitHub client
 	githubClient := github.NewClient(cfg.GitHubClientID, cfg.GitHubClientSecret)
 
+	// Initialize container registry client
+	registryClient := registry.NewClient()
+	registryClient.SetInsecureRegistries(strings.Split(cfg.InsecureRegistries, ","))
+
+	// Initialize the cipher for secrets stored in the database
+	cipher, err := encryption.NewCipher(cfg.EncryptionKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize encryption: %v", err)
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	deploymentRepo := repository.NewDeploymentRepository(db)
 	githubTokenRepo := repository.NewGitHubTokenRepository(db)
 	nodeRepo := repository.NewNodeRepository(db)
+	addonRepo := repository.NewAddonRepository(db)
+	registryCredentialRepo := repository.NewRegistryCredentialRepository(db)
+	nodeCredentialRepo := repository.NewNodeCredentialRepository(db)
+	nodeEventRepo := repository.NewNodeEventRepository(db)
+	bootstrapTokenRepo := repository.NewBootstrapTokenRepository(db)
+	nodeOperationRepo := repository.NewNodeOperationRepository(db)
+	fleetRepo := repository.NewFleetRepository(db)
+	evacuationRepo := repository.NewEvacuationRepository(db)
+
+	// Initialize the per-node cluster clients; nodes without credentials use KUBECONFIG
+	clusters := k8s.NewClusterManager(k8sClient, usecase.NodeCredentialLoader(nodeRepo, nodeCredentialRepo, cipher))
+
+	// Grace periods before a silent node is marked degraded, then offline
+	nodeDegradedAfter, err := time.ParseDuration(cfg.NodeDegradedAfter)
+	if err != nil {
+		log.Fatalf("Invalid NODE_DEGRADED_AFTER: %v", err)
+	}
+	nodeOfflineAfter, err := time.ParseDuration(cfg.NodeOfflineAfter)
+	if err != nil || nodeOfflineAfter < nodeDegradedAfter {
+		log.Fatalf("Invalid NODE_OFFLINE_AFTER: must be a duration no shorter than NODE_DEGRADED_AFTER")
+	}
 
 	// Initialize use cases
 	authUseCase := usecase.NewAuthUseCase(userRepo, cfg.JWTSecret)
-	nodeUseCase := usecase.NewNodeUseCase(nodeRepo)
-	deploymentUseCase := usecase.NewDeploymentUseCase(deploymentRepo, k8sClient, githubClient, githubTokenRepo)
+	nodeMonitorUseCase := usecase.NewNodeMonitorUseCase(nodeRepo, deploymentRepo, nodeEventRepo, nodeDegradedAfter, nodeOfflineAfter)
+	nodeUseCase := usecase.NewNodeUseCase(nodeRepo, nodeCredentialRepo, bootstrapTokenRepo, deploymentRepo, addonRepo, clusters, cipher, nodeMonitorUseCase, cfg.JWTSecret)
+	nodeOperationUseCase := usecase.NewNodeOperationUseCase(nodeOperationRepo)
+	placementUseCase := usecase.NewPlacementUseCase(nodeRepo, deploymentRepo)
+	deploymentUseCase := usecase.NewDeploymentUseCase(deploymentRepo, clusters, githubClient, githubTokenRepo, registryClient, registryCredentialRepo, cipher, nodeRepo, nodeOperationUseCase, placementUseCase)
+	fleetUseCase := usecase.NewFleetUseCase(fleetRepo, nodeRepo, deploymentRepo, deploymentUseCase, placementUseCase, clusters)
+	evacuationUseCase := usecase.NewEvacuationUseCase(evacuationRepo, nodeRepo, deploymentRepo, fleetRepo, githubTokenRepo, deploymentUseCase, clusters)
 	githubUseCase := usecase.NewGitHubUseCase(githubClient, githubTokenRepo)
 	k8sUseCase := usecase.NewK8sUseCase(k8sClient)
 	metricsUseCase := usecase.NewMetricsUseCase(k8sClient)
+	addonUseCase := usecase.NewAddonUseCase(addonRepo, deploymentRepo, clusters)
+	reconcileUseCase := usecase.NewReconcileUseCase(deploymentRepo, nodeRepo, clusters)
+	gcUseCase := usecase.NewGCUseCase(deploymentRepo, addonRepo, nodeRepo, clusters)
+	importUseCase := usecase.NewImportUseCase(deploymentRepo, clusters)
+	registryCredentialUseCase := usecase.NewRegistryCredentialUseCase(registryCredentialRepo, deploymentRepo, clusters, registryClient, cipher)
+
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	if interval, err := time.ParseDuration(cfg.ReconcileInterval); err == nil && interval > 0 {
+		reconcileUseCase.Start(workerCtx, interval)
+		log.Printf("🔁 Drift reconciler running every %s\n", interval)
+	}
+
+	if interval, err := time.ParseDuration(cfg.GCInterval); err == nil && interval > 0 {
+		gcUseCase.Start(workerCtx, interval, cfg.GCDryRun)
+		log.Printf("🧹 Garbage collector running every %s (dry run: %t)\n", interval, cfg.GCDryRun)
+	}
+
+	if interval, err := time.ParseDuration(cfg.ImageWatchInterval); err == nil && interval > 0 {
+		deploymentUseCase.StartImageWatcher(workerCtx, interval)
+		log.Printf("🏷️  Image tag watcher running every %s\n", interval)
+	}
+
+	if interval, err := time.ParseDuration(cfg.NodeMonitorInterval); err == nil && interval > 0 {
+		nodeMonitorUseCase.Start(workerCtx, interval)
+		log.Printf("🩺 Node liveness monitor running every %s (degraded after %s, offline after %s)\n", interval, nodeDegradedAfter, nodeOfflineAfter)
+	}
+
+	if interval, err := time.ParseDuration(cfg.NodeResourceInterval); err == nil && interval > 0 {
+		nodeUseCase.StartResourceRefresh(workerCtx, interval)
+		log.Printf("📊 Node resource refresh running every %s\n", interval)
+	}
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
@@ -109,11 +184,19 @@ func main() {
 
 	// Initialize handlers
 	api.SetupAuthRoutes(apiV1, authUseCase, cfg.JWTSecret)
-	api.SetupNodeRoutes(apiV1, nodeUseCase, cfg.JWTSecret)
+	api.SetupNodeRoutes(apiV1, nodeUseCase, evacuationUseCase, cfg.JWTSecret)
+	api.SetupNodeOperationRoutes(apiV1, nodeUseCase, nodeOperationUseCase, cfg.JWTSecret)
 	api.SetupGitHubRoutes(apiV1, githubUseCase, cfg.JWTSecret)
 	api.SetupDeploymentRoutes(apiV1, deploymentUseCase, cfg.JWTSecret)
+	api.SetupFleetRoutes(apiV1, fleetUseCase, cfg.JWTSecret)
 	api.SetupK8sRoutes(apiV1, k8sUseCase, cfg.JWTSecret)
 	api.SetupMetricsRoutes(apiV1, metricsUseCase, cfg.JWTSecret)
+	api.SetupAddonRoutes(apiV1, addonUseCase, cfg.JWTSecret)
+	api.SetupReconcileRoutes(apiV1, reconcileUseCase, cfg.JWTSecret)
+	api.SetupGCRoutes(apiV1, gcUseCase, cfg.JWTSecret)
+	api.SetupImportRoutes(apiV1, importUseCase, cfg.JWTSecret)
+	api.SetupRegistryCredentialRoutes(apiV1, registryCredentialUseCase, cfg.JWTSecret)
+	api.SetupWebhookRoutes(apiV1, deploymentUseCase, cfg.GitHubWebhookSecret)
 
 	// 404 handler
 	app.Use(func(c *fiber.Ctx) error {
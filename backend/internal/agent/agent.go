@@ -0,0 +1,222 @@
+// Package agent runs on each node, enrolls it with the deployer using a bootstrap token
+// and keeps its record current with heartbeats and resource reports. Nodes the server
+// cannot reach also pull their deployment work through it.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxRetryDelay caps the backoff between failed registrations
+const maxRetryDelay = time.Minute
+
+type Agent struct {
+	cfg       *Config
+	client    *Client
+	collector *Collector
+	executor  *Executor
+
+	mu         sync.RWMutex // Guards credential, which the operation loop reads
+	credential *Credential
+}
+
+func New(cfg *Config) *Agent {
+	collector := NewCollector(cfg)
+	return &Agent{
+		cfg:       cfg,
+		client:    NewClient(cfg),
+		collector: collector,
+		executor:  NewExecutor(collector),
+	}
+}
+
+// Run registers the node and then heartbeats and reports resources on their intervals
+// until ctx is cancelled or the node is revoked
+func (a *Agent) Run(ctx context.Context) error {
+	credential, err := loadCredential(a.cfg.CredentialFile)
+	if err != nil {
+		return fmt.Errorf("failed to read credential from %s: %w", a.cfg.CredentialFile, err)
+	}
+	a.setCredential(credential)
+
+	if err := a.register(ctx); err != nil {
+		return err
+	}
+
+	if a.cfg.PullOperations {
+		go a.pullOperations(ctx)
+	}
+
+	heartbeat := time.NewTicker(a.cfg.HeartbeatInterval)
+	defer heartbeat.Stop()
+	resources := time.NewTicker(a.cfg.ResourceInterval)
+	defer resources.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			err = a.client.Heartbeat(ctx, a.currentCredential())
+		case <-resources.C:
+			usage := a.collector.Resources(ctx)
+			err = a.client.ReportResources(ctx, a.currentCredential(), &usage)
+		}
+
+		switch {
+		case errors.Is(err, ErrNodeRevoked), errors.Is(err, ErrCredentialRejected):
+			// Another enrollment took over the node, or an admin shut it out
+			return err
+		case errors.Is(err, ErrNodeNotFound):
+			// A deleted node enrolls again and carries on under its new ID
+			log.Printf("Node %s is no longer registered, enrolling again", a.currentCredential().NodeID)
+			a.forgetCredential()
+			err = a.register(ctx)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Agent: %v", err)
+		}
+	}
+}
+
+// register retries with backoff until the server accepts the node or ctx is cancelled.
+// Errors no retry can fix are returned right away.
+func (a *Agent) register(ctx context.Context) error {
+	delay := 5 * time.Second
+	for {
+		err := a.registerOnce(ctx)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrNodeRevoked) || errors.Is(err, ErrBootstrapTokenRejected) || errors.Is(err, ErrAlreadyEnrolled) || errors.Is(err, errNoBootstrapToken) || errors.Is(err, errNoMacAddress) {
+			return err
+		}
+		log.Printf("Registration failed, retrying in %s: %v", delay, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+var (
+	errNoBootstrapToken = errors.New("node has no valid credential; set ESPAZE_BOOTSTRAP_TOKEN to enroll it")
+	errNoMacAddress     = errors.New("cannot identify the node")
+)
+
+// registerOnce refreshes the node with its stored credential, or enrolls it with the
+// bootstrap token when there is no usable credential
+func (a *Agent) registerOnce(ctx context.Context) error {
+	req, err := a.collector.Registration(ctx)
+	if err != nil {
+		// Without a MAC address the node cannot be identified; retrying will not help
+		return fmt.Errorf("%w: %v", errNoMacAddress, err)
+	}
+
+	if credential := a.currentCredential(); credential != nil {
+		node, err := a.client.Register(ctx, credential, req)
+		switch {
+		case err == nil:
+			log.Printf("✅ Registered node %s (%s)", node.NodeName, credential.NodeID)
+			return nil
+		case errors.Is(err, ErrNodeNotFound), errors.Is(err, ErrCredentialRejected):
+			log.Printf("Stored credential is no longer accepted, enrolling again: %v", err)
+			a.forgetCredential()
+		default:
+			return err
+		}
+	}
+
+	if a.cfg.BootstrapToken == "" {
+		return errNoBootstrapToken
+	}
+	enrollment, err := a.client.Enroll(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	credential := &Credential{NodeID: enrollment.Node.ID.Hex(), Token: enrollment.Credential}
+	a.setCredential(credential)
+	if err := saveCredential(a.cfg.CredentialFile, credential); err != nil {
+		// The node keeps running, but the server refuses to enroll a known node again:
+		// after a restart an admin must delete the node before it can enroll
+		log.Printf("Failed to store credential in %s: %v", a.cfg.CredentialFile, err)
+	}
+	log.Printf("✅ Enrolled node %s (%s)", enrollment.Node.NodeName, credential.NodeID)
+	return nil
+}
+
+// pullOperations long-polls the server for work queued for the node, runs it and
+// reports the result until ctx is cancelled. Credential problems are left to the
+// heartbeat loop, which enrolls again or stops the agent.
+func (a *Agent) pullOperations(ctx context.Context) {
+	log.Printf("📥 Pulling operations from %s", a.cfg.ServerURL)
+
+	for ctx.Err() == nil {
+		credential := a.currentCredential()
+		if credential == nil {
+			sleep(ctx, 5*time.Second)
+			continue
+		}
+
+		operation, err := a.client.NextOperation(ctx, credential, a.cfg.OperationPollWait)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Polling operations failed: %v", err)
+				sleep(ctx, 5*time.Second)
+			}
+			continue
+		}
+		if operation == nil {
+			continue
+		}
+
+		log.Printf("Running %s operation %s", operation.Type, operation.ID.Hex())
+		result := a.executor.Run(ctx, operation)
+		if !result.Success {
+			log.Printf("%s operation %s failed: %s", operation.Type, operation.ID.Hex(), result.Error)
+		}
+		// An unreported operation is handed out again on the next poll
+		if err := a.client.ReportOperation(ctx, credential, operation.ID.Hex(), result); err != nil {
+			log.Printf("Reporting operation %s failed: %v", operation.ID.Hex(), err)
+		}
+	}
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+func (a *Agent) currentCredential() *Credential {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.credential
+}
+
+func (a *Agent) setCredential(credential *Credential) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.credential = credential
+}
+
+func (a *Agent) forgetCredential() {
+	a.setCredential(nil)
+	if err := removeCredential(a.cfg.CredentialFile); err != nil {
+		log.Printf("Failed to remove credential %s: %v", a.cfg.CredentialFile, err)
+	}
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+)
+
+var (
+	// ErrNodeNotFound is returned once the server no longer knows the node, e.g. after
+	// an admin deleted it
+	ErrNodeNotFound = errors.New("node is not registered")
+	// ErrNodeRevoked is returned once an admin revoked the node
+	ErrNodeRevoked = errors.New("node has been revoked")
+	// ErrCredentialRejected is returned when the node credential is no longer current,
+	// e.g. because the node enrolled again elsewhere
+	ErrCredentialRejected = errors.New("node credential was rejected")
+	// ErrBootstrapTokenRejected is returned when enrollment is refused
+	ErrBootstrapTokenRejected = errors.New("bootstrap token was rejected")
+	// ErrAlreadyEnrolled is returned when the server already knows the node under a
+	// credential the agent no longer has
+	ErrAlreadyEnrolled = errors.New("node is already enrolled; an admin must delete the node before it can enroll again")
+)
+
+// Client talks to the deployer's node API
+type Client struct {
+	cfg        *Config
+	httpClient *http.Client
+}
+
+func NewClient(cfg *Config) *Client {
+	// Long polls for operations are held open by the server for up to the poll wait
+	timeout := 30 * time.Second
+	if cfg.PullOperations && cfg.OperationPollWait+15*time.Second > timeout {
+		timeout = cfg.OperationPollWait + 15*time.Second
+	}
+
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Enroll exchanges the bootstrap token for a node credential
+func (c *Client) Enroll(ctx context.Context, req *entities.NodeRegistrationRequest) (*entities.NodeEnrollment, error) {
+	var enrollment entities.NodeEnrollment
+	err := c.do(ctx, http.MethodPost, "/nodes/enroll", c.cfg.BootstrapToken, req, &enrollment)
+	if errors.Is(err, ErrCredentialRejected) {
+		return nil, ErrBootstrapTokenRejected
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// Register refreshes the node's record
+func (c *Client) Register(ctx context.Context, credential *Credential, req *entities.NodeRegistrationRequest) (*entities.Node, error) {
+	var node entities.Node
+	if err := c.do(ctx, http.MethodPost, "/nodes/register", credential.Token, req, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func (c *Client) Heartbeat(ctx context.Context, credential *Credential) error {
+	return c.do(ctx, http.MethodPost, "/nodes/"+credential.NodeID+"/heartbeat", credential.Token, nil, nil)
+}
+
+func (c *Client) ReportResources(ctx context.Context, credential *Credential, resources *entities.NodeResources) error {
+	return c.do(ctx, http.MethodPut, "/nodes/"+credential.NodeID+"/resources", credential.Token, resources, nil)
+}
+
+// NextOperation waits up to wait for work queued for the node. It returns nil if none
+// arrived in time.
+func (c *Client) NextOperation(ctx context.Context, credential *Credential, wait time.Duration) (*entities.NodeOperation, error) {
+	var operation entities.NodeOperation
+	path := "/nodes/" + credential.NodeID + "/operations/next?wait=" + wait.String()
+	if err := c.do(ctx, http.MethodGet, path, credential.Token, nil, &operation); err != nil {
+		return nil, err
+	}
+	if operation.ID.IsZero() {
+		return nil, nil
+	}
+	return &operation, nil
+}
+
+func (c *Client) ReportOperation(ctx context.Context, credential *Credential, operationID string, result *entities.NodeOperationResult) error {
+	return c.do(ctx, http.MethodPost, "/nodes/"+credential.NodeID+"/operations/"+operationID+"/result", credential.Token, result, nil)
+}
+
+// do sends a request to the API and maps the statuses the agent acts on to errors
+func (c *Client) do(ctx context.Context, method, path, token string, body, out interface{}) error {
+	status, err := c.send(ctx, method, path, token, body, out)
+	switch status {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: %v", ErrCredentialRejected, err)
+	case http.StatusForbidden:
+		return fmt.Errorf("%w: %v", ErrNodeRevoked, err)
+	case http.StatusNotFound:
+		return ErrNodeNotFound
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %v", ErrAlreadyEnrolled, err)
+	}
+	return err
+}
+
+func (c *Client) send(ctx context.Context, method, path, token string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.ServerURL+"/api/v1"+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return resp.StatusCode, fmt.Errorf("%s %s: %s", method, path, apiErr.Error)
+	}
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
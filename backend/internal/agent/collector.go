@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/hostinfo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Collector gathers what the server tracks about the node from the host and its cluster
+type Collector struct {
+	cfg *Config
+	cpu hostinfo.CPUSampler
+
+	mu        sync.Mutex // Guards k8sClient, which operations use alongside reports
+	k8sClient *k8s.Client
+}
+
+func NewCollector(cfg *Config) *Collector {
+	return &Collector{cfg: cfg}
+}
+
+// Registration describes the node for /nodes/register
+func (c *Collector) Registration(ctx context.Context) (*entities.NodeRegistrationRequest, error) {
+	macAddress, err := hostinfo.MacAddress()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MAC address: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	nodeName := c.cfg.NodeName
+	if nodeName == "" {
+		nodeName = hostname
+	}
+
+	privateIP, err := hostinfo.PrivateIP()
+	if err != nil {
+		log.Printf("Could not detect private IP: %v", err)
+	}
+
+	publicIP := ""
+	if c.cfg.PublicIPURL != "" {
+		ipCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		publicIP, err = hostinfo.PublicIP(ipCtx, c.cfg.PublicIPURL)
+		cancel()
+		if err != nil {
+			log.Printf("Could not detect public IP: %v", err)
+		}
+	}
+
+	kernelVersion, err := hostinfo.KernelVersion()
+	if err != nil {
+		log.Printf("Could not detect kernel version: %v", err)
+	}
+
+	return &entities.NodeRegistrationRequest{
+		NodeName:    nodeName,
+		MacAddress:  macAddress,
+		PublicIP:    publicIP,
+		PrivateIP:   privateIP,
+		Location:    c.cfg.Location,
+		ClusterInfo: c.ClusterInfo(ctx),
+		Metadata: entities.NodeMetadata{
+			OSType:        runtime.GOOS,
+			Architecture:  runtime.GOARCH,
+			Hostname:      hostname,
+			KernelVersion: kernelVersion,
+			Labels:        c.cfg.Labels,
+			Tags:          []string{},
+		},
+		Resources:  c.Resources(ctx),
+		AccessMode: c.accessMode(),
+	}, nil
+}
+
+// ClusterInfo reports the version and size of the node's cluster, or only the configured
+// name and provider while the cluster cannot be reached
+func (c *Collector) ClusterInfo(ctx context.Context) entities.ClusterInfo {
+	info := entities.ClusterInfo{
+		ClusterName: c.cfg.ClusterName,
+		Provider:    c.cfg.Provider,
+	}
+
+	client := c.cluster()
+	if client == nil {
+		return info
+	}
+	clusterInfo, err := client.GetClusterInfo(ctx)
+	if err != nil {
+		log.Printf("Could not read cluster info: %v", err)
+		return info
+	}
+
+	info.KubeVersion, _ = clusterInfo["version"].(string)
+	info.NodesCount, _ = clusterInfo["nodesCount"].(int)
+	info.NamespacesCount, _ = clusterInfo["namespacesCount"].(int)
+	return info
+}
+
+// Resources measures the host's CPU, memory and disk and the cluster's pods. Anything
+// that cannot be measured is reported as zero.
+func (c *Collector) Resources(ctx context.Context) entities.NodeResources {
+	resources := entities.NodeResources{
+		CPUCores: runtime.NumCPU(),
+	}
+
+	if usage, err := c.cpu.Usage(); err == nil {
+		resources.CPUUsage = usage
+	} else {
+		log.Printf("Could not measure CPU usage: %v", err)
+	}
+
+	if memory, err := hostinfo.Memory(); err == nil {
+		resources.MemoryTotal = memory.Total
+		resources.MemoryUsed = memory.Used
+		resources.MemoryUsage = memory.Usage
+	} else {
+		log.Printf("Could not measure memory: %v", err)
+	}
+
+	if disk, err := hostinfo.Disk(c.cfg.DiskPath); err == nil {
+		resources.DiskTotal = disk.Total
+		resources.DiskUsed = disk.Used
+		resources.DiskUsage = disk.Usage
+	} else {
+		log.Printf("Could not measure disk %s: %v", c.cfg.DiskPath, err)
+	}
+
+	if client := c.cluster(); client != nil {
+		if err := countPods(ctx, client, &resources); err != nil {
+			log.Printf("Could not count pods: %v", err)
+		}
+	}
+
+	return resources
+}
+
+// accessMode tells the server to queue work for the agent instead of reaching the
+// cluster itself. Without PULL_OPERATIONS the node keeps the mode it has.
+func (c *Collector) accessMode() entities.NodeAccessMode {
+	if c.cfg.PullOperations {
+		return entities.NodeAccessAgent
+	}
+	return ""
+}
+
+// cluster connects to the node's cluster on first use and again after a failure
+func (c *Collector) cluster() *k8s.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.k8sClient != nil {
+		return c.k8sClient
+	}
+
+	client, err := k8s.NewClient(c.cfg.KubeConfig)
+	if err != nil {
+		log.Printf("Kubernetes cluster not reachable: %v", err)
+		return nil
+	}
+	c.k8sClient = client
+	return client
+}
+
+// countPods counts running pods and the pod capacity the kubelets allow, and notes
+// disk pressure reported by a kubelet
+func countPods(ctx context.Context, client *k8s.Client, resources *entities.NodeResources) error {
+	pods, err := client.GetPods(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning {
+			resources.PodsRunning++
+		}
+	}
+
+	nodes, err := client.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes.Items {
+		resources.PodsCapacity += int(node.Status.Allocatable.Pods().Value())
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeDiskPressure && condition.Status == corev1.ConditionTrue {
+				resources.DiskPressure = true
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+)
+
+type Config struct {
+	// Server
+	ServerURL string
+
+	// Enrollment: a bootstrap token is exchanged for a node credential stored in
+	// CredentialFile; it is only needed until the node has a credential
+	BootstrapToken string
+	CredentialFile string
+
+	// Node
+	NodeName string
+	Location entities.Location
+	Labels   map[string]string
+
+	// Cluster
+	KubeConfig  string
+	ClusterName string
+	Provider    string
+
+	// Measurements
+	DiskPath    string
+	PublicIPURL string
+
+	// Intervals
+	HeartbeatInterval time.Duration
+	ResourceInterval  time.Duration
+
+	// Operations: nodes the server cannot reach pull their work from it instead
+	PullOperations    bool
+	OperationPollWait time.Duration
+}
+
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		ServerURL:      strings.TrimSuffix(getEnv("ESPAZE_SERVER_URL", "http://localhost:8080"), "/"),
+		BootstrapToken: getEnv("ESPAZE_BOOTSTRAP_TOKEN", ""),
+		CredentialFile: getEnv("ESPAZE_CREDENTIAL_FILE", defaultCredentialFile()),
+		NodeName:       getEnv("NODE_NAME", ""),
+		KubeConfig:     getEnv("KUBECONFIG", os.Getenv("HOME")+"/.kube/config"),
+		ClusterName:    getEnv("CLUSTER_NAME", ""),
+		Provider:       getEnv("CLUSTER_PROVIDER", ""),
+		DiskPath:       getEnv("DISK_PATH", "/"),
+		PublicIPURL:    getEnv("PUBLIC_IP_URL", "https://api.ipify.org"),
+		PullOperations: getEnv("PULL_OPERATIONS", "false") == "true",
+		Location: entities.Location{
+			City:     getEnv("NODE_CITY", ""),
+			Country:  getEnv("NODE_COUNTRY", ""),
+			Region:   getEnv("NODE_REGION", ""),
+			Timezone: getEnv("NODE_TIMEZONE", time.Local.String()),
+		},
+	}
+
+	var err error
+	if cfg.Location.Latitude, err = getFloat("NODE_LATITUDE"); err != nil {
+		return nil, err
+	}
+	if cfg.Location.Longitude, err = getFloat("NODE_LONGITUDE"); err != nil {
+		return nil, err
+	}
+	if cfg.Labels, err = parseLabels(getEnv("NODE_LABELS", "")); err != nil {
+		return nil, err
+	}
+	if cfg.HeartbeatInterval, err = getDuration("HEARTBEAT_INTERVAL", "30s"); err != nil {
+		return nil, err
+	}
+	if cfg.ResourceInterval, err = getDuration("RESOURCE_INTERVAL", "1m"); err != nil {
+		return nil, err
+	}
+	if cfg.OperationPollWait, err = getDuration("OPERATION_POLL_WAIT", "25s"); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// defaultCredentialFile keeps the credential in the user's config directory
+func defaultCredentialFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".espaze-agent-credential.json"
+	}
+	return filepath.Join(dir, "espaze-agent", "credential.json")
+}
+
+// parseLabels reads labels written as key=value,key=value
+func parseLabels(value string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("NODE_LABELS: invalid label %q, expected key=value", pair)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return labels, nil
+}
+
+func getFloat(key string) (float64, error) {
+	value := getEnv(key, "0")
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return f, nil
+}
+
+func getDuration(key, defaultValue string) (time.Duration, error) {
+	d, err := time.ParseDuration(getEnv(key, defaultValue))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", key)
+	}
+	return d, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return strings.TrimSpace(value)
+}
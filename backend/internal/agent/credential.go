@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Credential is what the agent keeps from its enrollment
+type Credential struct {
+	NodeID string `json:"nodeId"`
+	Token  string `json:"token"`
+}
+
+// loadCredential reads a stored credential; it returns nil if none was stored yet
+func loadCredential(path string) (*Credential, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var credential Credential
+	if err := json.Unmarshal(data, &credential); err != nil {
+		return nil, err
+	}
+	if credential.NodeID == "" || credential.Token == "" {
+		return nil, nil
+	}
+	return &credential, nil
+}
+
+// saveCredential stores the credential readable by the agent's user only
+func saveCredential(path string, credential *Credential) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func removeCredential(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+)
+
+// deletionTimeout bounds how long a delete operation waits for the objects to disappear
+const deletionTimeout = 5 * time.Minute
+
+// Executor runs operations queued by the server against the node's cluster
+type Executor struct {
+	collector *Collector
+}
+
+func NewExecutor(collector *Collector) *Executor {
+	return &Executor{collector: collector}
+}
+
+// Run executes an operation and describes the outcome for the server
+func (e *Executor) Run(ctx context.Context, operation *entities.NodeOperation) *entities.NodeOperationResult {
+	client := e.collector.cluster()
+	if client == nil {
+		return &entities.NodeOperationResult{Error: "Kubernetes cluster not reachable"}
+	}
+
+	result, err := e.run(ctx, client, operation)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+func (e *Executor) run(ctx context.Context, client *k8s.Client, operation *entities.NodeOperation) (*entities.NodeOperationResult, error) {
+	result := &entities.NodeOperationResult{}
+
+	switch operation.Type {
+	case entities.NodeOperationApply:
+		objects, err := k8s.DecodeManifests("operation "+operation.ID.Hex(), operation.Manifests)
+		if err != nil {
+			return result, err
+		}
+		// The objects of the previous rollout let the apply prune what was dropped
+		deployment := &entities.Deployment{
+			ID:             operation.DeploymentID,
+			Name:           operation.Name,
+			Namespace:      operation.Namespace,
+			AppliedObjects: operation.Objects,
+		}
+		result.Applied, err = client.ApplyManifests(ctx, deployment, objects)
+		return result, err
+
+	case entities.NodeOperationScale:
+		return result, client.ScaleDeployment(ctx, operation.Namespace, operation.Name, operation.Replicas)
+
+	case entities.NodeOperationRestart:
+		return result, client.RestartDeployment(ctx, operation.Namespace, operation.Name)
+
+	case entities.NodeOperationDelete:
+		deployment := &entities.Deployment{
+			ID:        operation.DeploymentID,
+			Name:      operation.Name,
+			Namespace: operation.Namespace,
+		}
+		deleted, err := client.DeleteManifests(ctx, deployment, operation.Objects)
+		if err != nil {
+			return result, err
+		}
+		return result, client.WaitForManifestsDeletion(ctx, deleted, deletionTimeout)
+
+	case entities.NodeOperationLogs:
+		logs, err := client.ApplicationLogs(ctx, operation.Namespace, operation.Selector, operation.TailLines)
+		result.Output = logs
+		return result, err
+	}
+
+	return result, fmt.Errorf("unsupported operation type %q", operation.Type)
+}
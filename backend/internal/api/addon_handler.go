@@ -0,0 +1,122 @@
+package api
+
+import (
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func SetupAddonRoutes(router fiber.Router, addonUC usecase.AddonUseCase, jwtSecret string) {
+	addons := router.Group("/addons", AuthMiddleware(jwtSecret))
+
+	addons.Get("/catalog", func(c *fiber.Ctx) error {
+		return c.JSON(addonUC.GetCatalog())
+	})
+
+	addons.Post("/", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		var req entities.AddonRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		nodeID, err := primitive.ObjectIDFromHex(c.Query("nodeId"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Node ID is required"})
+		}
+
+		addon, err := addonUC.CreateAddon(c.Context(), userObjID, nodeID, &req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(201).JSON(addon)
+	})
+
+	addons.Get("/", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		addons, err := addonUC.GetAddonsByUser(c.Context(), userObjID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(addons)
+	})
+
+	addons.Get("/:id", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid add-on ID"})
+		}
+
+		addon, err := addonUC.GetAddon(c.Context(), id)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(addon)
+	})
+
+	addons.Post("/:id/bind", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid add-on ID"})
+		}
+
+		var req entities.AddonBindRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		if err := addonUC.BindAddon(c.Context(), id, &req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Add-on bound successfully"})
+	})
+
+	addons.Post("/:id/unbind", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid add-on ID"})
+		}
+
+		var req struct {
+			DeploymentID string `json:"deploymentId"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		deploymentID, err := primitive.ObjectIDFromHex(req.DeploymentID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		if err := addonUC.UnbindAddon(c.Context(), id, deploymentID); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Add-on unbound successfully"})
+	})
+
+	addons.Delete("/:id", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid add-on ID"})
+		}
+
+		retainData := c.QueryBool("retainData", true)
+
+		if err := addonUC.DeleteAddon(c.Context(), id, retainData); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Add-on deleted successfully"})
+	})
+}
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
 	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
 	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
@@ -20,21 +23,34 @@ func SetupDeploymentRoutes(router fiber.Router, deploymentUC usecase.DeploymentU
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 		}
 
-		// Get node ID from request or use current node
-		nodeID, err := primitive.ObjectIDFromHex(c.Query("nodeId"))
-		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "Node ID is required"})
+		// Without a node ID the scheduler places the deployment
+		var nodeID primitive.ObjectID
+		if nodeIDStr := c.Query("nodeId"); nodeIDStr != "" {
+			id, err := primitive.ObjectIDFromHex(nodeIDStr)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+			}
+			nodeID = id
+		}
+
+		// Dry run: validate and render without storing anything
+		if c.QueryBool("dryRun") {
+			preview, err := deploymentUC.PreviewDeployment(c.Context(), userObjID, nodeID, &req, c.Get("X-GitHub-Token"))
+			if err != nil {
+				return deploymentError(c, err)
+			}
+			return c.JSON(preview)
 		}
 
-		// Get GitHub token from header
+		// Get GitHub token from header; prebuilt images do not need one
 		githubToken := c.Get("X-GitHub-Token")
-		if githubToken == "" {
+		if githubToken == "" && req.Source.Type != entities.SourceTypeImage {
 			return c.Status(400).JSON(fiber.Map{"error": "GitHub token is required"})
 		}
 
 		deployment, err := deploymentUC.CreateDeployment(c.Context(), userObjID, nodeID, &req, githubToken)
 		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			return deploymentError(c, err)
 		}
 
 		return c.Status(201).JSON(deployment)
@@ -80,6 +96,26 @@ func SetupDeploymentRoutes(router fiber.Router, deploymentUC usecase.DeploymentU
 		return c.JSON(deployment)
 	})
 
+	deployments.Get("/:id/manifests", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		format := entities.ManifestFormat(c.Query("format", string(entities.ManifestFormatYAML)))
+		export, err := deploymentUC.ExportManifests(c.Context(), id, format)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		// Plain YAML is shown inline, chart and kustomize archives are downloaded
+		if export.Format != entities.ManifestFormatYAML {
+			c.Attachment(export.FileName)
+		}
+		c.Set(fiber.HeaderContentType, export.ContentType)
+		return c.Send(export.Content)
+	})
+
 	deployments.Put("/:id", func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
@@ -91,6 +127,14 @@ func SetupDeploymentRoutes(router fiber.Router, deploymentUC usecase.DeploymentU
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 		}
 
+		if c.QueryBool("dryRun") {
+			preview, err := deploymentUC.PreviewUpdate(c.Context(), id, &req)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.JSON(preview)
+		}
+
 		if err := deploymentUC.UpdateDeployment(c.Context(), id, &req); err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -106,11 +150,107 @@ func SetupDeploymentRoutes(router fiber.Router, deploymentUC usecase.DeploymentU
 
 		// Note: In production, pass the correct k8s client based on node
 		// For now, we'll skip this implementation detail
-		if err := deploymentUC.DeleteDeployment(c.Context(), id, nil); err != nil {
+		if err := deploymentUC.DeleteDeployment(c.Context(), id); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(202).JSON(fiber.Map{"message": "Deployment deletion started"})
+	})
+
+	deployments.Post("/:id/redeploy", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		// Required for every source except prebuilt images, which the use case checks
+		deployment, err := deploymentUC.RedeployDeployment(c.Context(), id, c.Get("X-GitHub-Token"))
+		if err != nil {
+			return deploymentError(c, err)
+		}
+
+		return c.Status(202).JSON(deployment)
+	})
+
+	deployments.Post("/:id/rollback", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		var req entities.HelmRollbackRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+			}
+		}
+
+		if err := deploymentUC.RollbackDeployment(c.Context(), id, req.Revision); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(202).JSON(fiber.Map{"message": "Deployment rollback started"})
+	})
+
+	deployments.Get("/:id/release", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		status, err := deploymentUC.GetReleaseStatus(c.Context(), id)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(status)
+	})
+
+	deployments.Get("/:id/objects", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		status, err := deploymentUC.GetManifestStatus(c.Context(), id)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(status)
+	})
+
+	deployments.Get("/:id/dockerfile", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		dockerfile, err := deploymentUC.GetDockerfile(c.Context(), id)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(dockerfile)
+	})
+
+	deployments.Put("/:id/dockerfile", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		var req entities.DockerfileOverrideRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		dockerfile, err := deploymentUC.SetDockerfile(c.Context(), id, req.Content)
+		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		return c.JSON(fiber.Map{"message": "Deployment deleted successfully"})
+		return c.JSON(dockerfile)
 	})
 
 	deployments.Post("/:id/restart", func(c *fiber.Ctx) error {
@@ -119,7 +259,7 @@ func SetupDeploymentRoutes(router fiber.Router, deploymentUC usecase.DeploymentU
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
 		}
 
-		if err := deploymentUC.RestartDeployment(c.Context(), id, nil); err != nil {
+		if err := deploymentUC.RestartDeployment(c.Context(), id); err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
 
@@ -139,13 +279,31 @@ func SetupDeploymentRoutes(router fiber.Router, deploymentUC usecase.DeploymentU
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 		}
 
-		if err := deploymentUC.ScaleDeployment(c.Context(), id, req.Replicas, nil); err != nil {
+		if err := deploymentUC.ScaleDeployment(c.Context(), id, req.Replicas); err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
 
 		return c.JSON(fiber.Map{"message": "Deployment scaled successfully"})
 	})
 
+	deployments.Get("/:id/logs", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+		tailLines, _ := strconv.ParseInt(c.Query("tail", "100"), 10, 64)
+
+		logs, err := deploymentUC.GetDeploymentLogs(c.Context(), id, tailLines)
+		if err != nil {
+			if errors.Is(err, usecase.ErrOperationTimeout) {
+				return c.Status(504).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"logs": logs})
+	})
+
 	deployments.Get("/stats", func(c *fiber.Ctx) error {
 		var nodeID *primitive.ObjectID
 		if nodeIDStr := c.Query("nodeId"); nodeIDStr != "" {
@@ -164,3 +322,27 @@ func SetupDeploymentRoutes(router fiber.Router, deploymentUC usecase.DeploymentU
 	})
 }
 
+// deploymentError reports a failed create or redeploy, listing espaze.yaml problems separately
+func deploymentError(c *fiber.Ctx, err error) error {
+	var manifestErr *usecase.AppManifestError
+	if errors.As(err, &manifestErr) {
+		return c.Status(400).JSON(fiber.Map{
+			"error":    err.Error(),
+			"problems": manifestErr.Problems,
+		})
+	}
+	var placementErr *usecase.PlacementError
+	if errors.As(err, &placementErr) {
+		return c.Status(409).JSON(fiber.Map{
+			"error":    err.Error(),
+			"rejected": placementErr.Rejected,
+		})
+	}
+	if errors.Is(err, usecase.ErrNodeInMaintenance) {
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, usecase.ErrNodeNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func SetupFleetRoutes(router fiber.Router, fleetUC usecase.FleetUseCase, jwtSecret string) {
+	fleets := router.Group("/fleets", AuthMiddleware(jwtSecret))
+
+	fleets.Post("/", func(c *fiber.Ctx) error {
+		userObjID, _ := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+
+		var req entities.FleetRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		// Prebuilt images do not need a GitHub token
+		githubToken := c.Get("X-GitHub-Token")
+		if githubToken == "" && req.Spec.Source.Type != entities.SourceTypeImage {
+			return c.Status(400).JSON(fiber.Map{"error": "GitHub token is required"})
+		}
+
+		fleet, err := fleetUC.CreateFleet(c.Context(), userObjID, &req, githubToken)
+		if err != nil {
+			return deploymentError(c, err)
+		}
+
+		return c.Status(201).JSON(fleet)
+	})
+
+	fleets.Get("/", func(c *fiber.Ctx) error {
+		userObjID, _ := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+
+		fleets, err := fleetUC.GetFleetsByUser(c.Context(), userObjID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fleets)
+	})
+
+	fleets.Get("/:id", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid fleet ID"})
+		}
+
+		fleet, err := fleetUC.GetFleet(c.Context(), id)
+		if err != nil {
+			return fleetError(c, err)
+		}
+
+		return c.JSON(fleet)
+	})
+
+	fleets.Delete("/:id", func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid fleet ID"})
+		}
+
+		if err := fleetUC.DeleteFleet(c.Context(), id); err != nil {
+			return fleetError(c, err)
+		}
+
+		return c.JSON(fiber.Map{"message": "Fleet deleted successfully"})
+	})
+}
+
+func fleetError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, usecase.ErrFleetNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupGCRoutes(router fiber.Router, gcUC usecase.GCUseCase, jwtSecret string) {
+	gc := router.Group("/gc", AuthMiddleware(jwtSecret), AdminMiddleware())
+
+	// Dry-run listing of orphaned objects
+	gc.Get("/orphans", func(c *fiber.Ctx) error {
+		report, err := gcUC.Run(c.Context(), true)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(report)
+	})
+
+	gc.Post("/run", func(c *fiber.Ctx) error {
+		dryRun := c.QueryBool("dryRun", false)
+
+		report, err := gcUC.Run(c.Context(), dryRun)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(report)
+	})
+
+	gc.Get("/report", func(c *fiber.Ctx) error {
+		report := gcUC.GetLastReport()
+		if report == nil {
+			return c.Status(404).JSON(fiber.Map{"error": "No garbage collection has run yet"})
+		}
+
+		return c.JSON(report)
+	})
+}
@@ -88,6 +88,39 @@ func SetupGitHubRoutes(router fiber.Router, githubUC usecase.GitHubUseCase, jwtS
 		return c.JSON(branches)
 	})
 
+	github.Get("/repos/:owner/:repo/contents", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		owner := c.Params("owner")
+		repo := c.Params("repo")
+
+		entries, err := githubUC.ListContents(c.Context(), userObjID, owner, repo, c.Query("path"), c.Query("branch"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(entries)
+	})
+
+	github.Get("/repos/:owner/:repo/dockerfiles", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		owner := c.Params("owner")
+		repo := c.Params("repo")
+
+		dockerfiles, truncated, err := githubUC.FindDockerfiles(c.Context(), userObjID, owner, repo, c.Query("branch"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{
+			"dockerfiles": dockerfiles,
+			"truncated":   truncated,
+		})
+	})
+
 	github.Get("/search", func(c *fiber.Ctx) error {
 		userID := c.Locals("userId").(string)
 		userObjID, _ := primitive.ObjectIDFromHex(userID)
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func SetupImportRoutes(router fiber.Router, importUC usecase.ImportUseCase, jwtSecret string) {
+	imports := router.Group("/imports", AuthMiddleware(jwtSecret), AdminMiddleware())
+
+	imports.Get("/candidates", func(c *fiber.Ctx) error {
+		nodeID, err := primitive.ObjectIDFromHex(c.Query("nodeId"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Node ID is required"})
+		}
+
+		candidates, err := importUC.ListCandidates(c.Context(), nodeID, c.Query("namespace"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(candidates)
+	})
+
+	imports.Post("/", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		var req entities.ImportRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		nodeID, err := primitive.ObjectIDFromHex(c.Query("nodeId"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Node ID is required"})
+		}
+
+		deployment, err := importUC.ImportDeployment(c.Context(), userObjID, nodeID, &req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(201).JSON(deployment)
+	})
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"errors"
 	"strings"
 
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
 	"github.com/espazeindia/espazeNodeDeployer/pkg/auth"
 	"github.com/gofiber/fiber/v2"
 )
@@ -39,3 +41,61 @@ func AuthMiddleware(jwtSecret string) fiber.Handler {
 	}
 }
 
+
+// AdminMiddleware only lets admins through; it must run after AuthMiddleware
+func AdminMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if role, _ := c.Locals("userRole").(string); role != "admin" {
+			return c.Status(403).JSON(fiber.Map{"error": "Admin access required"})
+		}
+
+		return c.Next()
+	}
+}
+
+// NodeAuthMiddleware accepts node credentials issued at enrollment. On routes with an
+// :id the credential must belong to that node.
+func NodeAuthMiddleware(nodeUC usecase.NodeUseCase) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		credential, ok := bearerToken(c)
+		if !ok {
+			return c.Status(401).JSON(fiber.Map{"error": "Missing or invalid authorization header"})
+		}
+
+		node, err := nodeUC.AuthenticateNode(c.Context(), credential)
+		if err != nil {
+			switch {
+			case errors.Is(err, usecase.ErrNodeNotFound):
+				// Tells the agent to enroll again
+				return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+			case errors.Is(err, usecase.ErrNodeRevoked):
+				return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+			case errors.Is(err, usecase.ErrInvalidNodeCredential):
+				return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if id := c.Params("id"); id != "" && id != node.ID.Hex() {
+			return c.Status(403).JSON(fiber.Map{"error": "Credential does not belong to this node"})
+		}
+
+		c.Locals("node", node)
+		c.Locals("nodeId", node.ID)
+
+		return c.Next()
+	}
+}
+
+// nodeCredentialHeader carries an enrolled node's current credential when it enrolls
+// again with a bootstrap token
+const nodeCredentialHeader = "X-Node-Credential"
+
+// bearerToken reads the token from an "Authorization: Bearer <token>" header
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	tokenParts := strings.Split(c.Get("Authorization"), " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" || tokenParts[1] == "" {
+		return "", false
+	}
+	return tokenParts[1], true
+}
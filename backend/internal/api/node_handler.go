@@ -1,27 +1,100 @@
 package api
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
 	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func SetupNodeRoutes(router fiber.Router, nodeUC usecase.NodeUseCase, jwtSecret string) {
+func SetupNodeRoutes(router fiber.Router, nodeUC usecase.NodeUseCase, evacuationUC usecase.EvacuationUseCase, jwtSecret string) {
 	nodes := router.Group("/nodes")
 
-	nodes.Post("/register", func(c *fiber.Ctx) error {
+	// Agents exchange a bootstrap token for a node credential
+	nodes.Post("/enroll", func(c *fiber.Ctx) error {
+		bootstrapToken, ok := bearerToken(c)
+		if !ok {
+			return c.Status(401).JSON(fiber.Map{"error": "Missing bootstrap token"})
+		}
+
+		var req entities.NodeRegistrationRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		// A node that is already enrolled proves it is the same machine with its credential
+		enrollment, err := nodeUC.EnrollNode(c.Context(), bootstrapToken, c.Get(nodeCredentialHeader), &req)
+		if err != nil {
+			switch {
+			case errors.Is(err, usecase.ErrInvalidBootstrapToken):
+				return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+			case errors.Is(err, usecase.ErrNodeRevoked):
+				return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+			case errors.Is(err, usecase.ErrNodeAlreadyEnrolled):
+				return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(201).JSON(enrollment)
+	})
+
+	nodes.Post("/register", NodeAuthMiddleware(nodeUC), func(c *fiber.Ctx) error {
 		var req entities.NodeRegistrationRequest
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 		}
 
-		node, err := nodeUC.RegisterNode(c.Context(), &req)
+		node, err := nodeUC.RegisterNode(c.Context(), c.Locals("nodeId").(primitive.ObjectID), &req)
+		if err != nil {
+			return nodeError(c, err)
+		}
+
+		return c.JSON(node)
+	})
+
+	nodes.Post("/bootstrap-tokens", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
+		userID, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid user ID"})
+		}
+
+		var req entities.BootstrapTokenRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		token, err := nodeUC.CreateBootstrapToken(c.Context(), userID, &req)
 		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		return c.Status(201).JSON(node)
+		return c.Status(201).JSON(token)
+	})
+
+	nodes.Get("/bootstrap-tokens", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
+		tokens, err := nodeUC.ListBootstrapTokens(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(tokens)
+	})
+
+	nodes.Delete("/bootstrap-tokens/:tokenId", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("tokenId"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid token ID"})
+		}
+
+		if err := nodeUC.DeleteBootstrapToken(c.Context(), id); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Bootstrap token deleted successfully"})
 	})
 
 	nodes.Get("/current", func(c *fiber.Ctx) error {
@@ -48,6 +121,49 @@ func SetupNodeRoutes(router fiber.Router, nodeUC usecase.NodeUseCase, jwtSecret
 		return c.JSON(nodes)
 	})
 
+	// Nodes within radiusKm of a point, nearest first; radiusKm and limit are optional
+	nodes.Get("/nearby", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+		latitude, err := strconv.ParseFloat(c.Query("latitude"), 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid latitude"})
+		}
+		longitude, err := strconv.ParseFloat(c.Query("longitude"), 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid longitude"})
+		}
+		radiusKm, err := strconv.ParseFloat(c.Query("radiusKm", "0"), 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid radius"})
+		}
+
+		filters := make(map[string]interface{})
+		if status := c.Query("status"); status != "" {
+			filters["status"] = status
+		}
+
+		nodes, err := nodeUC.GetNodesByLocation(c.Context(), latitude, longitude, radiusKm, int64(c.QueryInt("limit", 0)), filters)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(nodes)
+	})
+
+	// Nodes inside a GeoJSON polygon, e.g. all nodes in a state
+	nodes.Post("/within", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+		var req entities.NodeRegionQuery
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		nodes, err := nodeUC.GetNodesInRegion(c.Context(), &req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(nodes)
+	})
+
 	nodes.Get("/:id", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
@@ -62,7 +178,9 @@ func SetupNodeRoutes(router fiber.Router, nodeUC usecase.NodeUseCase, jwtSecret
 		return c.JSON(node)
 	})
 
-	nodes.Put("/:id", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+	// Maintenance and evacuation affect every deployment on the node, so only admins
+	// change nodes
+	nodes.Put("/:id", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
@@ -72,40 +190,172 @@ func SetupNodeRoutes(router fiber.Router, nodeUC usecase.NodeUseCase, jwtSecret
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 		}
+		evacuate := req.Evacuate
+		if evacuate && req.Status != entities.NodeStatusMaintenance {
+			return c.Status(400).JSON(fiber.Map{"error": "evacuate requires the maintenance status"})
+		}
 
 		if err := nodeUC.UpdateNode(c.Context(), id, &req); err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
 
+		// Deployments are moved off the node in the background
+		if evacuate {
+			evacuation, err := evacuationUC.Evacuate(c.Context(), id)
+			if err != nil {
+				// The update is not rolled back; the caller can retry the evacuation
+				return c.Status(500).JSON(fiber.Map{
+					"error":       "Node updated, but its evacuation could not be started: " + err.Error(),
+					"nodeUpdated": true,
+					"evacuated":   false,
+				})
+			}
+			return c.Status(202).JSON(fiber.Map{
+				"message":    "Node updated successfully",
+				"evacuation": evacuation,
+			})
+		}
+
 		return c.JSON(fiber.Map{"message": "Node updated successfully"})
 	})
 
-	nodes.Delete("/:id", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+	nodes.Get("/:id/evacuation", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+		}
+
+		evacuation, err := evacuationUC.GetEvacuation(c.Context(), id)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if evacuation == nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Node has not been evacuated"})
+		}
+
+		return c.JSON(evacuation)
+	})
+
+	nodes.Delete("/:id", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
 		}
 
 		if err := nodeUC.DeleteNode(c.Context(), id); err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			if errors.Is(err, usecase.ErrNodeInUse) {
+				return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+			}
+			return nodeError(c, err)
 		}
 
 		return c.JSON(fiber.Map{"message": "Node deleted successfully"})
 	})
 
-	nodes.Post("/:id/heartbeat", func(c *fiber.Ctx) error {
+	nodes.Get("/:id/credentials", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
 		}
 
-		if err := nodeUC.Heartbeat(c.Context(), id); err != nil {
+		credential, err := nodeUC.GetClusterCredentials(c.Context(), id)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(credential)
+	})
+
+	nodes.Put("/:id/credentials", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+		}
+
+		var req entities.NodeCredentialRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		credential, err := nodeUC.SetClusterCredentials(c.Context(), id, &req)
+		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
 
+		return c.JSON(credential)
+	})
+
+	nodes.Delete("/:id/credentials", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+		}
+
+		if err := nodeUC.DeleteClusterCredentials(c.Context(), id); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Cluster credentials deleted successfully"})
+	})
+
+	nodes.Post("/:id/revoke", AuthMiddleware(jwtSecret), AdminMiddleware(), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+		}
+
+		if err := nodeUC.RevokeNode(c.Context(), id); err != nil {
+			return nodeError(c, err)
+		}
+
+		return c.JSON(fiber.Map{"message": "Node revoked successfully"})
+	})
+
+	nodes.Post("/:id/heartbeat", NodeAuthMiddleware(nodeUC), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+		}
+
+		if err := nodeUC.Heartbeat(c.Context(), id); err != nil {
+			return nodeError(c, err)
+		}
+
 		return c.JSON(fiber.Map{"message": "Heartbeat recorded"})
 	})
 
+	nodes.Get("/:id/events", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+		}
+
+		events, err := nodeUC.GetNodeEvents(c.Context(), id, int64(c.QueryInt("limit", 50)))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(events)
+	})
+
+	nodes.Put("/:id/resources", NodeAuthMiddleware(nodeUC), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+		}
+
+		var resources entities.NodeResources
+		if err := c.BodyParser(&resources); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		if err := nodeUC.ReportResources(c.Context(), id, &resources); err != nil {
+			return nodeError(c, err)
+		}
+
+		return c.JSON(fiber.Map{"message": "Resources updated successfully"})
+	})
+
 	nodes.Get("/stats", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
 		stats, err := nodeUC.GetNodeStats(c.Context())
 		if err != nil {
@@ -116,3 +366,10 @@ func SetupNodeRoutes(router fiber.Router, nodeUC usecase.NodeUseCase, jwtSecret
 	})
 }
 
+// nodeError tells a deleted node apart so the agent knows to register again
+func nodeError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, usecase.ErrNodeNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetupNodeOperationRoutes serves the work queue agents pull from when the server
+// cannot reach their node's cluster
+func SetupNodeOperationRoutes(router fiber.Router, nodeUC usecase.NodeUseCase, operationUC usecase.NodeOperationUseCase, jwtSecret string) {
+	nodes := router.Group("/nodes")
+
+	// Long poll: answers with the next operation, or 204 if none was queued in time
+	nodes.Get("/:id/operations/next", NodeAuthMiddleware(nodeUC), func(c *fiber.Ctx) error {
+		wait, err := time.ParseDuration(c.Query("wait", "30s"))
+		if err != nil || wait < 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid wait duration"})
+		}
+
+		operation, err := operationUC.Poll(c.Context(), c.Locals("nodeId").(primitive.ObjectID), wait)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if operation == nil {
+			return c.SendStatus(204)
+		}
+
+		return c.JSON(operation)
+	})
+
+	nodes.Post("/:id/operations/:operationId/result", NodeAuthMiddleware(nodeUC), func(c *fiber.Ctx) error {
+		operationID, err := primitive.ObjectIDFromHex(c.Params("operationId"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid operation ID"})
+		}
+
+		var result entities.NodeOperationResult
+		if err := c.BodyParser(&result); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		if err := operationUC.Complete(c.Context(), c.Locals("nodeId").(primitive.ObjectID), operationID, &result); err != nil {
+			if errors.Is(err, usecase.ErrOperationNotFound) {
+				return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Result recorded"})
+	})
+
+	nodes.Get("/:id/operations", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid node ID"})
+		}
+
+		operations, err := operationUC.GetOperations(c.Context(), id, int64(c.QueryInt("limit", 50)))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(operations)
+	})
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func SetupReconcileRoutes(router fiber.Router, reconcileUC usecase.ReconcileUseCase, jwtSecret string) {
+	router.Get("/drift", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+		deployments, err := reconcileUC.GetDriftedDeployments(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(deployments)
+	})
+
+	router.Get("/deployments/:id/drift", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		report, err := reconcileUC.CheckDeployment(c.Context(), id)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(report)
+	})
+
+	router.Post("/deployments/:id/reconcile", AuthMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid deployment ID"})
+		}
+
+		report, err := reconcileUC.RepairDeployment(c.Context(), id)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(report)
+	})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func SetupRegistryCredentialRoutes(router fiber.Router, credentialUC usecase.RegistryCredentialUseCase, jwtSecret string) {
+	credentials := router.Group("/registry-credentials", AuthMiddleware(jwtSecret))
+
+	credentials.Post("/", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		var req entities.RegistryCredentialRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		credential, err := credentialUC.CreateCredential(c.Context(), userObjID, &req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(201).JSON(credential)
+	})
+
+	credentials.Get("/", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		list, err := credentialUC.GetCredentials(c.Context(), userObjID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(list)
+	})
+
+	credentials.Put("/:id", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid registry credential ID"})
+		}
+
+		var req entities.RegistryCredentialRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		credential, err := credentialUC.UpdateCredential(c.Context(), userObjID, id, &req)
+		if err != nil {
+			return registryCredentialError(c, err)
+		}
+
+		return c.JSON(credential)
+	})
+
+	credentials.Delete("/:id", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid registry credential ID"})
+		}
+
+		if err := credentialUC.DeleteCredential(c.Context(), userObjID, id); err != nil {
+			return registryCredentialError(c, err)
+		}
+
+		return c.JSON(fiber.Map{"message": "Registry credential deleted successfully"})
+	})
+
+	credentials.Post("/:id/verify", func(c *fiber.Ctx) error {
+		userID := c.Locals("userId").(string)
+		userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid registry credential ID"})
+		}
+
+		credential, err := credentialUC.VerifyCredential(c.Context(), userObjID, id)
+		if err != nil {
+			return registryCredentialError(c, err)
+		}
+
+		return c.JSON(credential)
+	})
+}
+
+// registryCredentialError maps a registry credential use case error onto a response
+func registryCredentialError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, usecase.ErrRegistryCredentialNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// githubPush is the part of a GitHub push webhook payload used for redeploys
+type githubPush struct {
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+}
+
+// SetupWebhookRoutes registers the GitHub push webhook. GitHub cannot send a JWT, so
+// requests are authenticated by their signature over the shared webhook secret.
+func SetupWebhookRoutes(router fiber.Router, deploymentUC usecase.DeploymentUseCase, webhookSecret string) {
+	router.Post("/webhooks/github", func(c *fiber.Ctx) error {
+		if webhookSecret == "" {
+			return c.Status(503).JSON(fiber.Map{"error": "GitHub webhooks are not configured"})
+		}
+		if !validSignature(c.Body(), c.Get("X-Hub-Signature-256"), webhookSecret) {
+			return c.Status(401).JSON(fiber.Map{"error": "Invalid webhook signature"})
+		}
+
+		switch c.Get("X-GitHub-Event") {
+		case "ping":
+			return c.JSON(fiber.Map{"message": "pong"})
+		case "push":
+		default:
+			return c.JSON(fiber.Map{"message": "Event ignored"})
+		}
+
+		var payload githubPush
+		if err := c.BodyParser(&payload); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+		if payload.Deleted || !strings.HasPrefix(payload.Ref, "refs/heads/") {
+			return c.JSON(fiber.Map{"message": "Event ignored"})
+		}
+
+		push := &entities.PushEvent{
+			Repository: payload.Repository.FullName,
+			Branch:     strings.TrimPrefix(payload.Ref, "refs/heads/"),
+			After:      payload.After,
+		}
+		for _, commit := range payload.Commits {
+			push.Files = append(push.Files, commit.Added...)
+			push.Files = append(push.Files, commit.Modified...)
+			push.Files = append(push.Files, commit.Removed...)
+		}
+
+		redeployed, err := deploymentUC.HandlePush(c.Context(), push)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"redeployed": redeployed})
+	})
+}
+
+// validSignature checks a GitHub X-Hub-Signature-256 header against the body
+func validSignature(body []byte, signature, secret string) bool {
+	sum, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sum, mac.Sum(nil))
+}
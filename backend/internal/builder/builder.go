@@ -0,0 +1,358 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrUnknownStack is returned when a build context matches none of the supported stacks
+var ErrUnknownStack = errors.New("could not detect how to build the repository")
+
+// Stack is a kind of project a Dockerfile can be generated for
+type Stack string
+
+const (
+	StackGo     Stack = "go"
+	StackNode   Stack = "node"
+	StackPython Stack = "python"
+	StackStatic Stack = "static"
+)
+
+const (
+	defaultGoVersion     = "1.22"
+	defaultNodeVersion   = "20"
+	defaultPythonVersion = "3.12"
+	nginxImage           = "nginx:1.27-alpine"
+)
+
+// MarkerFiles are the files whose contents Generate reads when they exist in the build
+// context. Callers fetch those present in Project.Files into Project.Contents.
+var MarkerFiles = []string{
+	"go.mod",
+	"package.json",
+	"requirements.txt",
+	"pyproject.toml",
+	".python-version",
+	".nvmrc",
+	"manage.py",
+}
+
+// Project describes the build context of a repository without a Dockerfile
+type Project struct {
+	Name     string            // repository name, used to pick between several Go commands
+	Language string            // primary language reported by GitHub, breaks ties between stacks
+	Files    []string          // names directly inside the build context
+	Commands []string          // directories under cmd/, for Go projects
+	Contents map[string]string // contents of the MarkerFiles present
+	Port     int32             // port the container listens on
+}
+
+func (p *Project) has(name string) bool {
+	for _, file := range p.Files {
+		if file == name {
+			return true
+		}
+	}
+	return false
+}
+
+// markers lists the files that identify each stack, in detection order
+var markers = []struct {
+	stack Stack
+	files []string
+}{
+	{StackGo, []string{"go.mod"}},
+	{StackNode, []string{"package.json"}},
+	{StackPython, []string{"requirements.txt", "pyproject.toml", "Pipfile"}},
+	{StackStatic, []string{"index.html"}},
+}
+
+// languageStacks maps GitHub languages onto stacks
+var languageStacks = map[string]Stack{
+	"Go":         StackGo,
+	"JavaScript": StackNode,
+	"TypeScript": StackNode,
+	"Python":     StackPython,
+	"HTML":       StackStatic,
+	"CSS":        StackStatic,
+}
+
+// Detect returns the stack of a project. When files of several stacks are present, the
+// repository's primary language decides.
+func Detect(p *Project) (Stack, error) {
+	var found []Stack
+	for _, marker := range markers {
+		for _, file := range marker.files {
+			if p.has(file) {
+				found = append(found, marker.stack)
+				break
+			}
+		}
+	}
+	if len(found) == 0 {
+		return "", ErrUnknownStack
+	}
+
+	if preferred, ok := languageStacks[p.Language]; ok {
+		for _, stack := range found {
+			if stack == preferred {
+				return stack, nil
+			}
+		}
+	}
+	return found[0], nil
+}
+
+// Generate detects a project's stack and writes a Dockerfile that builds it
+func Generate(p *Project) (Stack, string, error) {
+	stack, err := Detect(p)
+	if err != nil {
+		return "", "", err
+	}
+
+	var dockerfile string
+	switch stack {
+	case StackGo:
+		dockerfile, err = generateGo(p)
+	case StackNode:
+		dockerfile, err = generateNode(p)
+	case StackPython:
+		dockerfile, err = generatePython(p)
+	case StackStatic:
+		dockerfile = generateStatic(p)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return stack, dockerfile, nil
+}
+
+var goDirective = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+(?:\.\d+)?)\s*$`)
+
+func generateGo(p *Project) (string, error) {
+	version := defaultGoVersion
+	if m := goDirective.FindStringSubmatch(p.Contents["go.mod"]); m != nil {
+		version = m[1]
+	}
+
+	// Build the root package, or the one command under cmd/
+	pkg := "."
+	if !p.has("main.go") {
+		switch {
+		case len(p.Commands) == 1:
+			pkg = "./cmd/" + p.Commands[0]
+		case len(p.Commands) > 1:
+			pkg = ""
+			for _, command := range p.Commands {
+				if command == p.Name || command == "server" {
+					pkg = "./cmd/" + command
+					break
+				}
+			}
+			if pkg == "" {
+				return "", fmt.Errorf("found several commands under cmd/ (%s); provide a Dockerfile", strings.Join(p.Commands, ", "))
+			}
+		}
+	}
+
+	goSum := ""
+	if p.has("go.sum") {
+		goSum = " go.sum"
+	}
+
+	return fmt.Sprintf(`# Generated by Espaze Node Deployer for a Go module
+FROM golang:%s-alpine AS build
+WORKDIR /src
+COPY go.mod%s ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 go build -trimpath -ldflags="-s -w" -o /out/app %s
+
+FROM gcr.io/distroless/static-debian12:nonroot
+COPY --from=build /out/app /app
+EXPOSE %d
+ENTRYPOINT ["/app"]
+`, version, goSum, pkg, p.Port), nil
+}
+
+// packageJSON is the part of package.json that decides how a Node project is built
+type packageJSON struct {
+	Main    string            `json:"main"`
+	Scripts map[string]string `json:"scripts"`
+	Engines struct {
+		Node string `json:"node"`
+	} `json:"engines"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+func (pkg *packageJSON) depends(name string) bool {
+	_, dep := pkg.Dependencies[name]
+	_, dev := pkg.DevDependencies[name]
+	return dep || dev
+}
+
+var majorVersion = regexp.MustCompile(`\d+`)
+
+func generateNode(p *Project) (string, error) {
+	var pkg packageJSON
+	if err := json.Unmarshal([]byte(p.Contents["package.json"]), &pkg); err != nil {
+		return "", fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	version := defaultNodeVersion
+	if m := majorVersion.FindString(pkg.Engines.Node); m != "" {
+		version = m
+	} else if m := majorVersion.FindString(p.Contents[".nvmrc"]); m != "" {
+		version = m
+	}
+
+	// Install with whichever package manager the lockfile belongs to
+	copyFiles, install, run := "package.json", "npm install", "npm run"
+	switch {
+	case p.has("package-lock.json"):
+		copyFiles, install = "package.json package-lock.json", "npm ci"
+	case p.has("yarn.lock"):
+		copyFiles, install, run = "package.json yarn.lock", "corepack enable && yarn install --frozen-lockfile", "yarn"
+	case p.has("pnpm-lock.yaml"):
+		copyFiles, install, run = "package.json pnpm-lock.yaml", "corepack enable && pnpm install --frozen-lockfile", "pnpm run"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `# Generated by Espaze Node Deployer for a Node.js project
+FROM node:%s-alpine AS build
+WORKDIR /app
+COPY %s ./
+RUN %s
+COPY . .
+`, version, copyFiles, install)
+
+	_, hasBuild := pkg.Scripts["build"]
+	_, hasStart := pkg.Scripts["start"]
+	if hasBuild {
+		fmt.Fprintf(&b, "RUN %s build\n", run)
+	}
+
+	// A project that only builds is a single page app, served as static files
+	if hasBuild && !hasStart && pkg.Main == "" {
+		output := "dist"
+		if pkg.depends("react-scripts") {
+			output = "build"
+		}
+		b.WriteString("\n")
+		writeNginx(&b, "--from=build /app/"+output, p.Port)
+		return b.String(), nil
+	}
+
+	command := `["npm", "start"]`
+	if !hasStart {
+		main := pkg.Main
+		if main == "" {
+			main = "index.js"
+		}
+		command = fmt.Sprintf(`["node", %q]`, main)
+	}
+
+	fmt.Fprintf(&b, `
+FROM node:%s-alpine
+WORKDIR /app
+ENV NODE_ENV=production PORT=%d
+COPY --from=build /app ./
+USER node
+EXPOSE %d
+CMD %s
+`, version, p.Port, p.Port, command)
+	return b.String(), nil
+}
+
+func generatePython(p *Project) (string, error) {
+	version := defaultPythonVersion
+	if v := strings.TrimSpace(p.Contents[".python-version"]); v != "" {
+		version = v
+	}
+
+	install := "COPY requirements.txt ./\nRUN pip install --no-cache-dir -r requirements.txt\nCOPY . .\n"
+	switch {
+	case p.has("requirements.txt"):
+	case p.has("pyproject.toml"):
+		install = "COPY . .\nRUN pip install --no-cache-dir .\n"
+	default:
+		install = "COPY Pipfile* ./\nRUN pip install --no-cache-dir pipenv && pipenv requirements > requirements.txt && pip install --no-cache-dir -r requirements.txt\nCOPY . .\n"
+	}
+
+	command, err := pythonCommand(p)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`# Generated by Espaze Node Deployer for a Python project
+FROM python:%s-slim AS build
+WORKDIR /app
+RUN python -m venv /venv
+ENV PATH=/venv/bin:$PATH
+%s
+FROM python:%s-slim
+WORKDIR /app
+ENV PATH=/venv/bin:$PATH PYTHONUNBUFFERED=1 PORT=%d
+COPY --from=build /venv /venv
+COPY --from=build /app ./
+EXPOSE %d
+CMD %s
+`, version, install, version, p.Port, p.Port, command), nil
+}
+
+// djangoSettings finds the project package in manage.py's default settings module,
+// e.g. mysite in "mysite.settings" or "mysite.settings.production"
+var djangoSettings = regexp.MustCompile(`DJANGO_SETTINGS_MODULE['"]\s*,\s*['"]([\w.]+?)\.settings\b`)
+
+// pythonCommand picks how to start a Python app from its entry file and the server
+// it depends on
+func pythonCommand(p *Project) (string, error) {
+	deps := strings.ToLower(p.Contents["requirements.txt"] + p.Contents["pyproject.toml"])
+	bind := fmt.Sprintf("0.0.0.0:%d", p.Port)
+
+	// Django is served by gunicorn through the project's WSGI module; the development
+	// server is only a fallback for projects that do not depend on gunicorn
+	if p.has("manage.py") {
+		m := djangoSettings.FindStringSubmatch(p.Contents["manage.py"])
+		if m != nil && strings.Contains(deps, "gunicorn") {
+			return fmt.Sprintf(`["gunicorn", "--bind", %q, "%s.wsgi"]`, bind, m[1]), nil
+		}
+		return fmt.Sprintf(`["python", "manage.py", "runserver", "--noreload", %q]`, bind), nil
+	}
+
+	for _, entry := range []string{"main.py", "app.py", "server.py"} {
+		if !p.has(entry) {
+			continue
+		}
+		module := strings.TrimSuffix(entry, ".py")
+		switch {
+		case strings.Contains(deps, "uvicorn"):
+			return fmt.Sprintf(`["uvicorn", "%s:app", "--host", "0.0.0.0", "--port", "%d"]`, module, p.Port), nil
+		case strings.Contains(deps, "gunicorn"):
+			return fmt.Sprintf(`["gunicorn", "--bind", %q, "%s:app"]`, bind, module), nil
+		default:
+			return fmt.Sprintf(`["python", %q]`, entry), nil
+		}
+	}
+	return "", errors.New("found no main.py, app.py, server.py or manage.py to start; provide a Dockerfile")
+}
+
+func generateStatic(p *Project) string {
+	var b strings.Builder
+	b.WriteString("# Generated by Espaze Node Deployer for a static site\n")
+	writeNginx(&b, ".", p.Port)
+	return b.String()
+}
+
+// writeNginx writes a stage serving the files copied from source with nginx on port
+func writeNginx(b *strings.Builder, source string, port int32) {
+	fmt.Fprintf(b, `FROM %s
+COPY %s /usr/share/nginx/html
+RUN sed -i 's/listen  *80;/listen %d;/; s/listen  *\[::\]:80;/listen [::]:%d;/' /etc/nginx/conf.d/default.conf
+EXPOSE %d
+`, nginxImage, source, port, port, port)
+}
@@ -18,14 +18,23 @@ type Config struct {
 	JWTSecret string
 	JWTExpiry string
 
+	// Key for secrets stored in the database
+	EncryptionKey string
+
 	// GitHub
-	GitHubClientID     string
-	GitHubClientSecret string
-	GitHubRedirectURL  string
+	GitHubClientID      string
+	GitHubClientSecret  string
+	GitHubRedirectURL   string
+	GitHubWebhookSecret string
 
 	// Kubernetes
 	KubeConfig       string
 	DefaultNamespace string
+	HelmBinary       string
+	KubectlBinary    string
+
+	// Container registries
+	InsecureRegistries string
 
 	// CORS
 	AllowedOrigins string
@@ -40,6 +49,20 @@ type Config struct {
 	// Observability
 	EnableMetrics bool
 	MetricsPort   string
+
+	// Background workers
+	ReconcileInterval  string
+	GCInterval         string
+	GCDryRun           bool
+	ImageWatchInterval string
+
+	// Node liveness
+	NodeMonitorInterval string
+	NodeDegradedAfter   string
+	NodeOfflineAfter    string
+
+	// Node resource usage read from the clusters
+	NodeResourceInterval string
 }
 
 func Load() *Config {
@@ -50,11 +73,16 @@ func Load() *Config {
 		DatabaseName:         getEnv("DATABASE_NAME", "espaze_node_deployer"),
 		JWTSecret:            getEnv("JWT_SECRET", "change-this-secret-in-production"),
 		JWTExpiry:            getEnv("JWT_EXPIRY", "24h"),
+		EncryptionKey:        getEnv("ENCRYPTION_KEY", "change-this-encryption-key-in-production"),
 		GitHubClientID:       getEnv("GITHUB_CLIENT_ID", ""),
 		GitHubClientSecret:   getEnv("GITHUB_CLIENT_SECRET", ""),
 		GitHubRedirectURL:    getEnv("GITHUB_REDIRECT_URL", "http://localhost:5173/auth/callback"),
+		GitHubWebhookSecret:  getEnv("GITHUB_WEBHOOK_SECRET", ""),
 		KubeConfig:           getEnv("KUBECONFIG", os.Getenv("HOME")+"/.kube/config"),
 		DefaultNamespace:     getEnv("DEFAULT_NAMESPACE", "espaze-node-deployer-apps"),
+		HelmBinary:           getEnv("HELM_BINARY", "helm"),
+		KubectlBinary:        getEnv("KUBECTL_BINARY", "kubectl"),
+		InsecureRegistries:   getEnv("INSECURE_REGISTRIES", ""),
 		AllowedOrigins:       getEnv("ALLOWED_ORIGINS", "http://localhost:5173,http://localhost:3000"),
 		DefaultMemoryLimit:   getEnv("DEFAULT_MEMORY_LIMIT", "512Mi"),
 		DefaultMemoryRequest: getEnv("DEFAULT_MEMORY_REQUEST", "256Mi"),
@@ -63,6 +91,14 @@ func Load() *Config {
 		DefaultReplicas:      getEnv("DEFAULT_REPLICAS", "2"),
 		EnableMetrics:        getEnv("ENABLE_METRICS", "true") == "true",
 		MetricsPort:          getEnv("METRICS_PORT", "9090"),
+		ReconcileInterval:    getEnv("RECONCILE_INTERVAL", "2m"),
+		GCInterval:           getEnv("GC_INTERVAL", "0"),
+		GCDryRun:             getEnv("GC_DRY_RUN", "true") == "true",
+		ImageWatchInterval:   getEnv("IMAGE_WATCH_INTERVAL", "5m"),
+		NodeMonitorInterval:  getEnv("NODE_MONITOR_INTERVAL", "30s"),
+		NodeDegradedAfter:    getEnv("NODE_DEGRADED_AFTER", "90s"),
+		NodeOfflineAfter:     getEnv("NODE_OFFLINE_AFTER", "5m"),
+		NodeResourceInterval: getEnv("NODE_RESOURCE_INTERVAL", "1m"),
 	}
 }
 
@@ -0,0 +1,87 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Addon represents a managed datastore provisioned on a node
+type Addon struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	NodeID         primitive.ObjectID `bson:"node_id" json:"nodeId"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"userId"`
+	Name           string             `bson:"name" json:"name"`
+	Type           AddonType          `bson:"type" json:"type"`
+	Version        string             `bson:"version" json:"version"`
+	Namespace      string             `bson:"namespace" json:"namespace"`
+	StorageSize    string             `bson:"storage_size" json:"storageSize"`
+	Status         AddonStatus        `bson:"status" json:"status"`
+	Bindings       []AddonBinding     `bson:"bindings" json:"bindings"`
+	KubernetesInfo AddonK8sInfo       `bson:"kubernetes_info" json:"kubernetesInfo"`
+	CreatedAt      time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// AddonType identifies an entry in the add-on catalog
+type AddonType string
+
+const (
+	AddonTypePostgres AddonType = "postgres"
+	AddonTypeRedis    AddonType = "redis"
+	AddonTypeMongoDB  AddonType = "mongodb"
+)
+
+// AddonStatus represents add-on lifecycle status
+type AddonStatus string
+
+const (
+	AddonStatusProvisioning AddonStatus = "provisioning"
+	AddonStatusReady        AddonStatus = "ready"
+	AddonStatusFailed       AddonStatus = "failed"
+	AddonStatusDeleting     AddonStatus = "deleting"
+)
+
+// AddonBinding links an add-on to a deployment through an injected env var
+type AddonBinding struct {
+	DeploymentID primitive.ObjectID `bson:"deployment_id" json:"deploymentId"`
+	EnvVar       string             `bson:"env_var" json:"envVar"`
+	BoundAt      time.Time          `bson:"bound_at" json:"boundAt"`
+}
+
+// AddonK8sInfo contains the Kubernetes objects backing an add-on
+type AddonK8sInfo struct {
+	StatefulSetName     string `bson:"statefulset_name" json:"statefulSetName"`
+	ServiceName         string `bson:"service_name" json:"serviceName"`
+	HeadlessServiceName string `bson:"headless_service_name" json:"headlessServiceName"`
+	SecretName          string `bson:"secret_name" json:"secretName"`
+	Host                string `bson:"host" json:"host"`
+	Port                int32  `bson:"port" json:"port"`
+}
+
+// AddonDefinition describes an add-on offered by the catalog
+type AddonDefinition struct {
+	Type           AddonType `json:"type"`
+	DisplayName    string    `json:"displayName"`
+	Image          string    `json:"image"`
+	DefaultVersion string    `json:"defaultVersion"`
+	Port           int32     `json:"port"`
+	DataPath       string    `json:"dataPath"`
+	DefaultEnvVar  string    `json:"defaultEnvVar"`
+	DefaultStorage string    `json:"defaultStorage"`
+}
+
+// AddonRequest is used to provision a new add-on
+type AddonRequest struct {
+	Name        string    `json:"name"`
+	Type        AddonType `json:"type"`
+	Version     string    `json:"version"`
+	Namespace   string    `json:"namespace"`
+	StorageSize string    `json:"storageSize"`
+}
+
+// AddonBindRequest is used to bind an add-on to a deployment
+type AddonBindRequest struct {
+	DeploymentID string `json:"deploymentId"`
+	EnvVar       string `json:"envVar"`
+}
@@ -0,0 +1,38 @@
+package entities
+
+// DefaultAppManifestPath is where the app manifest is looked up when no path is configured
+const DefaultAppManifestPath = "espaze.yaml"
+
+// AppManifest is the espaze.yaml file kept next to the application code.
+// Every field is optional; fields that are set override the deployer defaults
+// but are themselves overridden by values given explicitly in the API request.
+type AppManifest struct {
+	Version     int                   `json:"version"`
+	Port        int32                 `json:"port,omitempty"`
+	ServicePort int32                 `json:"servicePort,omitempty"`
+	Replicas    *int32                `json:"replicas,omitempty"`
+	Resources   *AppManifestResources `json:"resources,omitempty"`
+	Env         map[string]string     `json:"env,omitempty"`
+	HealthCheck *HealthCheckConfig    `json:"healthCheck,omitempty"`
+	AutoScaling *AutoScalingConfig    `json:"autoScaling,omitempty"`
+	Build       *AppManifestBuild     `json:"build,omitempty"`
+}
+
+// AppManifestResources contains container resource requests and limits
+type AppManifestResources struct {
+	Requests AppManifestResourceList `json:"requests,omitempty"`
+	Limits   AppManifestResourceList `json:"limits,omitempty"`
+}
+
+// AppManifestResourceList holds CPU and memory quantities
+type AppManifestResourceList struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// AppManifestBuild contains Docker build settings
+type AppManifestBuild struct {
+	Dockerfile string            `json:"dockerfile,omitempty"`
+	Context    string            `json:"context,omitempty"`
+	Args       map[string]string `json:"args,omitempty"`
+}
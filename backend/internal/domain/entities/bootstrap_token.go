@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BootstrapToken is issued by an admin and lets an agent enroll its node once (or up to
+// MaxUses times) before it expires. Only a hash of the token is stored.
+type BootstrapToken struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Description string             `bson:"description" json:"description"`
+	TokenHash   string             `bson:"token_hash" json:"-"`
+	MaxUses     int                `bson:"max_uses" json:"maxUses"` // 0 means unlimited until expiry
+	Uses        int                `bson:"uses" json:"uses"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"createdBy"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expiresAt"`
+	LastUsedAt  *time.Time         `bson:"last_used_at,omitempty" json:"lastUsedAt,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// BootstrapTokenRequest is used to issue a bootstrap token
+type BootstrapTokenRequest struct {
+	Description string `json:"description"`
+	TTL         string `json:"ttl"` // e.g. "24h"; defaults to 24h
+	MaxUses     int    `json:"maxUses"`
+}
+
+// BootstrapTokenResponse carries the token itself; it is only ever shown once
+type BootstrapTokenResponse struct {
+	BootstrapToken
+	Token string `json:"token"`
+}
+
+// NodeEnrollment is returned to an agent that enrolled with a bootstrap token
+type NodeEnrollment struct {
+	Node       *Node  `json:"node"`
+	Credential string `json:"credential"`
+}
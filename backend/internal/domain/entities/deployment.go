@@ -16,9 +16,21 @@ type Deployment struct {
 	Namespace         string             `bson:"namespace" json:"namespace"`
 	Status            DeploymentStatus   `bson:"status" json:"status"`
 	GitHubRepo        GitHubRepository   `bson:"github_repo" json:"githubRepo"`
+	Source            SourceConfig       `bson:"source" json:"source"`
+	HelmRelease       *HelmRelease       `bson:"helm_release,omitempty" json:"helmRelease,omitempty"`
+	AppliedObjects    []AppliedObject    `bson:"applied_objects,omitempty" json:"appliedObjects,omitempty"`
+	ImageDigest       string             `bson:"image_digest,omitempty" json:"imageDigest,omitempty"`       // digest the image source's tag resolved to
+	DeployedCommit    string             `bson:"deployed_commit,omitempty" json:"deployedCommit,omitempty"` // branch head at the last rollout
 	Configuration     DeploymentConfig   `bson:"configuration" json:"configuration"`
+	ConfigOverrides   DeploymentConfig   `bson:"config_overrides" json:"configOverrides"` // values given explicitly through the API
+	ExplicitSettings  ExplicitSettings   `bson:"explicit_settings" json:"explicitSettings"`
+	ManifestPath      string             `bson:"manifest_path" json:"manifestPath"`       // espaze.yaml location in the repo
 	KubernetesInfo    K8sDeploymentInfo  `bson:"kubernetes_info" json:"kubernetesInfo"`
 	Metrics           DeploymentMetrics  `bson:"metrics" json:"metrics"`
+	Drift             DriftReport        `bson:"drift" json:"drift"`
+	DeletionError     string             `bson:"deletion_error,omitempty" json:"deletionError,omitempty"`
+	Placement         *PlacementDecision `bson:"placement,omitempty" json:"placement,omitempty"` // set when the scheduler chose the node
+	FleetID           *primitive.ObjectID `bson:"fleet_id,omitempty" json:"fleetId,omitempty"`    // set for the child deployments of a fleet
 	CreatedAt         time.Time          `bson:"created_at" json:"createdAt"`
 	UpdatedAt         time.Time          `bson:"updated_at" json:"updatedAt"`
 	DeployedAt        time.Time          `bson:"deployed_at" json:"deployedAt"`
@@ -51,8 +63,60 @@ type DeploymentConfig struct {
 	AutoScaling        AutoScalingConfig      `bson:"auto_scaling" json:"autoScaling"`
 	HealthCheck        HealthCheckConfig      `bson:"health_check" json:"healthCheck"`
 	ImagePullPolicy    string                 `bson:"image_pull_policy" json:"imagePullPolicy"`
+	ImagePullSecrets   []string               `bson:"image_pull_secrets,omitempty" json:"imagePullSecrets,omitempty"` // Secrets of synced registry credentials are added automatically
 	RestartPolicy      string                 `bson:"restart_policy" json:"restartPolicy"`
 	BuildConfig        BuildConfig            `bson:"build_config" json:"buildConfig"`
+	WorkloadKind       WorkloadKind           `bson:"workload_kind" json:"workloadKind"`
+	StatefulSet        StatefulSetConfig      `bson:"stateful_set" json:"statefulSet"`
+	SecretEnvVars      []SecretEnvVar         `bson:"secret_env_vars" json:"secretEnvVars"`
+	ReconcilePolicy    ReconcilePolicy        `bson:"reconcile_policy" json:"reconcilePolicy"`
+}
+
+// ExplicitSettings records ConfigOverrides that were given through the API even when
+// their value is zero or false, e.g. scaling to 0 or turning autoscaling off, which
+// ConfigOverrides alone cannot tell apart from values never given
+type ExplicitSettings struct {
+	Replicas    bool `bson:"replicas" json:"replicas"`
+	AutoScaling bool `bson:"auto_scaling" json:"autoScaling"`
+}
+
+// ReconcilePolicy controls what the reconciler does when live objects drift
+type ReconcilePolicy string
+
+const (
+	ReconcilePolicyOff        ReconcilePolicy = "off"
+	ReconcilePolicyReport     ReconcilePolicy = "report"
+	ReconcilePolicyAutoRepair ReconcilePolicy = "auto-repair"
+)
+
+// SecretEnvVar is an environment variable sourced from a Kubernetes Secret
+type SecretEnvVar struct {
+	Name       string `bson:"name" json:"name"`
+	SecretName string `bson:"secret_name" json:"secretName"`
+	SecretKey  string `bson:"secret_key" json:"secretKey"`
+}
+
+// WorkloadKind selects the Kubernetes controller that runs the application
+type WorkloadKind string
+
+const (
+	WorkloadKindDeployment  WorkloadKind = "Deployment"
+	WorkloadKindStatefulSet WorkloadKind = "StatefulSet"
+)
+
+// StatefulSetConfig contains settings used when WorkloadKind is StatefulSet
+type StatefulSetConfig struct {
+	PodManagementPolicy  string                `bson:"pod_management_policy" json:"podManagementPolicy"` // OrderedReady or Parallel
+	VolumeClaimTemplates []VolumeClaimTemplate `bson:"volume_claim_templates" json:"volumeClaimTemplates"`
+}
+
+// VolumeClaimTemplate describes a per-replica persistent volume
+type VolumeClaimTemplate struct {
+	Name             string `bson:"name" json:"name"`
+	MountPath        string `bson:"mount_path" json:"mountPath"`
+	StorageSize      string `bson:"storage_size" json:"storageSize"`
+	StorageClassName string `bson:"storage_class_name" json:"storageClassName"`
+	AccessMode       string `bson:"access_mode" json:"accessMode"`
 }
 
 // AutoScalingConfig contains HPA configuration
@@ -78,24 +142,43 @@ type HealthCheckConfig struct {
 
 // BuildConfig contains Docker build configuration
 type BuildConfig struct {
-	Dockerfile     string            `bson:"dockerfile" json:"dockerfile"`
-	BuildContext   string            `bson:"build_context" json:"buildContext"`
-	BuildArgs      map[string]string `bson:"build_args" json:"buildArgs"`
-	ImageName      string            `bson:"image_name" json:"imageName"`
-	ImageTag       string            `bson:"image_tag" json:"imageTag"`
-	RegistryURL    string            `bson:"registry_url" json:"registryUrl"`
+	Dockerfile        string            `bson:"dockerfile" json:"dockerfile"`
+	DockerfileContent string            `bson:"dockerfile_content,omitempty" json:"dockerfileContent,omitempty"` // used instead of a Dockerfile from the repository
+	Stack             string            `bson:"stack,omitempty" json:"stack,omitempty"`                          // set when DockerfileContent was generated for a detected stack
+	BuildContext      string            `bson:"build_context" json:"buildContext"`
+	BuildArgs         map[string]string `bson:"build_args" json:"buildArgs"`
+	ImageName         string            `bson:"image_name" json:"imageName"`
+	ImageTag          string            `bson:"image_tag" json:"imageTag"`
+	RegistryURL       string            `bson:"registry_url" json:"registryUrl"`
+}
+
+// DeploymentDockerfile is the Dockerfile a deployment is built with
+type DeploymentDockerfile struct {
+	Path       string `json:"path,omitempty"`  // Dockerfile in the repository, when it has one
+	Stack      string `json:"stack,omitempty"` // stack Content was generated for
+	Content    string `json:"content,omitempty"`
+	Overridden bool   `json:"overridden"` // Content was given by hand
+}
+
+// DockerfileOverrideRequest replaces a deployment's Dockerfile. Empty content goes back
+// to the generated one.
+type DockerfileOverrideRequest struct {
+	Content string `json:"content"`
 }
 
 // K8sDeploymentInfo contains actual Kubernetes deployment info
 type K8sDeploymentInfo struct {
-	DeploymentName  string    `bson:"deployment_name" json:"deploymentName"`
-	ServiceName     string    `bson:"service_name" json:"serviceName"`
-	IngressName     string    `bson:"ingress_name" json:"ingressName"`
-	ConfigMapName   string    `bson:"configmap_name" json:"configMapName"`
-	SecretName      string    `bson:"secret_name" json:"secretName"`
-	URL             string    `bson:"url" json:"url"`
-	InternalURL     string    `bson:"internal_url" json:"internalUrl"`
-	PodSelector     string    `bson:"pod_selector" json:"podSelector"`
+	WorkloadKind        WorkloadKind `bson:"workload_kind" json:"workloadKind"`
+	DeploymentName      string       `bson:"deployment_name" json:"deploymentName"`
+	ServiceName         string       `bson:"service_name" json:"serviceName"`
+	HeadlessServiceName string       `bson:"headless_service_name" json:"headlessServiceName"`
+	IngressName         string       `bson:"ingress_name" json:"ingressName"`
+	ConfigMapName       string       `bson:"configmap_name" json:"configMapName"`
+	HPAName             string       `bson:"hpa_name" json:"hpaName"`
+	SecretName          string       `bson:"secret_name" json:"secretName"`
+	URL                 string       `bson:"url" json:"url"`
+	InternalURL         string       `bson:"internal_url" json:"internalUrl"`
+	PodSelector         string       `bson:"pod_selector" json:"podSelector"`
 }
 
 // DeploymentMetrics contains runtime metrics
@@ -114,17 +197,49 @@ type DeploymentMetrics struct {
 	LastRestartTime  time.Time `bson:"last_restart_time" json:"lastRestartTime"`
 }
 
+// DriftReport is the result of comparing a deployment with its live objects
+type DriftReport struct {
+	Drifted    bool        `bson:"drifted" json:"drifted"`
+	Items      []DriftItem `bson:"items" json:"items"`
+	Repaired   bool        `bson:"repaired" json:"repaired"`
+	Error      string      `bson:"error,omitempty" json:"error,omitempty"`
+	CheckedAt  time.Time   `bson:"checked_at" json:"checkedAt"`
+	RepairedAt time.Time   `bson:"repaired_at" json:"repairedAt"`
+}
+
+// DriftItem describes a single difference between desired and live state
+type DriftItem struct {
+	Type     DriftType `bson:"type" json:"type"`
+	Object   string    `bson:"object" json:"object"` // e.g. Deployment/my-app
+	Field    string    `bson:"field,omitempty" json:"field,omitempty"`
+	Expected string    `bson:"expected,omitempty" json:"expected,omitempty"`
+	Actual   string    `bson:"actual,omitempty" json:"actual,omitempty"`
+}
+
+// DriftType classifies a drift item
+type DriftType string
+
+const (
+	DriftTypeMissing  DriftType = "missing"
+	DriftTypeImage    DriftType = "image"
+	DriftTypeReplicas DriftType = "replicas"
+	DriftTypeEnv      DriftType = "env"
+)
+
 // DeploymentStatus represents deployment status
 type DeploymentStatus string
 
 const (
-	DeploymentStatusPending    DeploymentStatus = "pending"
-	DeploymentStatusBuilding   DeploymentStatus = "building"
-	DeploymentStatusDeploying  DeploymentStatus = "deploying"
-	DeploymentStatusRunning    DeploymentStatus = "running"
-	DeploymentStatusFailed     DeploymentStatus = "failed"
-	DeploymentStatusStopped    DeploymentStatus = "stopped"
-	DeploymentStatusUpdating   DeploymentStatus = "updating"
+	DeploymentStatusPending     DeploymentStatus = "pending"
+	DeploymentStatusBuilding    DeploymentStatus = "building"
+	DeploymentStatusDeploying   DeploymentStatus = "deploying"
+	DeploymentStatusRunning     DeploymentStatus = "running"
+	DeploymentStatusFailed      DeploymentStatus = "failed"
+	DeploymentStatusStopped     DeploymentStatus = "stopped"
+	DeploymentStatusUpdating    DeploymentStatus = "updating"
+	DeploymentStatusDeleting    DeploymentStatus = "deleting"
+	DeploymentStatusUnreachable DeploymentStatus = "unreachable" // Node went offline
+	DeploymentStatusMissing     DeploymentStatus = "missing"     // Workload is gone from the cluster; the reconciler keeps checking
 )
 
 // DeploymentRequest is used to create a new deployment
@@ -132,8 +247,13 @@ type DeploymentRequest struct {
 	Name          string           `json:"name" binding:"required"`
 	ContextPath   string           `json:"contextPath" binding:"required"`
 	GitHubRepo    GitHubRepository `json:"githubRepo" binding:"required"`
+	Source        SourceConfig     `json:"source"`
 	Configuration DeploymentConfig `json:"configuration"`
 	Namespace     string           `json:"namespace"`
+	ManifestPath  string           `json:"manifestPath"`        // defaults to espaze.yaml
+	Placement     *PlacementRequest `json:"placement,omitempty"` // used when no node is given
+	FleetID       primitive.ObjectID `json:"-" bson:"-"`          // set for the child deployments of a fleet
+	Explicit      ExplicitSettings   `json:"-" bson:"-"`          // set when redeploying an existing deployment's overrides
 }
 
 // DeploymentUpdateRequest is used to update deployment
@@ -141,5 +261,7 @@ type DeploymentUpdateRequest struct {
 	Replicas        *int32                 `json:"replicas,omitempty"`
 	EnvironmentVars map[string]string      `json:"environmentVars,omitempty"`
 	AutoScaling     *AutoScalingConfig     `json:"autoScaling,omitempty"`
+	ReconcilePolicy *ReconcilePolicy       `json:"reconcilePolicy,omitempty"`
+	Image           *string                `json:"image,omitempty"` // image sources only
 }
 
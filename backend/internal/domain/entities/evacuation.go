@@ -0,0 +1,56 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Evacuation moves the deployments off a node in maintenance, one at a time
+type Evacuation struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	NodeID     primitive.ObjectID `bson:"node_id" json:"nodeId"`
+	NodeName   string             `bson:"node_name" json:"nodeName"`
+	Status     EvacuationStatus   `bson:"status" json:"status"`
+	Migrations []Migration        `bson:"migrations" json:"migrations"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updatedAt"`
+	FinishedAt *time.Time         `bson:"finished_at,omitempty" json:"finishedAt,omitempty"`
+}
+
+// EvacuationStatus represents evacuation status
+type EvacuationStatus string
+
+const (
+	EvacuationStatusRunning   EvacuationStatus = "running"
+	EvacuationStatusCompleted EvacuationStatus = "completed" // Every deployment was moved
+	EvacuationStatusFailed    EvacuationStatus = "failed"    // Some deployments are still on the node
+	EvacuationStatusCancelled EvacuationStatus = "cancelled" // The node left maintenance first
+)
+
+// Migration moves one deployment: a replacement is deployed to another node and the
+// original is deleted once the replacement is healthy
+type Migration struct {
+	DeploymentID   primitive.ObjectID `bson:"deployment_id" json:"deploymentId"`
+	Name           string             `bson:"name" json:"name"`
+	Status         MigrationStatus    `bson:"status" json:"status"`
+	ReplacementID  primitive.ObjectID `bson:"replacement_id,omitempty" json:"replacementId,omitempty"`
+	TargetNodeID   primitive.ObjectID `bson:"target_node_id,omitempty" json:"targetNodeId,omitempty"`
+	TargetNodeName string             `bson:"target_node_name,omitempty" json:"targetNodeName,omitempty"`
+	Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt      *time.Time         `bson:"started_at,omitempty" json:"startedAt,omitempty"`
+	FinishedAt     *time.Time         `bson:"finished_at,omitempty" json:"finishedAt,omitempty"`
+}
+
+// MigrationStatus represents migration status
+type MigrationStatus string
+
+const (
+	MigrationStatusPending   MigrationStatus = "pending"
+	MigrationStatusDeploying MigrationStatus = "deploying" // Waiting for the replacement to become healthy
+	MigrationStatusCompleted MigrationStatus = "completed"
+	MigrationStatusFailed    MigrationStatus = "failed"  // The original keeps running
+	MigrationStatusSkipped   MigrationStatus = "skipped" // Deleted or moved in the meantime
+	MigrationStatusCancelled MigrationStatus = "cancelled"
+)
@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Fleet is one app spec deployed to many nodes. Each node runs a child deployment of
+// its own; the fleet tracks them and aggregates their state.
+type Fleet struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID   `bson:"user_id" json:"userId"`
+	Name      string               `bson:"name" json:"name"`
+	Spec      DeploymentRequest    `bson:"spec" json:"spec"`
+	Target    FleetTarget          `bson:"target" json:"target"`
+	Members   []FleetMember        `bson:"members" json:"members"`
+	Rejected  []PlacementRejection `bson:"rejected,omitempty" json:"rejected,omitempty"` // Targeted nodes that could not take the spec
+	CreatedAt time.Time            `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time            `bson:"updated_at" json:"updatedAt"`
+}
+
+// FleetTargetType selects how a fleet picks its nodes
+type FleetTargetType string
+
+const (
+	FleetTargetNearest  FleetTargetType = "nearest"  // The Count nearest eligible nodes to Near
+	FleetTargetRegion   FleetTargetType = "region"   // Every eligible node in Region or inside Area
+	FleetTargetSelector FleetTargetType = "selector" // Every eligible node with the NodeSelector labels
+)
+
+// FleetTarget describes the nodes a fleet is deployed to
+type FleetTarget struct {
+	Type         FleetTargetType   `bson:"type" json:"type"`
+	Near         *Coordinates      `bson:"near,omitempty" json:"near,omitempty"`
+	Count        int               `bson:"count,omitempty" json:"count,omitempty"`
+	Region       string            `bson:"region,omitempty" json:"region,omitempty"` // Matches the nodes' location region
+	Area         *GeoGeometry      `bson:"area,omitempty" json:"area,omitempty"`     // GeoJSON polygon, e.g. a state
+	NodeSelector map[string]string `bson:"node_selector,omitempty" json:"nodeSelector,omitempty"`
+}
+
+// FleetMember is a node of the fleet and the child deployment it runs
+type FleetMember struct {
+	NodeID       primitive.ObjectID `bson:"node_id" json:"nodeId"`
+	NodeName     string             `bson:"node_name" json:"nodeName"`
+	DeploymentID primitive.ObjectID `bson:"deployment_id,omitempty" json:"deploymentId,omitempty"`
+	DistanceKm   *float64           `bson:"distance_km,omitempty" json:"distanceKm,omitempty"`
+	Error        string             `bson:"error,omitempty" json:"error,omitempty"` // Why the child deployment could not be created
+}
+
+// FleetRequest creates a fleet
+type FleetRequest struct {
+	Name   string            `json:"name"`
+	Spec   DeploymentRequest `json:"spec"`
+	Target FleetTarget       `json:"target"`
+}
+
+// FleetStatus sums up the states of a fleet's child deployments
+type FleetStatus string
+
+const (
+	FleetStatusProgressing FleetStatus = "progressing" // Some children are still rolling out
+	FleetStatusRunning     FleetStatus = "running"     // Every child runs
+	FleetStatusDegraded    FleetStatus = "degraded"    // Some children failed or are unreachable
+	FleetStatusFailed      FleetStatus = "failed"      // No child runs
+	FleetStatusDeleting    FleetStatus = "deleting"
+)
+
+// FleetView is a fleet with the aggregated state of its child deployments
+type FleetView struct {
+	Fleet
+	Status      FleetStatus              `json:"status"`
+	Statuses    map[DeploymentStatus]int `json:"statuses"` // Children per status
+	Metrics     DeploymentMetrics        `json:"metrics"`  // Summed over the children
+	Deployments []*Deployment            `json:"deployments"`
+}
@@ -0,0 +1,23 @@
+package entities
+
+import "time"
+
+// ManagedObject is a Kubernetes object carrying the managed-by label
+type ManagedObject struct {
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	App       string    `json:"app"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GCReport is the result of a garbage collection pass
+type GCReport struct {
+	DryRun    bool            `json:"dryRun"`
+	Scanned   int             `json:"scanned"`
+	Orphans   []ManagedObject `json:"orphans"`
+	Deleted   int             `json:"deleted"`
+	Errors    []string        `json:"errors"`
+	StartedAt time.Time       `json:"startedAt"`
+	Duration  string          `json:"duration"`
+}
@@ -0,0 +1,40 @@
+package entities
+
+// GeoPoint is a GeoJSON point. Coordinates are longitude first, then latitude.
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewGeoPoint returns the point of a location, or nil when the location is unset (0, 0)
+// or out of range and cannot be indexed
+func NewGeoPoint(location Location) *GeoPoint {
+	if location.Latitude == 0 && location.Longitude == 0 {
+		return nil
+	}
+	if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+		return nil
+	}
+	return &GeoPoint{
+		Type:        "Point",
+		Coordinates: []float64{location.Longitude, location.Latitude},
+	}
+}
+
+// GeoGeometry is a GeoJSON Polygon or MultiPolygon outlining a region, e.g. a state
+type GeoGeometry struct {
+	Type        string      `bson:"type" json:"type"`
+	Coordinates interface{} `bson:"coordinates" json:"coordinates"`
+}
+
+// NodeDistance is a node found near a point
+type NodeDistance struct {
+	Node       `bson:",inline"`
+	DistanceKm float64 `bson:"distance_km" json:"distanceKm"`
+}
+
+// NodeRegionQuery asks for the nodes inside a region
+type NodeRegionQuery struct {
+	Geometry GeoGeometry `json:"geometry"`
+	Status   NodeStatus  `json:"status,omitempty"`
+}
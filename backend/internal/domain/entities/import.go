@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+// ImportCandidate is an existing Kubernetes Deployment that can be adopted
+type ImportCandidate struct {
+	Name        string    `json:"name"`
+	Namespace   string    `json:"namespace"`
+	Image       string    `json:"image"`
+	Replicas    int32     `json:"replicas"`
+	ServiceName string    `json:"serviceName,omitempty"`
+	IngressName string    `json:"ingressName,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ImportRequest is used to adopt an existing Kubernetes Deployment
+type ImportRequest struct {
+	Namespace      string `json:"namespace"`
+	DeploymentName string `json:"deploymentName"`
+	Name           string `json:"name,omitempty"`        // must match the Kubernetes name if set
+	ContextPath    string `json:"contextPath,omitempty"` // defaults to the Ingress path
+}
@@ -0,0 +1,18 @@
+package entities
+
+// ManifestFormat selects how exported manifests are packaged
+type ManifestFormat string
+
+const (
+	ManifestFormatYAML      ManifestFormat = "yaml"
+	ManifestFormatHelm      ManifestFormat = "helm"
+	ManifestFormatKustomize ManifestFormat = "kustomize"
+)
+
+// ManifestExport is a rendered set of manifests ready to be downloaded
+type ManifestExport struct {
+	Format      ManifestFormat `json:"format"`
+	FileName    string         `json:"fileName"`
+	ContentType string         `json:"contentType"`
+	Content     []byte         `json:"-"`
+}
@@ -14,13 +14,19 @@ type Node struct {
 	PublicIP    string             `bson:"public_ip" json:"publicIp"`
 	PrivateIP   string             `bson:"private_ip" json:"privateIp"`
 	Location    Location           `bson:"location" json:"location"`
+	GeoLocation *GeoPoint          `bson:"geo_location,omitempty" json:"geoLocation,omitempty"` // Indexed copy of Location
 	Status      NodeStatus         `bson:"status" json:"status"`
+	AccessMode  NodeAccessMode     `bson:"access_mode,omitempty" json:"accessMode,omitempty"`
 	ClusterInfo ClusterInfo        `bson:"cluster_info" json:"clusterInfo"`
 	Resources   NodeResources      `bson:"resources" json:"resources"`
 	Metadata    NodeMetadata       `bson:"metadata" json:"metadata"`
 	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
 	LastSeenAt  time.Time          `bson:"last_seen_at" json:"lastSeenAt"`
+
+	// Credentials issued before the current generation are no longer accepted
+	CredentialGeneration int        `bson:"credential_generation" json:"-"`
+	RevokedAt            *time.Time `bson:"revoked_at,omitempty" json:"revokedAt,omitempty"`
 }
 
 // Location represents GPS coordinates of the node
@@ -52,6 +58,7 @@ type NodeResources struct {
 	DiskTotal      int64   `bson:"disk_total" json:"diskTotal"`         // Bytes
 	DiskUsed       int64   `bson:"disk_used" json:"diskUsed"`           // Bytes
 	DiskUsage      float64 `bson:"disk_usage" json:"diskUsage"`         // Percentage
+	DiskPressure   bool    `bson:"disk_pressure" json:"diskPressure"`   // A kubelet reports the DiskPressure condition
 	PodsRunning    int     `bson:"pods_running" json:"podsRunning"`
 	PodsCapacity   int     `bson:"pods_capacity" json:"podsCapacity"`
 }
@@ -71,9 +78,19 @@ type NodeStatus string
 
 const (
 	NodeStatusOnline      NodeStatus = "online"
+	NodeStatusDegraded    NodeStatus = "degraded" // Heartbeats are late
 	NodeStatusOffline     NodeStatus = "offline"
 	NodeStatusMaintenance NodeStatus = "maintenance"
 	NodeStatusError       NodeStatus = "error"
+	NodeStatusRevoked     NodeStatus = "revoked" // Credential revoked by an admin
+)
+
+// NodeAccessMode says how the server reaches a node's cluster
+type NodeAccessMode string
+
+const (
+	NodeAccessDirect NodeAccessMode = "direct" // The server talks to the Kubernetes API
+	NodeAccessAgent  NodeAccessMode = "agent"  // The agent pulls queued operations, e.g. behind NAT
 )
 
 // NodeRegistrationRequest is used when a node registers itself
@@ -86,13 +103,19 @@ type NodeRegistrationRequest struct {
 	ClusterInfo  ClusterInfo       `json:"clusterInfo"`
 	Metadata     NodeMetadata      `json:"metadata"`
 	Resources    NodeResources     `json:"resources"`
+	AccessMode   NodeAccessMode    `json:"accessMode,omitempty"`
 }
 
 // NodeUpdateRequest is used to update node information
 type NodeUpdateRequest struct {
 	Status      NodeStatus    `json:"status,omitempty"`
+	PublicIP    string        `json:"publicIp,omitempty"`
+	PrivateIP   string        `json:"privateIp,omitempty"`
 	Location    *Location     `json:"location,omitempty"`
 	Resources   *NodeResources `json:"resources,omitempty"`
 	ClusterInfo *ClusterInfo  `json:"clusterInfo,omitempty"`
+	Metadata    *NodeMetadata `json:"metadata,omitempty"`
+	AccessMode  NodeAccessMode `json:"accessMode,omitempty"`
+	Evacuate    bool           `json:"evacuate,omitempty"` // With the maintenance status, move the node's deployments elsewhere
 }
 
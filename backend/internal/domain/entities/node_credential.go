@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NodeCredentialType is how the deployer authenticates to a node's cluster
+type NodeCredentialType string
+
+const (
+	NodeCredentialTypeKubeconfig NodeCredentialType = "kubeconfig"
+	NodeCredentialTypeToken      NodeCredentialType = "token" // Service account token
+)
+
+// NodeCredential holds what the deployer needs to reach the cluster of a node. Nodes
+// without credentials are deployed to the default cluster.
+type NodeCredential struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	NodeID        primitive.ObjectID `bson:"node_id" json:"nodeId"`
+	Type          NodeCredentialType `bson:"type" json:"type"`
+	Kubeconfig    string             `bson:"kubeconfig,omitempty" json:"-"` // Encrypted, never expose
+	Context       string             `bson:"context,omitempty" json:"context,omitempty"`
+	Server        string             `bson:"server,omitempty" json:"server,omitempty"`
+	Token         string             `bson:"token,omitempty" json:"-"`                                // Encrypted, never expose
+	CACertificate string             `bson:"ca_certificate,omitempty" json:"caCertificate,omitempty"` // PEM
+	Insecure      bool               `bson:"insecure" json:"insecure"`
+	VerifiedAt    *time.Time         `bson:"verified_at,omitempty" json:"verifiedAt,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// NodeCredentialRequest is used to upload a kubeconfig or service account token for a node
+type NodeCredentialRequest struct {
+	Type          NodeCredentialType `json:"type"`
+	Kubeconfig    string             `json:"kubeconfig"`
+	Context       string             `json:"context"`
+	Server        string             `json:"server"`
+	Token         string             `json:"token"`
+	CACertificate string             `json:"caCertificate"`
+	Insecure      bool               `json:"insecure"`
+}
@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NodeEventType identifies what happened to a node
+type NodeEventType string
+
+const (
+	NodeEventStatusChanged NodeEventType = "status_changed"
+)
+
+// NodeEvent records a change in a node's lifecycle. Events are stored and published to
+// subscribers inside the server, e.g. for alerts or failover.
+type NodeEvent struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	NodeID     primitive.ObjectID `bson:"node_id" json:"nodeId"`
+	NodeName   string             `bson:"node_name" json:"nodeName"`
+	Type       NodeEventType      `bson:"type" json:"type"`
+	From       NodeStatus         `bson:"from,omitempty" json:"from,omitempty"`
+	To         NodeStatus         `bson:"to,omitempty" json:"to,omitempty"`
+	Reason     string             `bson:"reason" json:"reason"`
+	LastSeenAt time.Time          `bson:"last_seen_at" json:"lastSeenAt"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+}
@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NodeOperationType is the kind of work queued for a node's agent
+type NodeOperationType string
+
+const (
+	NodeOperationApply   NodeOperationType = "apply"
+	NodeOperationScale   NodeOperationType = "scale"
+	NodeOperationRestart NodeOperationType = "restart"
+	NodeOperationDelete  NodeOperationType = "delete"
+	NodeOperationLogs    NodeOperationType = "logs"
+)
+
+// NodeOperationStatus tracks an operation from queueing to the agent's result
+type NodeOperationStatus string
+
+const (
+	NodeOperationPending   NodeOperationStatus = "pending"
+	NodeOperationRunning   NodeOperationStatus = "running" // Claimed by the agent
+	NodeOperationSucceeded NodeOperationStatus = "succeeded"
+	NodeOperationFailed    NodeOperationStatus = "failed"
+)
+
+// NodeOperation is desired-state work for a node whose cluster the server cannot reach.
+// The node's agent claims it, runs it against its local cluster and reports the result.
+type NodeOperation struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	NodeID       primitive.ObjectID  `bson:"node_id" json:"nodeId"`
+	DeploymentID primitive.ObjectID  `bson:"deployment_id,omitempty" json:"deploymentId,omitempty"`
+	Type         NodeOperationType   `bson:"type" json:"type"`
+	Status       NodeOperationStatus `bson:"status" json:"status"`
+
+	// What to do; which fields are set depends on the type
+	Namespace string          `bson:"namespace" json:"namespace"`
+	Name      string          `bson:"name,omitempty" json:"name,omitempty"`           // Deployment for apply, workload for scale and restart
+	Manifests string          `bson:"manifests,omitempty" json:"manifests,omitempty"` // YAML documents to apply
+	Objects   []AppliedObject `bson:"objects,omitempty" json:"objects,omitempty"`     // Applied before (pruned by apply) or to delete
+	Replicas  int32           `bson:"replicas,omitempty" json:"replicas,omitempty"`
+	Selector  string          `bson:"selector,omitempty" json:"selector,omitempty"` // Pods to read logs from
+	TailLines int64           `bson:"tail_lines,omitempty" json:"tailLines,omitempty"`
+
+	// Result reported by the agent
+	Applied []AppliedObject `bson:"applied,omitempty" json:"applied,omitempty"`
+	Output  string          `bson:"output,omitempty" json:"output,omitempty"`
+	Error   string          `bson:"error,omitempty" json:"error,omitempty"`
+
+	Attempts    int        `bson:"attempts" json:"attempts"`
+	ClaimedAt   *time.Time `bson:"claimed_at,omitempty" json:"claimedAt,omitempty"`
+	CompletedAt *time.Time `bson:"completed_at,omitempty" json:"completedAt,omitempty"`
+	CreatedAt   time.Time  `bson:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time  `bson:"updated_at" json:"updatedAt"`
+}
+
+// NodeOperationResult is what an agent reports after running an operation
+type NodeOperationResult struct {
+	Success bool            `json:"success"`
+	Applied []AppliedObject `json:"applied,omitempty"`
+	Output  string          `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
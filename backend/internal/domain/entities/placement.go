@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PlacementRequest constrains and steers the choice of a node for a deployment created
+// without one
+type PlacementRequest struct {
+	Near         *Coordinates         `bson:"near,omitempty" json:"near,omitempty"`                 // Prefer nodes close to this point
+	Architecture string               `bson:"architecture,omitempty" json:"architecture,omitempty"` // e.g. amd64, arm64
+	NodeSelector map[string]string    `bson:"node_selector,omitempty" json:"nodeSelector,omitempty"`
+	ExcludeNodes []primitive.ObjectID `bson:"-" json:"-"` // Set by the server, e.g. to keep a replacement off its original's cluster
+}
+
+// Coordinates is a point on the globe in degrees
+type Coordinates struct {
+	Latitude  float64 `bson:"latitude" json:"latitude"`
+	Longitude float64 `bson:"longitude" json:"longitude"`
+}
+
+// PlacementDecision records which node the scheduler chose for a deployment and why
+type PlacementDecision struct {
+	NodeID     primitive.ObjectID   `bson:"node_id" json:"nodeId"`
+	NodeName   string               `bson:"node_name" json:"nodeName"`
+	Score      float64              `bson:"score" json:"score"`
+	Reason     string               `bson:"reason" json:"reason"`
+	Request    PlacementRequest     `bson:"request" json:"request"`
+	Candidates []PlacementCandidate `bson:"candidates" json:"candidates"` // Best scored first
+	Rejected   []PlacementRejection `bson:"rejected,omitempty" json:"rejected,omitempty"`
+	DecidedAt  time.Time            `bson:"decided_at" json:"decidedAt"`
+}
+
+// PlacementCandidate is an eligible node with its score. Each part is between 0 and 1,
+// higher is better.
+type PlacementCandidate struct {
+	NodeID     primitive.ObjectID `bson:"node_id" json:"nodeId"`
+	NodeName   string             `bson:"node_name" json:"nodeName"`
+	Score      float64            `bson:"score" json:"score"`
+	Proximity  float64            `bson:"proximity" json:"proximity"`
+	Load       float64            `bson:"load" json:"load"`
+	Spread     float64            `bson:"spread" json:"spread"`
+	DistanceKm *float64           `bson:"distance_km,omitempty" json:"distanceKm,omitempty"`
+}
+
+// PlacementRejection says why a node was not eligible
+type PlacementRejection struct {
+	NodeID   primitive.ObjectID `bson:"node_id" json:"nodeId"`
+	NodeName string             `bson:"node_name" json:"nodeName"`
+	Reason   string             `bson:"reason" json:"reason"`
+}
@@ -0,0 +1,36 @@
+package entities
+
+// DeploymentPreview is the result of a dry-run create or update
+type DeploymentPreview struct {
+	Deployment *Deployment     `json:"deployment"` // record as it would be stored
+	Objects    []PreviewObject `json:"objects"`
+	Valid      bool            `json:"valid"` // true when the API server accepted every object
+}
+
+// PreviewAction is what applying an object would do to the cluster
+type PreviewAction string
+
+const (
+	PreviewActionCreate    PreviewAction = "create"
+	PreviewActionUpdate    PreviewAction = "update"
+	PreviewActionUnchanged PreviewAction = "unchanged"
+)
+
+// PreviewObject is one rendered object together with the API server's verdict
+type PreviewObject struct {
+	Kind      string                 `json:"kind"`
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Action    PreviewAction          `json:"action"`
+	Manifest  map[string]interface{} `json:"manifest"`
+	Diff      []FieldDiff            `json:"diff,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Warning   string                 `json:"warning,omitempty"`
+}
+
+// FieldDiff is a single field that differs between the live and the desired object
+type FieldDiff struct {
+	Path    string `json:"path"`
+	Live    string `json:"live,omitempty"`
+	Desired string `json:"desired,omitempty"`
+}
@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RegistryCredential authenticates image pulls from a private container registry.
+// It is synced as a kubernetes.io/dockerconfigjson Secret into the namespace of every
+// deployment whose image lives in that registry.
+type RegistryCredential struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
+	Name       string             `bson:"name" json:"name"`
+	Registry   string             `bson:"registry" json:"registry"` // host, e.g. ghcr.io, docker.io or localhost:5000
+	Username   string             `bson:"username" json:"username"`
+	Password   string             `bson:"password" json:"-"` // Encrypted, never expose
+	VerifiedAt *time.Time         `bson:"verified_at,omitempty" json:"verifiedAt,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// RegistryCredentialRequest is used to save registry credentials. An empty password
+// keeps the stored one on update.
+type RegistryCredentialRequest struct {
+	Name     string `json:"name"`
+	Registry string `json:"registry"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,88 @@
+package entities
+
+import "time"
+
+// SourceType selects what a deployment is built and rolled out from
+type SourceType string
+
+const (
+	SourceTypeDockerfile SourceType = "dockerfile" // image built from a Dockerfile, objects rendered by the deployer
+	SourceTypeHelm       SourceType = "helm"       // Helm chart kept in the repository
+	SourceTypeManifests  SourceType = "manifests"  // plain manifests or a kustomization kept in the repository
+	SourceTypeImage      SourceType = "image"      // prebuilt image, no GitHub repository
+)
+
+// SourceConfig describes where in the repository the deployment comes from
+type SourceConfig struct {
+	Type        SourceType `bson:"type" json:"type"`
+	ChartPath   string     `bson:"chart_path,omitempty" json:"chartPath,omitempty"`     // helm: chart directory, defaults to the repo root
+	ValuesFiles []string   `bson:"values_files,omitempty" json:"valuesFiles,omitempty"` // helm: values files relative to the chart
+	ReleaseName string     `bson:"release_name,omitempty" json:"releaseName,omitempty"` // helm: defaults to the deployment name
+	ManifestDir string     `bson:"manifest_dir,omitempty" json:"manifestDir,omitempty"` // manifests: directory to apply, defaults to the repo root
+	Image       string     `bson:"image,omitempty" json:"image,omitempty"`              // image: reference with a tag, a digest or both
+	AutoUpdate  bool       `bson:"auto_update,omitempty" json:"autoUpdate,omitempty"`   // image: redeploy when the tag moves to a new digest
+	WatchPaths  []string   `bson:"watch_paths,omitempty" json:"watchPaths,omitempty"`   // paths whose changes trigger a redeploy, defaults to the source directory
+}
+
+// PushEvent is a branch update reported by a GitHub push webhook
+type PushEvent struct {
+	Repository string // owner/name
+	Branch     string
+	After      string   // new head commit
+	Files      []string // files touched by the pushed commits, as listed in the payload
+}
+
+// AppliedObject identifies one object applied from a deployment's manifests
+type AppliedObject struct {
+	APIVersion string `bson:"api_version" json:"apiVersion"`
+	Kind       string `bson:"kind" json:"kind"`
+	Namespace  string `bson:"namespace,omitempty" json:"namespace,omitempty"`
+	Name       string `bson:"name" json:"name"`
+}
+
+// AppliedObjectStatus is the live state of an applied object
+type AppliedObjectStatus struct {
+	AppliedObject `json:",inline"`
+	Exists        bool   `json:"exists"`
+	Ready         bool   `json:"ready"`
+	Message       string `json:"message,omitempty"`
+}
+
+// ManifestStatus summarises the live state of a manifests deployment
+type ManifestStatus struct {
+	Ready   bool                  `json:"ready"`
+	Objects []AppliedObjectStatus `json:"objects"`
+}
+
+// HelmRelease is the last known state of a deployment's Helm release
+type HelmRelease struct {
+	Name           string    `bson:"name" json:"name"`
+	Revision       int       `bson:"revision" json:"revision"`
+	Status         string    `bson:"status" json:"status"`
+	ChartName      string    `bson:"chart_name" json:"chartName"`
+	ChartVersion   string    `bson:"chart_version" json:"chartVersion"`
+	AppVersion     string    `bson:"app_version" json:"appVersion"`
+	Description    string    `bson:"description" json:"description"`
+	LastDeployedAt time.Time `bson:"last_deployed_at" json:"lastDeployedAt"`
+}
+
+// HelmRevision is one entry of a release's history
+type HelmRevision struct {
+	Revision    int       `json:"revision"`
+	Updated     time.Time `json:"updated"`
+	Status      string    `json:"status"`
+	Chart       string    `json:"chart"`
+	AppVersion  string    `json:"appVersion"`
+	Description string    `json:"description"`
+}
+
+// HelmReleaseStatus combines the current release with its history
+type HelmReleaseStatus struct {
+	Release HelmRelease    `json:"release"`
+	History []HelmRevision `json:"history"`
+}
+
+// HelmRollbackRequest is used to roll a Helm release back
+type HelmRollbackRequest struct {
+	Revision int `json:"revision"` // 0 rolls back to the previous revision
+}
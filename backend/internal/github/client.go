@@ -1,13 +1,25 @@
 package github
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/oauth2"
 )
 
+// ErrFileNotFound is returned by GetFileContent when the path does not exist on the branch
+var ErrFileNotFound = errors.New("file not found")
+
 type Client struct {
 	clientID     string
 	clientSecret string
@@ -170,51 +182,110 @@ func (c *Client) GetAuthenticatedUser(ctx context.Context, token string) (*User,
 	}, nil
 }
 
-// CheckDockerfile checks if a Dockerfile exists in the repository
-func (c *Client) CheckDockerfile(ctx context.Context, token, owner, repo, branch string) (bool, string, error) {
+// dockerfileCandidates are the Dockerfile locations checked, relative to a build context
+var dockerfileCandidates = []string{"Dockerfile", "dockerfile", ".docker/Dockerfile"}
+
+// CheckDockerfile checks if a Dockerfile exists in a directory of the repository;
+// an empty dir means the repository root
+func (c *Client) CheckDockerfile(ctx context.Context, token, owner, repo, branch, dir string) (bool, string, error) {
 	client := c.CreateAuthenticatedClient(ctx, token)
 
-	// Check for Dockerfile in root
-	_, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, "Dockerfile", &github.RepositoryContentGetOptions{
-		Ref: branch,
-	})
+	for _, candidate := range dockerfileCandidates {
+		filePath := path.Join(dir, candidate)
 
-	if resp.StatusCode == 200 {
-		return true, "Dockerfile", nil
+		fileContent, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, filePath, &github.RepositoryContentGetOptions{
+			Ref: branch,
+		})
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return false, "", fmt.Errorf("failed to check %s: %w", filePath, err)
+		}
+		if fileContent != nil {
+			return true, filePath, nil
+		}
 	}
 
-	// Check for dockerfile (lowercase)
-	_, _, resp, err = client.Repositories.GetContents(ctx, owner, repo, "dockerfile", &github.RepositoryContentGetOptions{
-		Ref: branch,
-	})
+	return false, "", nil
+}
 
-	if resp.StatusCode == 200 {
-		return true, "dockerfile", nil
+// IsDockerfile reports whether a file name is a Dockerfile, including variants such as
+// Dockerfile.prod and api.Dockerfile
+func IsDockerfile(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "dockerfile" ||
+		strings.HasPrefix(lower, "dockerfile.") ||
+		strings.HasSuffix(lower, ".dockerfile")
+}
+
+// FindDockerfiles lists every Dockerfile in the repository tree at branch. Very large
+// trees are cut short by GitHub, which is reported as truncated.
+func (c *Client) FindDockerfiles(ctx context.Context, token, owner, repo, branch string) ([]*Dockerfile, bool, error) {
+	client := c.CreateAuthenticatedClient(ctx, token)
+
+	tree, _, err := client.Git.GetTree(ctx, owner, repo, branch, true)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get repository tree: %w", err)
 	}
 
-	// Check for Dockerfile in .docker directory
-	_, _, resp, err = client.Repositories.GetContents(ctx, owner, repo, ".docker/Dockerfile", &github.RepositoryContentGetOptions{
-		Ref: branch,
-	})
+	dockerfiles := []*Dockerfile{}
+	for _, entry := range tree.Entries {
+		if entry.GetType() != "blob" || !IsDockerfile(path.Base(entry.GetPath())) {
+			continue
+		}
 
-	if resp.StatusCode == 200 {
-		return true, ".docker/Dockerfile", nil
+		// Files in a .docker directory build the directory above it
+		buildContext := path.Dir(entry.GetPath())
+		if path.Base(buildContext) == ".docker" {
+			buildContext = path.Dir(buildContext)
+		}
+
+		dockerfiles = append(dockerfiles, &Dockerfile{
+			Path:    entry.GetPath(),
+			Context: buildContext,
+		})
 	}
 
-	return false, "", err
+	return dockerfiles, tree.GetTruncated(), nil
+}
+
+// GetChangedFiles returns the paths changed between two commits
+func (c *Client) GetChangedFiles(ctx context.Context, token, owner, repo, base, head string) ([]string, error) {
+	client := c.CreateAuthenticatedClient(ctx, token)
+
+	comparison, _, err := client.Repositories.CompareCommits(ctx, owner, repo, base, head, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare commits: %w", err)
+	}
+
+	files := make([]string, 0, len(comparison.Files))
+	for _, file := range comparison.Files {
+		files = append(files, file.GetFilename())
+		if previous := file.GetPreviousFilename(); previous != "" {
+			files = append(files, previous)
+		}
+	}
+	return files, nil
 }
 
 // GetFileContent retrieves the content of a file from the repository
 func (c *Client) GetFileContent(ctx context.Context, token, owner, repo, path, branch string) (string, error) {
 	client := c.CreateAuthenticatedClient(ctx, token)
 
-	fileContent, _, _, err := client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{
+	fileContent, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{
 		Ref: branch,
 	})
 
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", ErrFileNotFound
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to get file content: %w", err)
 	}
+	if fileContent == nil {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
 
 	content, err := fileContent.GetContent()
 	if err != nil {
@@ -224,6 +295,130 @@ func (c *Client) GetFileContent(ctx context.Context, token, owner, repo, path, b
 	return content, nil
 }
 
+// ListDirectory returns the entries directly inside a repository directory
+func (c *Client) ListDirectory(ctx context.Context, token, owner, repo, dir, branch string) ([]*ContentEntry, error) {
+	client := c.CreateAuthenticatedClient(ctx, token)
+
+	_, entries, resp, err := client.Repositories.GetContents(ctx, owner, repo, dir, &github.RepositoryContentGetOptions{
+		Ref: branch,
+	})
+
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directory: %w", err)
+	}
+	if entries == nil {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	contents := make([]*ContentEntry, 0, len(entries))
+	for _, entry := range entries {
+		contents = append(contents, &ContentEntry{
+			Name: entry.GetName(),
+			Path: entry.GetPath(),
+			Type: entry.GetType(),
+			Size: entry.GetSize(),
+		})
+	}
+	return contents, nil
+}
+
+// DownloadDirectory extracts one directory of the repository at ref into dest.
+// An empty dir or "." downloads the whole repository.
+func (c *Client) DownloadDirectory(ctx context.Context, token, owner, repo, ref, dir, dest string) error {
+	client := c.CreateAuthenticatedClient(ctx, token)
+
+	archiveURL, _, err := client.Repositories.GetArchiveLink(ctx, owner, repo, github.Tarball, &github.RepositoryContentGetOptions{
+		Ref: ref,
+	}, 1)
+	if err != nil {
+		return fmt.Errorf("failed to get archive link: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download archive: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download archive: %s", resp.Status)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	prefix := strings.Trim(path.Clean("/"+dir), "/")
+	found := false
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		// Entries are nested under a single "<owner>-<repo>-<sha>/" directory
+		parts := strings.SplitN(header.Name, "/", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		name := path.Clean(parts[1])
+		if prefix != "" {
+			if name != prefix && !strings.HasPrefix(name, prefix+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/")
+		}
+		if name == "" || name == "." {
+			found = true
+			continue
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tr)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("%s: %w", dir, ErrFileNotFound)
+	}
+	return nil
+}
+
 // Helper function to convert GitHub repository to our format
 func convertRepository(repo *github.Repository) *Repository {
 	return &Repository{
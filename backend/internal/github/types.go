@@ -42,6 +42,20 @@ type Commit struct {
 	URL     string    `json:"url"`
 }
 
+// ContentEntry is a file or directory in a repository
+type ContentEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // file, dir, symlink or submodule
+	Size int    `json:"size"`
+}
+
+// Dockerfile is a Dockerfile found in a repository together with its build context
+type Dockerfile struct {
+	Path    string `json:"path"`
+	Context string `json:"context"`
+}
+
 // User represents a GitHub user
 type User struct {
 	Login     string `json:"login"`
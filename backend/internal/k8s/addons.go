@@ -0,0 +1,418 @@
+package k8s
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var addonCatalog = map[entities.AddonType]entities.AddonDefinition{
+	entities.AddonTypePostgres: {
+		Type:           entities.AddonTypePostgres,
+		DisplayName:    "PostgreSQL",
+		Image:          "postgres",
+		DefaultVersion: "16",
+		Port:           5432,
+		DataPath:       "/var/lib/postgresql/data",
+		DefaultEnvVar:  "DATABASE_URL",
+		DefaultStorage: "5Gi",
+	},
+	entities.AddonTypeRedis: {
+		Type:           entities.AddonTypeRedis,
+		DisplayName:    "Redis",
+		Image:          "redis",
+		DefaultVersion: "7",
+		Port:           6379,
+		DataPath:       "/data",
+		DefaultEnvVar:  "REDIS_URL",
+		DefaultStorage: "1Gi",
+	},
+	entities.AddonTypeMongoDB: {
+		Type:           entities.AddonTypeMongoDB,
+		DisplayName:    "MongoDB",
+		Image:          "mongo",
+		DefaultVersion: "7",
+		Port:           27017,
+		DataPath:       "/data/db",
+		DefaultEnvVar:  "MONGODB_URI",
+		DefaultStorage: "5Gi",
+	},
+}
+
+// Keys stored in every add-on credentials Secret
+const (
+	AddonSecretKeyUsername = "username"
+	AddonSecretKeyPassword = "password"
+	AddonSecretKeyDatabase = "database"
+	AddonSecretKeyURL      = "url"
+)
+
+// AddonCatalog returns every add-on that can be provisioned
+func AddonCatalog() []entities.AddonDefinition {
+	return []entities.AddonDefinition{
+		addonCatalog[entities.AddonTypePostgres],
+		addonCatalog[entities.AddonTypeRedis],
+		addonCatalog[entities.AddonTypeMongoDB],
+	}
+}
+
+// GetAddonDefinition looks up a catalog entry by type
+func GetAddonDefinition(addonType entities.AddonType) (entities.AddonDefinition, bool) {
+	definition, ok := addonCatalog[addonType]
+	return definition, ok
+}
+
+// AddonInfo returns the object names, host and port ProvisionAddon records for an add-on
+func AddonInfo(addon *entities.Addon) entities.AddonK8sInfo {
+	name := sanitizeName(addon.Name)
+	return entities.AddonK8sInfo{
+		StatefulSetName:     name,
+		ServiceName:         fmt.Sprintf("%s-service", name),
+		HeadlessServiceName: fmt.Sprintf("%s-headless", name),
+		SecretName:          fmt.Sprintf("%s-credentials", name),
+		Host:                fmt.Sprintf("%s-service.%s.svc.cluster.local", name, addon.Namespace),
+		Port:                addonCatalog[addon.Type].Port,
+	}
+}
+
+// ExistingAddonObjects lists the objects ProvisionAddon would create for an add-on that
+// already exist in its namespace
+func (c *Client) ExistingAddonObjects(ctx context.Context, addon *entities.Addon) ([]string, error) {
+	info := AddonInfo(addon)
+	namespace := addon.Namespace
+
+	checks := []struct {
+		kind string
+		name string
+		get  func() error
+	}{
+		{"StatefulSet", info.StatefulSetName, func() error {
+			_, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, info.StatefulSetName, metav1.GetOptions{})
+			return err
+		}},
+		{"Service", info.ServiceName, func() error {
+			_, err := c.clientset.CoreV1().Services(namespace).Get(ctx, info.ServiceName, metav1.GetOptions{})
+			return err
+		}},
+		{"Service", info.HeadlessServiceName, func() error {
+			_, err := c.clientset.CoreV1().Services(namespace).Get(ctx, info.HeadlessServiceName, metav1.GetOptions{})
+			return err
+		}},
+		{"Secret", info.SecretName, func() error {
+			_, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, info.SecretName, metav1.GetOptions{})
+			return err
+		}},
+	}
+
+	existing := []string{}
+	for _, check := range checks {
+		err := check.get()
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, fmt.Sprintf("%s/%s", check.kind, check.name))
+	}
+	return existing, nil
+}
+
+// ProvisionAddon creates the Secret, Services and StatefulSet (with its PVC) for an add-on
+func (c *Client) ProvisionAddon(ctx context.Context, addon *entities.Addon) error {
+	definition, ok := addonCatalog[addon.Type]
+	if !ok {
+		return fmt.Errorf("unknown add-on type: %s", addon.Type)
+	}
+
+	namespace := addon.Namespace
+	name := sanitizeName(addon.Name)
+
+	// Ensure namespace exists
+	if _, err := c.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+		if err := c.CreateNamespace(ctx, namespace, map[string]string{
+			"managed-by": "espaze-node-deployer",
+		}); err != nil {
+			return fmt.Errorf("failed to create namespace: %w", err)
+		}
+	}
+
+	host := fmt.Sprintf("%s-service.%s.svc.cluster.local", name, namespace)
+
+	// 1. Create credentials Secret
+	if err := c.createAddonSecret(ctx, namespace, name, host, definition); err != nil {
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+	addon.KubernetesInfo.SecretName = fmt.Sprintf("%s-credentials", name)
+
+	// 2. Create Services
+	if err := c.createHeadlessService(ctx, namespace, name, definition.Port, nil); err != nil {
+		return fmt.Errorf("failed to create headless service: %w", err)
+	}
+	addon.KubernetesInfo.HeadlessServiceName = fmt.Sprintf("%s-headless", name)
+
+	if err := c.createService(ctx, namespace, name, definition.Port, definition.Port, nil); err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	addon.KubernetesInfo.ServiceName = fmt.Sprintf("%s-service", name)
+
+	// 3. Create StatefulSet with a volume claim template for the data directory
+	if err := c.createAddonStatefulSet(ctx, namespace, name, addon, definition); err != nil {
+		return fmt.Errorf("failed to create statefulset: %w", err)
+	}
+	addon.KubernetesInfo.StatefulSetName = name
+	addon.KubernetesInfo.Host = host
+	addon.KubernetesInfo.Port = definition.Port
+
+	return nil
+}
+
+func (c *Client) createAddonSecret(ctx context.Context, namespace, name, host string, definition entities.AddonDefinition) error {
+	password, err := generatePassword()
+	if err != nil {
+		return err
+	}
+
+	username := "espaze"
+	database := sanitizeName(name)
+
+	var url string
+	switch definition.Type {
+	case entities.AddonTypePostgres:
+		url = fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", username, password, host, definition.Port, database)
+	case entities.AddonTypeRedis:
+		username = ""
+		database = "0"
+		url = fmt.Sprintf("redis://:%s@%s:%d/0", password, host, definition.Port)
+	case entities.AddonTypeMongoDB:
+		url = fmt.Sprintf("mongodb://%s:%s@%s:%d/%s?authSource=admin", username, password, host, definition.Port, database)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-credentials", name),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":        name,
+				"managed-by": "espaze-node-deployer",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		StringData: map[string]string{
+			AddonSecretKeyUsername: username,
+			AddonSecretKeyPassword: password,
+			AddonSecretKeyDatabase: database,
+			AddonSecretKeyURL:      url,
+		},
+	}
+
+	_, err = c.clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	return err
+}
+
+func (c *Client) createAddonStatefulSet(ctx context.Context, namespace, name string, addon *entities.Addon, definition entities.AddonDefinition) error {
+	secretName := fmt.Sprintf("%s-credentials", name)
+	secretEnv := func(envName, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: envName,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  key,
+				},
+			},
+		}
+	}
+
+	container := corev1.Container{
+		Name:  name,
+		Image: fmt.Sprintf("%s:%s", definition.Image, addon.Version),
+		Ports: []corev1.ContainerPort{
+			{
+				ContainerPort: definition.Port,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "data",
+				MountPath: definition.DataPath,
+			},
+		},
+	}
+
+	switch definition.Type {
+	case entities.AddonTypePostgres:
+		container.Env = []corev1.EnvVar{
+			secretEnv("POSTGRES_USER", AddonSecretKeyUsername),
+			secretEnv("POSTGRES_PASSWORD", AddonSecretKeyPassword),
+			secretEnv("POSTGRES_DB", AddonSecretKeyDatabase),
+			// The volume root may contain lost+found, so keep data in a subdirectory
+			{Name: "PGDATA", Value: definition.DataPath + "/pgdata"},
+		}
+	case entities.AddonTypeRedis:
+		container.Env = []corev1.EnvVar{
+			secretEnv("REDIS_PASSWORD", AddonSecretKeyPassword),
+		}
+		container.Command = []string{"redis-server"}
+		container.Args = []string{"--requirepass", "$(REDIS_PASSWORD)", "--appendonly", "yes"}
+	case entities.AddonTypeMongoDB:
+		container.Env = []corev1.EnvVar{
+			secretEnv("MONGO_INITDB_ROOT_USERNAME", AddonSecretKeyUsername),
+			secretEnv("MONGO_INITDB_ROOT_PASSWORD", AddonSecretKeyPassword),
+			secretEnv("MONGO_INITDB_DATABASE", AddonSecretKeyDatabase),
+		}
+	}
+
+	claim, err := buildVolumeClaim(entities.VolumeClaimTemplate{
+		Name:        "data",
+		MountPath:   definition.DataPath,
+		StorageSize: addon.StorageSize,
+	})
+	if err != nil {
+		return err
+	}
+	claim.Labels["app"] = name
+
+	replicas := int32(1)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":        name,
+				"addon":      string(definition.Type),
+				"managed-by": "espaze-node-deployer",
+			},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: fmt.Sprintf("%s-headless", name),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": name,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":   name,
+						"addon": string(definition.Type),
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{container},
+				},
+			},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{claim},
+		},
+	}
+
+	_, err = c.clientset.AppsV1().StatefulSets(namespace).Create(ctx, statefulSet, metav1.CreateOptions{})
+	return err
+}
+
+// DeleteAddon removes the Kubernetes objects of an add-on. The data volumes are
+// only removed when retainData is false.
+func (c *Client) DeleteAddon(ctx context.Context, namespace, addonName string, retainData bool) error {
+	name := sanitizeName(addonName)
+	propagationPolicy := metav1.DeletePropagationForeground
+
+	deletes := []func() error{
+		func() error {
+			return c.clientset.AppsV1().StatefulSets(namespace).Delete(ctx, name, metav1.DeleteOptions{
+				PropagationPolicy: &propagationPolicy,
+			})
+		},
+		func() error {
+			return c.clientset.CoreV1().Services(namespace).Delete(ctx, fmt.Sprintf("%s-service", name), metav1.DeleteOptions{})
+		},
+		func() error {
+			return c.clientset.CoreV1().Services(namespace).Delete(ctx, fmt.Sprintf("%s-headless", name), metav1.DeleteOptions{})
+		},
+		func() error {
+			return c.clientset.CoreV1().Secrets(namespace).Delete(ctx, fmt.Sprintf("%s-credentials", name), metav1.DeleteOptions{})
+		},
+	}
+	if !retainData {
+		deletes = append(deletes, func() error {
+			return c.clientset.CoreV1().PersistentVolumeClaims(namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+				LabelSelector: fmt.Sprintf("app=%s,managed-by=espaze-node-deployer", name),
+			})
+		})
+	}
+
+	for _, del := range deletes {
+		if err := del(); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetSecretEnvVars replaces the secret-backed env vars of a running workload's container
+func (c *Client) SetSecretEnvVars(ctx context.Context, namespace, name string, vars []entities.SecretEnvVar) error {
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		statefulSet, stsErr := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if stsErr != nil {
+			return err
+		}
+		replaceSecretEnvVars(&statefulSet.Spec.Template, vars)
+		_, err = c.clientset.AppsV1().StatefulSets(namespace).Update(ctx, statefulSet, metav1.UpdateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	replaceSecretEnvVars(&deployment.Spec.Template, vars)
+	_, err = c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	return err
+}
+
+func replaceSecretEnvVars(template *corev1.PodTemplateSpec, vars []entities.SecretEnvVar) {
+	if len(template.Spec.Containers) == 0 {
+		return
+	}
+
+	env := []corev1.EnvVar{}
+	for _, envVar := range template.Spec.Containers[0].Env {
+		if envVar.ValueFrom == nil || envVar.ValueFrom.SecretKeyRef == nil {
+			env = append(env, envVar)
+		}
+	}
+	template.Spec.Containers[0].Env = append(env, buildSecretEnvVars(vars)...)
+}
+
+func buildSecretEnvVars(vars []entities.SecretEnvVar) []corev1.EnvVar {
+	envVars := make([]corev1.EnvVar, 0, len(vars))
+	for _, v := range vars {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: v.Name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: v.SecretName},
+					Key:                  v.SecretKey,
+				},
+			},
+		})
+	}
+	return envVars
+}
+
+func generatePassword() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,251 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const managedLabelPatch = `{"metadata":{"labels":{"managed-by":"espaze-node-deployer"}}}`
+
+// ListUnmanagedDeployments lists Deployments in a namespace that the deployer does not manage yet
+func (c *Client) ListUnmanagedDeployments(ctx context.Context, namespace string) ([]entities.ImportCandidate, error) {
+	deployments, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "managed-by!=espaze-node-deployer",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ingresses, err := c.clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]entities.ImportCandidate, 0, len(deployments.Items))
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		if len(deployment.OwnerReferences) > 0 {
+			continue
+		}
+
+		candidate := entities.ImportCandidate{
+			Name:      deployment.Name,
+			Namespace: deployment.Namespace,
+			Replicas:  replicaCount(deployment.Spec.Replicas),
+			CreatedAt: deployment.CreationTimestamp.Time,
+		}
+		if len(deployment.Spec.Template.Spec.Containers) > 0 {
+			candidate.Image = deployment.Spec.Template.Spec.Containers[0].Image
+		}
+		if service := findServiceFor(deployment, services.Items); service != nil {
+			candidate.ServiceName = service.Name
+			if ingress, _ := findIngressFor(service.Name, ingresses.Items); ingress != nil {
+				candidate.IngressName = ingress.Name
+			}
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// InspectDeployment builds a deployment record from the live spec of an existing Deployment
+// and its related Service and Ingress
+func (c *Client) InspectDeployment(ctx context.Context, namespace, name string) (*entities.Deployment, error) {
+	live, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := ownedElsewhere(live); err != nil {
+		return nil, err
+	}
+	if len(live.Spec.Template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("deployment %s has no containers", name)
+	}
+	container := live.Spec.Template.Spec.Containers[0]
+
+	imageName, imageTag := splitImage(container.Image)
+	config := entities.DeploymentConfig{
+		Replicas:        replicaCount(live.Spec.Replicas),
+		MemoryRequest:   quantityString(container.Resources.Requests, corev1.ResourceMemory),
+		MemoryLimit:     quantityString(container.Resources.Limits, corev1.ResourceMemory),
+		CPURequest:      quantityString(container.Resources.Requests, corev1.ResourceCPU),
+		CPULimit:        quantityString(container.Resources.Limits, corev1.ResourceCPU),
+		EnvironmentVars: map[string]string{},
+		SecretEnvVars:   []entities.SecretEnvVar{},
+		ImagePullPolicy: string(container.ImagePullPolicy),
+		RestartPolicy:   string(live.Spec.Template.Spec.RestartPolicy),
+		WorkloadKind:    entities.WorkloadKindDeployment,
+		ReconcilePolicy: entities.ReconcilePolicyReport,
+		BuildConfig: entities.BuildConfig{
+			ImageName: imageName,
+			ImageTag:  imageTag,
+		},
+	}
+
+	// Only literal values and secret references can be represented. Anything else would
+	// be dropped by the next rollout, so such workloads are not adopted.
+	unsupported := []string{}
+	for _, env := range container.Env {
+		switch {
+		case env.ValueFrom == nil:
+			config.EnvironmentVars[env.Name] = env.Value
+		case env.ValueFrom.SecretKeyRef != nil:
+			config.SecretEnvVars = append(config.SecretEnvVars, entities.SecretEnvVar{
+				Name:       env.Name,
+				SecretName: env.ValueFrom.SecretKeyRef.Name,
+				SecretKey:  env.ValueFrom.SecretKeyRef.Key,
+			})
+		default:
+			unsupported = append(unsupported, env.Name)
+		}
+	}
+	if len(container.EnvFrom) > 0 {
+		unsupported = append(unsupported, "envFrom")
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("deployment %s sets environment variables that cannot be represented: %s", name, strings.Join(unsupported, ", "))
+	}
+
+	if len(container.Ports) > 0 {
+		config.ContainerPort = container.Ports[0].ContainerPort
+	}
+
+	if probe := container.LivenessProbe; probe != nil && probe.HTTPGet != nil {
+		config.HealthCheck = entities.HealthCheckConfig{
+			Enabled:             true,
+			Path:                probe.HTTPGet.Path,
+			Port:                probe.HTTPGet.Port.IntVal,
+			InitialDelaySeconds: probe.InitialDelaySeconds,
+			PeriodSeconds:       probe.PeriodSeconds,
+			TimeoutSeconds:      probe.TimeoutSeconds,
+			SuccessThreshold:    probe.SuccessThreshold,
+			FailureThreshold:    probe.FailureThreshold,
+		}
+	}
+
+	deployment := &entities.Deployment{
+		Name:      live.Name,
+		Namespace: namespace,
+		KubernetesInfo: entities.K8sDeploymentInfo{
+			WorkloadKind:   entities.WorkloadKindDeployment,
+			DeploymentName: live.Name,
+			PodSelector:    metav1.FormatLabelSelector(live.Spec.Selector),
+		},
+	}
+
+	// Related Service and Ingress
+	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if service := findServiceFor(live, services.Items); service != nil {
+		if err := ownedElsewhere(service); err != nil {
+			return nil, err
+		}
+		deployment.KubernetesInfo.ServiceName = service.Name
+		if len(service.Spec.Ports) > 0 {
+			config.ServicePort = service.Spec.Ports[0].Port
+		}
+		deployment.KubernetesInfo.InternalURL = fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", service.Name, namespace, config.ServicePort)
+
+		ingresses, err := c.clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if ingress, path := findIngressFor(service.Name, ingresses.Items); ingress != nil {
+			if err := ownedElsewhere(ingress); err != nil {
+				return nil, err
+			}
+			deployment.KubernetesInfo.IngressName = ingress.Name
+			deployment.ContextPath = path
+			deployment.KubernetesInfo.URL = fmt.Sprintf("http://localhost%s", path)
+		}
+	}
+
+	deployment.Configuration = config
+	return deployment, nil
+}
+
+// AdoptApplication labels an existing Deployment and its related objects as managed
+func (c *Client) AdoptApplication(ctx context.Context, namespace string, info entities.K8sDeploymentInfo) error {
+	patch := []byte(managedLabelPatch)
+
+	if _, err := c.clientset.AppsV1().Deployments(namespace).Patch(ctx, info.DeploymentName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to label deployment: %w", err)
+	}
+	if info.ServiceName != "" {
+		if _, err := c.clientset.CoreV1().Services(namespace).Patch(ctx, info.ServiceName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to label service: %w", err)
+		}
+	}
+	if info.IngressName != "" {
+		if _, err := c.clientset.NetworkingV1().Ingresses(namespace).Patch(ctx, info.IngressName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to label ingress: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ownedElsewhere refuses objects that another controller or operator owns. It would
+// revert or delete whatever the deployer changed.
+func ownedElsewhere(object metav1.Object) error {
+	if owners := object.GetOwnerReferences(); len(owners) > 0 {
+		return fmt.Errorf("%s is owned by %s %s and cannot be adopted", object.GetName(), owners[0].Kind, owners[0].Name)
+	}
+	return nil
+}
+
+// findServiceFor returns the first Service whose selector matches the Deployment's pods
+func findServiceFor(deployment *appsv1.Deployment, services []corev1.Service) *corev1.Service {
+	podLabels := labels.Set(deployment.Spec.Template.Labels)
+	for i := range services {
+		selector := services[i].Spec.Selector
+		if len(selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(selector).Matches(podLabels) {
+			return &services[i]
+		}
+	}
+	return nil
+}
+
+// findIngressFor returns the first Ingress routing to the Service, with the matching path
+func findIngressFor(serviceName string, ingresses []networkingv1.Ingress) (*networkingv1.Ingress, string) {
+	for i := range ingresses {
+		for _, rule := range ingresses[i].Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service != nil && path.Backend.Service.Name == serviceName {
+					return &ingresses[i], path.Path
+				}
+			}
+		}
+	}
+	return nil, ""
+}
+
+func quantityString(list corev1.ResourceList, name corev1.ResourceName) string {
+	if quantity, ok := list[name]; ok {
+		return quantity.String()
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ApplyApplication brings the objects of an existing deployment in line with its
+// configuration using server-side apply. Unlike DeployApplication it can run repeatedly.
+func (c *Client) ApplyApplication(ctx context.Context, deployment *entities.Deployment) error {
+	namespace := applicationNamespace(deployment)
+
+	// Ensure namespace exists
+	if err := c.ensureNamespace(ctx, namespace); err != nil {
+		return err
+	}
+
+	objects, err := RenderApplication(deployment)
+	if err != nil {
+		return err
+	}
+
+	leaveReplicasToHPA(objects, deployment)
+
+	for _, object := range objects {
+		if _, _, err := c.applyObject(ctx, object, false); err != nil {
+			kind := object.GetObjectKind().GroupVersionKind().Kind
+			return fmt.Errorf("failed to apply %s: %w", kind, err)
+		}
+	}
+
+	name := sanitizeName(deployment.Name)
+	config := deployment.Configuration
+
+	// An HPA left over from a previous rollout would keep overriding the replica count
+	if !config.AutoScaling.Enabled {
+		err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Delete(ctx, fmt.Sprintf("%s-hpa", name), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete hpa: %w", err)
+		}
+	}
+
+	deployment.KubernetesInfo = ApplicationInfo(deployment)
+	return nil
+}
+
+// CheckApplicationOwner refuses a deployment whose objects would land on existing
+// objects that do not belong to it. Server-side apply with force would otherwise take
+// over whatever already uses the names.
+func (c *Client) CheckApplicationOwner(ctx context.Context, deployment *entities.Deployment) error {
+	objects, err := RenderApplication(deployment)
+	if err != nil {
+		return err
+	}
+	dynamicClient, mapper, err := c.dynamicClient()
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return err
+		}
+		gvk := object.GetObjectKind().GroupVersionKind()
+		resource, _, err := resourceFor(mapper, gvk)
+		if err != nil {
+			return err
+		}
+
+		existing, err := dynamicClient.Resource(resource).Namespace(accessor.GetNamespace()).Get(ctx, accessor.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get %s %s: %w", gvk.Kind, accessor.GetName(), err)
+		}
+		if !ownedBy(existing, deployment) {
+			return fmt.Errorf("%s %s already exists and does not belong to this deployment", gvk.Kind, accessor.GetName())
+		}
+	}
+	return nil
+}
+
+// leaveReplicasToHPA drops the replica count from the workload of an autoscaled
+// deployment. The HPA owns it, and applying it would undo every scaling decision.
+func leaveReplicasToHPA(objects []runtime.Object, deployment *entities.Deployment) {
+	if !deployment.Configuration.AutoScaling.Enabled {
+		return
+	}
+	for _, object := range objects {
+		switch workload := object.(type) {
+		case *appsv1.Deployment:
+			workload.Spec.Replicas = nil
+		case *appsv1.StatefulSet:
+			workload.Spec.Replicas = nil
+		}
+	}
+}
+
+// ApplicationInfo returns the object names and URLs DeployApplication records for a deployment
+func ApplicationInfo(deployment *entities.Deployment) entities.K8sDeploymentInfo {
+	namespace := applicationNamespace(deployment)
+	name := sanitizeName(deployment.Name)
+	config := deployment.Configuration
+
+	info := entities.K8sDeploymentInfo{
+		WorkloadKind:   entities.WorkloadKindDeployment,
+		DeploymentName: name,
+		ServiceName:    fmt.Sprintf("%s-service", name),
+		InternalURL:    fmt.Sprintf("http://%s-service.%s.svc.cluster.local:%d", name, namespace, config.ServicePort),
+		PodSelector:    fmt.Sprintf("app=%s", name),
+	}
+	if len(config.EnvironmentVars) > 0 {
+		info.ConfigMapName = fmt.Sprintf("%s-config", name)
+	}
+	if config.WorkloadKind == entities.WorkloadKindStatefulSet {
+		info.WorkloadKind = entities.WorkloadKindStatefulSet
+		info.HeadlessServiceName = fmt.Sprintf("%s-headless", name)
+	}
+	if deployment.ContextPath != "" {
+		info.IngressName = fmt.Sprintf("%s-ingress", name)
+		info.URL = fmt.Sprintf("http://localhost%s", deployment.ContextPath)
+	}
+	if config.AutoScaling.Enabled {
+		info.HPAName = fmt.Sprintf("%s-hpa", name)
+	}
+	return info
+}
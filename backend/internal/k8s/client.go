@@ -19,6 +19,8 @@ type Client struct {
 	clientset        *kubernetes.Clientset
 	metricsClientset *metricsv.Clientset
 	config           *rest.Config
+	helmBinary       string
+	kubectlBinary    string
 }
 
 func NewClient(kubeconfig string) (*Client, error) {
@@ -27,6 +29,11 @@ func NewClient(kubeconfig string) (*Client, error) {
 		return nil, fmt.Errorf("failed to build kubeconfig: %w", err)
 	}
 
+	return NewClientForConfig(config)
+}
+
+// NewClientForConfig connects to the cluster a REST config points at
+func NewClientForConfig(config *rest.Config) (*Client, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create clientset: %w", err)
@@ -51,6 +58,8 @@ func NewClient(kubeconfig string) (*Client, error) {
 		clientset:        clientset,
 		metricsClientset: metricsClientset,
 		config:           config,
+		helmBinary:       "helm",
+		kubectlBinary:    "kubectl",
 	}, nil
 }
 
@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ErrAgentManaged is returned for nodes whose cluster is only reachable through their
+// agent, e.g. behind NAT; work for them goes through the node operation queue
+var ErrAgentManaged = errors.New("node is managed through its agent; its cluster cannot be reached directly")
+
+// CredentialLoader returns the REST config for a node's cluster, or nil when the node
+// has no credentials of its own and runs on the default cluster. It returns
+// ErrAgentManaged for nodes that are reached through their agent.
+type CredentialLoader func(ctx context.Context, nodeID primitive.ObjectID) (*rest.Config, error)
+
+// ClusterManager builds a client for each node's cluster on first use and caches it
+type ClusterManager struct {
+	defaultClient *Client
+	loader        CredentialLoader
+
+	mu      sync.Mutex
+	clients map[primitive.ObjectID]*Client
+}
+
+func NewClusterManager(defaultClient *Client, loader CredentialLoader) *ClusterManager {
+	return &ClusterManager{
+		defaultClient: defaultClient,
+		loader:        loader,
+		clients:       make(map[primitive.ObjectID]*Client),
+	}
+}
+
+// Default returns the client for the cluster configured through KUBECONFIG
+func (m *ClusterManager) Default() *Client {
+	return m.defaultClient
+}
+
+// Client returns the client for a node's cluster
+func (m *ClusterManager) Client(ctx context.Context, nodeID primitive.ObjectID) (*Client, error) {
+	if nodeID.IsZero() {
+		return m.defaultClient, nil
+	}
+
+	m.mu.Lock()
+	client, ok := m.clients[nodeID]
+	m.mu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	// Connecting can take a while, so it happens outside the lock
+	config, err := m.loader(ctx, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster credentials of node %s: %w", nodeID.Hex(), err)
+	}
+	client = m.defaultClient
+	if config != nil {
+		if client, err = NewClientForConfig(config); err != nil {
+			return nil, fmt.Errorf("node %s: %w", nodeID.Hex(), err)
+		}
+		client.helmBinary = m.defaultClient.helmBinary
+		client.kubectlBinary = m.defaultClient.kubectlBinary
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cached, ok := m.clients[nodeID]; ok {
+		return cached, nil
+	}
+	m.clients[nodeID] = client
+	return client, nil
+}
+
+// Invalidate drops the cached client of a node so its credentials are read again
+func (m *ClusterManager) Invalidate(nodeID primitive.ObjectID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clients, nodeID)
+}
+
+// RESTConfigFromKubeconfig builds a REST config from an uploaded kubeconfig, using
+// contextName or the current context. Kubeconfigs that reference local files or run
+// exec or auth-provider plugins are refused, since they would run on the server.
+func RESTConfigFromKubeconfig(kubeconfig []byte, contextName string) (*rest.Config, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %s: certificate-authority files are not supported, embed certificate-authority-data", name)
+		}
+	}
+	for name, authInfo := range config.AuthInfos {
+		switch {
+		case authInfo.Exec != nil || authInfo.AuthProvider != nil:
+			return nil, fmt.Errorf("user %s: exec and auth-provider plugins are not supported", name)
+		case authInfo.TokenFile != "" || authInfo.ClientCertificate != "" || authInfo.ClientKey != "":
+			return nil, fmt.Errorf("user %s: credential files are not supported, embed the credentials", name)
+		}
+	}
+
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+	if _, ok := config.Contexts[contextName]; !ok {
+		return nil, fmt.Errorf("kubeconfig has no context %q", contextName)
+	}
+
+	return clientcmd.NewNonInteractiveClientConfig(*config, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+}
+
+// RESTConfigFromToken builds a REST config for a service account token
+func RESTConfigFromToken(server, token string, caData []byte, insecure bool) (*rest.Config, error) {
+	if server == "" || token == "" {
+		return nil, errors.New("server and token are required")
+	}
+	if len(caData) == 0 && !insecure {
+		return nil, errors.New("a CA certificate is required unless TLS verification is disabled")
+	}
+
+	config := &rest.Config{Host: server, BearerToken: token}
+	if insecure {
+		config.TLSClientConfig.Insecure = true
+	} else {
+		config.TLSClientConfig.CAData = caData
+	}
+	return config, nil
+}
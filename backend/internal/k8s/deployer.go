@@ -2,33 +2,29 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 // DeployApplication creates Kubernetes resources for a deployment
 func (c *Client) DeployApplication(ctx context.Context, deployment *entities.Deployment) error {
-	namespace := deployment.Namespace
-	if namespace == "" {
-		namespace = "espaze-node-deployer-apps"
-	}
+	namespace := applicationNamespace(deployment)
 
 	// Ensure namespace exists
-	_, err := c.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
-	if err != nil {
-		if err := c.CreateNamespace(ctx, namespace, map[string]string{
-			"managed-by": "espaze-node-deployer",
-		}); err != nil {
-			return fmt.Errorf("failed to create namespace: %w", err)
-		}
+	if err := c.ensureNamespace(ctx, namespace); err != nil {
+		return err
 	}
 
 	// Create deployment name (sanitized)
@@ -36,41 +32,70 @@ func (c *Client) DeployApplication(ctx context.Context, deployment *entities.Dep
 	
 	// 1. Create ConfigMap for environment variables (if any)
 	if len(deployment.Configuration.EnvironmentVars) > 0 {
-		if err := c.createConfigMap(ctx, namespace, deploymentName, deployment.Configuration.EnvironmentVars); err != nil {
+		if err := c.createConfigMap(ctx, namespace, deploymentName, deployment.Configuration.EnvironmentVars, deployment); err != nil {
 			return fmt.Errorf("failed to create configmap: %w", err)
 		}
 		deployment.KubernetesInfo.ConfigMapName = fmt.Sprintf("%s-config", deploymentName)
 	}
 
-	// 2. Create Deployment
-	if err := c.createDeployment(ctx, namespace, deployment); err != nil {
-		return fmt.Errorf("failed to create deployment: %w", err)
+	// 2. Create workload (Deployment or StatefulSet)
+	switch deployment.Configuration.WorkloadKind {
+	case entities.WorkloadKindStatefulSet:
+		if err := c.createHeadlessService(ctx, namespace, deploymentName, deployment.Configuration.ContainerPort, deployment); err != nil {
+			return fmt.Errorf("failed to create headless service: %w", err)
+		}
+		deployment.KubernetesInfo.HeadlessServiceName = fmt.Sprintf("%s-headless", deploymentName)
+
+		if err := c.createStatefulSet(ctx, namespace, deployment); err != nil {
+			return fmt.Errorf("failed to create statefulset: %w", err)
+		}
+		deployment.KubernetesInfo.WorkloadKind = entities.WorkloadKindStatefulSet
+	default:
+		if err := c.createDeployment(ctx, namespace, deployment); err != nil {
+			return fmt.Errorf("failed to create deployment: %w", err)
+		}
+		deployment.KubernetesInfo.WorkloadKind = entities.WorkloadKindDeployment
 	}
 	deployment.KubernetesInfo.DeploymentName = deploymentName
 
 	// 3. Create Service
-	if err := c.createService(ctx, namespace, deploymentName, deployment.Configuration.ServicePort, deployment.Configuration.ContainerPort); err != nil {
+	if err := c.createService(ctx, namespace, deploymentName, deployment.Configuration.ServicePort, deployment.Configuration.ContainerPort, deployment); err != nil {
 		return fmt.Errorf("failed to create service: %w", err)
 	}
 	deployment.KubernetesInfo.ServiceName = fmt.Sprintf("%s-service", deploymentName)
 
 	// 4. Create Ingress
 	if deployment.ContextPath != "" {
-		if err := c.createIngress(ctx, namespace, deploymentName, deployment.ContextPath, deployment.Configuration.ServicePort); err != nil {
+		if err := c.createIngress(ctx, namespace, deploymentName, deployment.ContextPath, deployment.Configuration.ServicePort, deployment); err != nil {
 			return fmt.Errorf("failed to create ingress: %w", err)
 		}
 		deployment.KubernetesInfo.IngressName = fmt.Sprintf("%s-ingress", deploymentName)
 		deployment.KubernetesInfo.URL = fmt.Sprintf("http://localhost%s", deployment.ContextPath)
 	}
 
+	// 5. Create HorizontalPodAutoscaler
+	if deployment.Configuration.AutoScaling.Enabled {
+		if err := c.createHPA(ctx, namespace, deployment); err != nil {
+			return fmt.Errorf("failed to create hpa: %w", err)
+		}
+		deployment.KubernetesInfo.HPAName = fmt.Sprintf("%s-hpa", deploymentName)
+	}
+
 	deployment.KubernetesInfo.InternalURL = fmt.Sprintf("http://%s-service.%s.svc.cluster.local:%d", deploymentName, namespace, deployment.Configuration.ServicePort)
 	deployment.KubernetesInfo.PodSelector = fmt.Sprintf("app=%s", deploymentName)
 
 	return nil
 }
 
-func (c *Client) createConfigMap(ctx context.Context, namespace, name string, data map[string]string) error {
-	configMap := &corev1.ConfigMap{
+func (c *Client) createConfigMap(ctx context.Context, namespace, name string, data map[string]string, owner *entities.Deployment) error {
+	configMap := buildConfigMap(namespace, name, data)
+	labelOwner(configMap, owner)
+	_, err := c.clientset.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	return err
+}
+
+func buildConfigMap(namespace, name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("%s-config", name),
 			Namespace: namespace,
@@ -81,15 +106,44 @@ func (c *Client) createConfigMap(ctx context.Context, namespace, name string, da
 		},
 		Data: data,
 	}
+}
 
-	_, err := c.clientset.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
+func (c *Client) createDeployment(ctx context.Context, namespace string, deployment *entities.Deployment) error {
+	k8sDeployment := buildDeployment(namespace, deployment)
+	labelOwner(k8sDeployment, deployment)
+	_, err := c.clientset.AppsV1().Deployments(namespace).Create(ctx, k8sDeployment, metav1.CreateOptions{})
 	return err
 }
 
-func (c *Client) createDeployment(ctx context.Context, namespace string, deployment *entities.Deployment) error {
+func buildDeployment(namespace string, deployment *entities.Deployment) *appsv1.Deployment {
 	deploymentName := sanitizeName(deployment.Name)
 	config := deployment.Configuration
 
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":        deploymentName,
+				"managed-by": "espaze-node-deployer",
+				"repo":       repoLabel(deployment.GitHubRepo.FullName),
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &config.Replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": deploymentName,
+				},
+			},
+			Template: buildPodTemplate(deploymentName, config),
+		},
+	}
+}
+
+// buildPodTemplate builds the pod template shared by Deployments and StatefulSets
+func buildPodTemplate(deploymentName string, config entities.DeploymentConfig) corev1.PodTemplateSpec {
+
 	// Parse resource quantities
 	memoryRequest, _ := resource.ParseQuantity(config.MemoryRequest)
 	memoryLimit, _ := resource.ParseQuantity(config.MemoryLimit)
@@ -104,65 +158,51 @@ func (c *Client) createDeployment(ctx context.Context, namespace string, deploym
 			Value: value,
 		})
 	}
+	envVars = append(envVars, buildSecretEnvVars(config.SecretEnvVars)...)
 
-	// Create deployment spec
-	k8sDeployment := &appsv1.Deployment{
+	// Create pod template
+	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      deploymentName,
-			Namespace: namespace,
 			Labels: map[string]string{
-				"app":        deploymentName,
-				"managed-by": "espaze-node-deployer",
-				"repo":       deployment.GitHubRepo.FullName,
+				"app": deploymentName,
 			},
 		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &config.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": deploymentName,
-				},
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": deploymentName,
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:            deploymentName,
+					Image:           containerImage(config),
+					ImagePullPolicy: corev1.PullPolicy(config.ImagePullPolicy),
+					Ports: []corev1.ContainerPort{
 						{
-							Name:            deploymentName,
-							Image:           config.BuildConfig.ImageName + ":" + config.BuildConfig.ImageTag,
-							ImagePullPolicy: corev1.PullPolicy(config.ImagePullPolicy),
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: config.ContainerPort,
-									Protocol:      corev1.ProtocolTCP,
-								},
-							},
-							Env: envVars,
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: memoryRequest,
-									corev1.ResourceCPU:    cpuRequest,
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: memoryLimit,
-									corev1.ResourceCPU:    cpuLimit,
-								},
-							},
+							ContainerPort: config.ContainerPort,
+							Protocol:      corev1.ProtocolTCP,
+						},
+					},
+					Env: envVars,
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceMemory: memoryRequest,
+							corev1.ResourceCPU:    cpuRequest,
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: memoryLimit,
+							corev1.ResourceCPU:    cpuLimit,
 						},
 					},
-					RestartPolicy: corev1.RestartPolicy(config.RestartPolicy),
 				},
 			},
+			RestartPolicy: corev1.RestartPolicy(config.RestartPolicy),
 		},
 	}
 
+	for _, secret := range config.ImagePullSecrets {
+		template.Spec.ImagePullSecrets = append(template.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+
 	// Add health checks if enabled
 	if config.HealthCheck.Enabled {
-		k8sDeployment.Spec.Template.Spec.Containers[0].LivenessProbe = &corev1.Probe{
+		template.Spec.Containers[0].LivenessProbe = &corev1.Probe{
 			ProbeHandler: corev1.ProbeHandler{
 				HTTPGet: &corev1.HTTPGetAction{
 					Path: config.HealthCheck.Path,
@@ -176,7 +216,7 @@ func (c *Client) createDeployment(ctx context.Context, namespace string, deploym
 			FailureThreshold:    config.HealthCheck.FailureThreshold,
 		}
 
-		k8sDeployment.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
+		template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
 			ProbeHandler: corev1.ProbeHandler{
 				HTTPGet: &corev1.HTTPGetAction{
 					Path: config.HealthCheck.Path,
@@ -191,12 +231,18 @@ func (c *Client) createDeployment(ctx context.Context, namespace string, deploym
 		}
 	}
 
-	_, err := c.clientset.AppsV1().Deployments(namespace).Create(ctx, k8sDeployment, metav1.CreateOptions{})
+	return template
+}
+
+func (c *Client) createService(ctx context.Context, namespace, name string, servicePort, targetPort int32, owner *entities.Deployment) error {
+	service := buildService(namespace, name, servicePort, targetPort)
+	labelOwner(service, owner)
+	_, err := c.clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
 	return err
 }
 
-func (c *Client) createService(ctx context.Context, namespace, name string, servicePort, targetPort int32) error {
-	service := &corev1.Service{
+func buildService(namespace, name string, servicePort, targetPort int32) *corev1.Service {
+	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("%s-service", name),
 			Namespace: namespace,
@@ -219,15 +265,19 @@ func (c *Client) createService(ctx context.Context, namespace, name string, serv
 			Type: corev1.ServiceTypeClusterIP,
 		},
 	}
+}
 
-	_, err := c.clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+func (c *Client) createIngress(ctx context.Context, namespace, name, path string, servicePort int32, owner *entities.Deployment) error {
+	ingress := buildIngress(namespace, name, path, servicePort)
+	labelOwner(ingress, owner)
+	_, err := c.clientset.NetworkingV1().Ingresses(namespace).Create(ctx, ingress, metav1.CreateOptions{})
 	return err
 }
 
-func (c *Client) createIngress(ctx context.Context, namespace, name, path string, servicePort int32) error {
+func buildIngress(namespace, name, path string, servicePort int32) *networkingv1.Ingress {
 	pathType := networkingv1.PathTypePrefix
 
-	ingress := &networkingv1.Ingress{
+	return &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("%s-ingress", name),
 			Namespace: namespace,
@@ -264,40 +314,224 @@ func (c *Client) createIngress(ctx context.Context, namespace, name, path string
 			},
 		},
 	}
+}
 
-	_, err := c.clientset.NetworkingV1().Ingresses(namespace).Create(ctx, ingress, metav1.CreateOptions{})
+// DeleteApplication removes all Kubernetes resources for a deployment.
+// Objects that are already gone count as deleted; every other failure is returned.
+func (c *Client) DeleteApplication(ctx context.Context, deployment *entities.Deployment) error {
+	objects, err := c.applicationObjects(ctx, deployment)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, object := range objects {
+		if err := object.delete(); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", object.kind, object.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WaitForApplicationDeletion blocks until every object of a deployment is gone or the timeout expires
+func (c *Client) WaitForApplicationDeletion(ctx context.Context, deployment *entities.Deployment, timeout time.Duration) error {
+	objects, err := c.applicationObjects(ctx, deployment)
+	if err != nil {
+		return err
+	}
+
+	var remaining []string
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		remaining = remaining[:0]
+		for _, object := range objects {
+			_, err := object.get()
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			remaining = append(remaining, fmt.Sprintf("%s/%s", object.kind, object.name))
+		}
+		return len(remaining) == 0, nil
+	})
+	if err != nil && len(remaining) > 0 {
+		return fmt.Errorf("objects still present: %s: %w", strings.Join(remaining, ", "), err)
+	}
 	return err
 }
 
-// DeleteApplication removes all Kubernetes resources for a deployment
-func (c *Client) DeleteApplication(ctx context.Context, namespace, deploymentName string) error {
-	name := sanitizeName(deploymentName)
+type applicationObject struct {
+	kind    string
+	name    string
+	derived bool // Name assumed rather than recorded in KubernetesInfo
+	get     func() (metav1.Object, error)
+	delete  func() error
+}
 
-	// Delete in reverse order
+// applicationObjects lists every object of a deployment, in deletion order. Names recorded
+// in KubernetesInfo win; otherwise the names DeployApplication would have used are assumed,
+// which also covers objects left behind by a rollout that failed half way. An object under
+// an assumed name is only listed when it carries the deployment's ID label, since another
+// deployment of the same name, or an adopted workload's neighbours, may use that name too.
+func (c *Client) applicationObjects(ctx context.Context, deployment *entities.Deployment) ([]applicationObject, error) {
+	namespace := deployment.Namespace
+	info := deployment.KubernetesInfo
 	propagationPolicy := metav1.DeletePropagationForeground
+	deleteOptions := metav1.DeleteOptions{PropagationPolicy: &propagationPolicy}
 
-	// Delete Ingress
-	c.clientset.NetworkingV1().Ingresses(namespace).Delete(ctx, fmt.Sprintf("%s-ingress", name), metav1.DeleteOptions{
-		PropagationPolicy: &propagationPolicy,
-	})
+	name := sanitizeName(deployment.Name)
+	ingressName := fmt.Sprintf("%s-ingress", name)
+	serviceName := fmt.Sprintf("%s-service", name)
+	headlessServiceName := fmt.Sprintf("%s-headless", name)
+	configMapName := fmt.Sprintf("%s-config", name)
+	hpaName := fmt.Sprintf("%s-hpa", name)
 
-	// Delete Service
-	c.clientset.CoreV1().Services(namespace).Delete(ctx, fmt.Sprintf("%s-service", name), metav1.DeleteOptions{})
+	if info.DeploymentName != "" {
+		name = info.DeploymentName
+	}
+	if info.IngressName != "" {
+		ingressName = info.IngressName
+	}
+	if info.ServiceName != "" {
+		serviceName = info.ServiceName
+	}
+	if info.HeadlessServiceName != "" {
+		headlessServiceName = info.HeadlessServiceName
+	}
+	if info.ConfigMapName != "" {
+		configMapName = info.ConfigMapName
+	}
+	if info.HPAName != "" {
+		hpaName = info.HPAName
+	}
 
-	// Delete Deployment
-	c.clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{
-		PropagationPolicy: &propagationPolicy,
-	})
+	// PVCs of StatefulSets are not listed, so their data survives deletion
+	objects := []applicationObject{
+		{
+			kind:    "HorizontalPodAutoscaler",
+			name:    hpaName,
+			derived: info.HPAName == "",
+			get: func() (metav1.Object, error) {
+				return c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, hpaName, metav1.GetOptions{})
+			},
+			delete: func() error {
+				return c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Delete(ctx, hpaName, deleteOptions)
+			},
+		},
+		{
+			kind:    "Ingress",
+			name:    ingressName,
+			derived: info.IngressName == "",
+			get: func() (metav1.Object, error) {
+				return c.clientset.NetworkingV1().Ingresses(namespace).Get(ctx, ingressName, metav1.GetOptions{})
+			},
+			delete: func() error {
+				return c.clientset.NetworkingV1().Ingresses(namespace).Delete(ctx, ingressName, deleteOptions)
+			},
+		},
+		{
+			kind:    "Service",
+			name:    serviceName,
+			derived: info.ServiceName == "",
+			get: func() (metav1.Object, error) {
+				return c.clientset.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
+			},
+			delete: func() error {
+				return c.clientset.CoreV1().Services(namespace).Delete(ctx, serviceName, deleteOptions)
+			},
+		},
+		{
+			kind:    "Deployment",
+			name:    name,
+			derived: info.DeploymentName == "" || info.WorkloadKind == entities.WorkloadKindStatefulSet,
+			get: func() (metav1.Object, error) {
+				return c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+			},
+			delete: func() error {
+				return c.clientset.AppsV1().Deployments(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind:    "StatefulSet",
+			name:    name,
+			derived: info.DeploymentName == "" || info.WorkloadKind != entities.WorkloadKindStatefulSet,
+			get: func() (metav1.Object, error) {
+				return c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			},
+			delete: func() error {
+				return c.clientset.AppsV1().StatefulSets(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind:    "Service",
+			name:    headlessServiceName,
+			derived: info.HeadlessServiceName == "",
+			get: func() (metav1.Object, error) {
+				return c.clientset.CoreV1().Services(namespace).Get(ctx, headlessServiceName, metav1.GetOptions{})
+			},
+			delete: func() error {
+				return c.clientset.CoreV1().Services(namespace).Delete(ctx, headlessServiceName, deleteOptions)
+			},
+		},
+		{
+			kind:    "ConfigMap",
+			name:    configMapName,
+			derived: info.ConfigMapName == "",
+			get: func() (metav1.Object, error) {
+				return c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, configMapName, metav1.GetOptions{})
+			},
+			delete: func() error {
+				return c.clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, configMapName, deleteOptions)
+			},
+		},
+	}
 
-	// Delete ConfigMap
-	c.clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, fmt.Sprintf("%s-config", name), metav1.DeleteOptions{})
+	owned := objects[:0]
+	for _, object := range objects {
+		if object.derived {
+			live, err := object.get()
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s %s: %w", object.kind, object.name, err)
+			}
+			if !ownedBy(live, deployment) {
+				continue
+			}
+		}
+		owned = append(owned, object)
+	}
+	return owned, nil
+}
 
-	return nil
+// labelOwner records on an object which deployment it belongs to. Objects without one,
+// e.g. of addons, are left alone.
+func labelOwner(object metav1.Object, deployment *entities.Deployment) {
+	if deployment == nil || deployment.ID.IsZero() {
+		return
+	}
+	labels := object.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[DeploymentIDLabel] = deployment.ID.Hex()
+	object.SetLabels(labels)
 }
 
-// ScaleDeployment scales a deployment to the specified number of replicas
+// ScaleDeployment scales a deployment to the specified number of replicas.
+// StatefulSets are scaled down in order by their controller.
 func (c *Client) ScaleDeployment(ctx context.Context, namespace, name string, replicas int32) error {
 	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		statefulSet, stsErr := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if stsErr != nil {
+			return err
+		}
+		return c.scaleStatefulSet(ctx, statefulSet, replicas)
+	}
 	if err != nil {
 		return err
 	}
@@ -311,6 +545,19 @@ func (c *Client) ScaleDeployment(ctx context.Context, namespace, name string, re
 // RestartDeployment restarts a deployment by updating an annotation
 func (c *Client) RestartDeployment(ctx context.Context, namespace, name string) error {
 	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		statefulSet, stsErr := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if stsErr != nil {
+			return err
+		}
+		if statefulSet.Spec.Template.ObjectMeta.Annotations == nil {
+			statefulSet.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+		}
+		statefulSet.Spec.Template.ObjectMeta.Annotations["kubectl.kubernetes.io/restartedAt"] = metav1.Now().Format("2006-01-02T15:04:05Z07:00")
+
+		_, err = c.clientset.AppsV1().StatefulSets(namespace).Update(ctx, statefulSet, metav1.UpdateOptions{})
+		return err
+	}
 	if err != nil {
 		return err
 	}
@@ -325,6 +572,38 @@ func (c *Client) RestartDeployment(ctx context.Context, namespace, name string)
 	return err
 }
 
+// containerImage returns the image reference the application container should run.
+// A sha256 tag pins the image by digest.
+func containerImage(config entities.DeploymentConfig) string {
+	if config.BuildConfig.ImageTag == "" {
+		return config.BuildConfig.ImageName
+	}
+	if strings.HasPrefix(config.BuildConfig.ImageTag, "sha256:") {
+		return config.BuildConfig.ImageName + "@" + config.BuildConfig.ImageTag
+	}
+	return config.BuildConfig.ImageName + ":" + config.BuildConfig.ImageTag
+}
+
+// splitImage splits an image reference into the name and tag (or digest) parts
+func splitImage(image string) (string, string) {
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, ""
+}
+
+// repoLabel turns a repository full name into a valid label value, since "/" is not allowed
+func repoLabel(fullName string) string {
+	value := strings.ReplaceAll(fullName, "/", ".")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "._-")
+}
+
 // Helper function to sanitize names for Kubernetes
 func sanitizeName(name string) string {
 	name = strings.ToLower(name)
@@ -0,0 +1,286 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DetectDrift compares a deployment record with the live objects named in its KubernetesInfo
+func (c *Client) DetectDrift(ctx context.Context, deployment *entities.Deployment) ([]entities.DriftItem, error) {
+	info := deployment.KubernetesInfo
+	namespace := deployment.Namespace
+	config := deployment.Configuration
+	items := []entities.DriftItem{}
+
+	// 1. Workload
+	template, replicas, kind, err := c.getWorkload(ctx, namespace, info)
+	if apierrors.IsNotFound(err) {
+		items = append(items, entities.DriftItem{
+			Type:   entities.DriftTypeMissing,
+			Object: fmt.Sprintf("%s/%s", kind, info.DeploymentName),
+		})
+	} else if err != nil {
+		return nil, err
+	} else {
+		object := fmt.Sprintf("%s/%s", kind, info.DeploymentName)
+
+		// Replicas are owned by the HPA when autoscaling is enabled
+		if !config.AutoScaling.Enabled && replicas != config.Replicas {
+			items = append(items, entities.DriftItem{
+				Type:     entities.DriftTypeReplicas,
+				Object:   object,
+				Field:    "spec.replicas",
+				Expected: fmt.Sprintf("%d", config.Replicas),
+				Actual:   fmt.Sprintf("%d", replicas),
+			})
+		}
+
+		if len(template.Spec.Containers) > 0 {
+			container := template.Spec.Containers[0]
+			if container.Image != containerImage(config) {
+				items = append(items, entities.DriftItem{
+					Type:     entities.DriftTypeImage,
+					Object:   object,
+					Field:    "spec.template.spec.containers[0].image",
+					Expected: containerImage(config),
+					Actual:   container.Image,
+				})
+			}
+
+			expected := describeEnv(buildPodTemplate(info.DeploymentName, config).Spec.Containers[0].Env)
+			actual := describeEnv(container.Env)
+			if expected != actual {
+				items = append(items, entities.DriftItem{
+					Type:     entities.DriftTypeEnv,
+					Object:   object,
+					Field:    "spec.template.spec.containers[0].env",
+					Expected: expected,
+					Actual:   actual,
+				})
+			}
+		}
+	}
+
+	// 2. ConfigMap
+	if info.ConfigMapName != "" {
+		configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, info.ConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			items = append(items, entities.DriftItem{
+				Type:   entities.DriftTypeMissing,
+				Object: fmt.Sprintf("ConfigMap/%s", info.ConfigMapName),
+			})
+		} else if err != nil {
+			return nil, err
+		} else if describeMap(configMap.Data) != describeMap(config.EnvironmentVars) {
+			items = append(items, entities.DriftItem{
+				Type:     entities.DriftTypeEnv,
+				Object:   fmt.Sprintf("ConfigMap/%s", info.ConfigMapName),
+				Field:    "data",
+				Expected: describeMap(config.EnvironmentVars),
+				Actual:   describeMap(configMap.Data),
+			})
+		}
+	}
+
+	// 3. Services and Ingress only need to exist
+	for _, name := range []string{info.ServiceName, info.HeadlessServiceName} {
+		if name == "" {
+			continue
+		}
+		_, err := c.clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			items = append(items, entities.DriftItem{
+				Type:   entities.DriftTypeMissing,
+				Object: fmt.Sprintf("Service/%s", name),
+			})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if info.IngressName != "" {
+		_, err := c.clientset.NetworkingV1().Ingresses(namespace).Get(ctx, info.IngressName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			items = append(items, entities.DriftItem{
+				Type:   entities.DriftTypeMissing,
+				Object: fmt.Sprintf("Ingress/%s", info.IngressName),
+			})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// ReconcileApplication recreates missing objects and resets drifted workload fields
+func (c *Client) ReconcileApplication(ctx context.Context, deployment *entities.Deployment) error {
+	info := deployment.KubernetesInfo
+	namespace := deployment.Namespace
+	config := deployment.Configuration
+	name := info.DeploymentName
+
+	// ConfigMap
+	if info.ConfigMapName != "" {
+		configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, info.ConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if err := c.createConfigMap(ctx, namespace, name, config.EnvironmentVars, deployment); err != nil {
+				return fmt.Errorf("failed to recreate configmap: %w", err)
+			}
+		} else if err != nil {
+			return err
+		} else if describeMap(configMap.Data) != describeMap(config.EnvironmentVars) {
+			configMap.Data = config.EnvironmentVars
+			if _, err := c.clientset.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to update configmap: %w", err)
+			}
+		}
+	}
+
+	// Workload
+	if info.WorkloadKind == entities.WorkloadKindStatefulSet {
+		if info.HeadlessServiceName != "" {
+			if err := c.ensureExists(func() error {
+				_, err := c.clientset.CoreV1().Services(namespace).Get(ctx, info.HeadlessServiceName, metav1.GetOptions{})
+				return err
+			}, func() error {
+				return c.createHeadlessService(ctx, namespace, name, config.ContainerPort, deployment)
+			}); err != nil {
+				return fmt.Errorf("failed to recreate headless service: %w", err)
+			}
+		}
+
+		statefulSet, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if err := c.createStatefulSet(ctx, namespace, deployment); err != nil {
+				return fmt.Errorf("failed to recreate statefulset: %w", err)
+			}
+		} else if err != nil {
+			return err
+		} else {
+			resetWorkload(&statefulSet.Spec.Template, name, config)
+			if !config.AutoScaling.Enabled {
+				statefulSet.Spec.Replicas = &config.Replicas
+			}
+			if _, err := c.clientset.AppsV1().StatefulSets(namespace).Update(ctx, statefulSet, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to update statefulset: %w", err)
+			}
+		}
+	} else {
+		k8sDeployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if err := c.createDeployment(ctx, namespace, deployment); err != nil {
+				return fmt.Errorf("failed to recreate deployment: %w", err)
+			}
+		} else if err != nil {
+			return err
+		} else {
+			resetWorkload(&k8sDeployment.Spec.Template, name, config)
+			if !config.AutoScaling.Enabled {
+				k8sDeployment.Spec.Replicas = &config.Replicas
+			}
+			if _, err := c.clientset.AppsV1().Deployments(namespace).Update(ctx, k8sDeployment, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to update deployment: %w", err)
+			}
+		}
+	}
+
+	// Service
+	if info.ServiceName != "" {
+		if err := c.ensureExists(func() error {
+			_, err := c.clientset.CoreV1().Services(namespace).Get(ctx, info.ServiceName, metav1.GetOptions{})
+			return err
+		}, func() error {
+			return c.createService(ctx, namespace, name, config.ServicePort, config.ContainerPort, deployment)
+		}); err != nil {
+			return fmt.Errorf("failed to recreate service: %w", err)
+		}
+	}
+
+	// Ingress
+	if info.IngressName != "" {
+		if err := c.ensureExists(func() error {
+			_, err := c.clientset.NetworkingV1().Ingresses(namespace).Get(ctx, info.IngressName, metav1.GetOptions{})
+			return err
+		}, func() error {
+			return c.createIngress(ctx, namespace, name, deployment.ContextPath, config.ServicePort, deployment)
+		}); err != nil {
+			return fmt.Errorf("failed to recreate ingress: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getWorkload fetches the pod template and replica count of a deployment's workload
+func (c *Client) getWorkload(ctx context.Context, namespace string, info entities.K8sDeploymentInfo) (*corev1.PodTemplateSpec, int32, entities.WorkloadKind, error) {
+	if info.WorkloadKind == entities.WorkloadKindStatefulSet {
+		statefulSet, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, info.DeploymentName, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, entities.WorkloadKindStatefulSet, err
+		}
+		return &statefulSet.Spec.Template, replicaCount(statefulSet.Spec.Replicas), entities.WorkloadKindStatefulSet, nil
+	}
+
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, info.DeploymentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, 0, entities.WorkloadKindDeployment, err
+	}
+	return &deployment.Spec.Template, replicaCount(deployment.Spec.Replicas), entities.WorkloadKindDeployment, nil
+}
+
+// ensureExists runs create when get reports that the object is missing
+func (c *Client) ensureExists(get func() error, create func() error) error {
+	err := get()
+	if apierrors.IsNotFound(err) {
+		return create()
+	}
+	return err
+}
+
+// resetWorkload restores the image and env of the application container
+func resetWorkload(template *corev1.PodTemplateSpec, name string, config entities.DeploymentConfig) {
+	if len(template.Spec.Containers) == 0 {
+		return
+	}
+	desired := buildPodTemplate(name, config).Spec.Containers[0]
+	template.Spec.Containers[0].Image = desired.Image
+	template.Spec.Containers[0].Env = desired.Env
+}
+
+func replicaCount(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// describeEnv renders env vars as a stable, comparable string
+func describeEnv(env []corev1.EnvVar) string {
+	parts := make([]string, 0, len(env))
+	for _, e := range env {
+		value := e.Value
+		if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+			value = fmt.Sprintf("secret:%s/%s", e.ValueFrom.SecretKeyRef.Name, e.ValueFrom.SecretKeyRef.Key)
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", e.Name, value))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func describeMap(data map[string]string) string {
+	parts := make([]string, 0, len(data))
+	for key, value := range data {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
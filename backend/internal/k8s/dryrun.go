@@ -0,0 +1,255 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fieldManager identifies the deployer in server-side apply requests
+const fieldManager = "espaze-node-deployer"
+
+// DryRunApplication renders a deployment, submits every object to the API server with
+// DryRun: All and diffs the server's answer against the live object
+func (c *Client) DryRunApplication(ctx context.Context, deployment *entities.Deployment) ([]entities.PreviewObject, error) {
+	objects, err := RenderApplication(deployment)
+	if err != nil {
+		return nil, err
+	}
+	leaveReplicasToHPA(objects, deployment)
+	namespace := applicationNamespace(deployment)
+
+	// Namespaced objects cannot be validated until their namespace exists
+	namespaceExists := true
+	if _, err := c.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		namespaceExists = false
+	} else if err != nil {
+		return nil, err
+	}
+
+	previews := make([]entities.PreviewObject, 0, len(objects))
+	for _, object := range objects {
+		manifest, err := manifestMap(object)
+		if err != nil {
+			return nil, err
+		}
+		meta := manifest["metadata"].(map[string]interface{})
+
+		preview := entities.PreviewObject{
+			Kind:      manifest["kind"].(string),
+			Name:      meta["name"].(string),
+			Namespace: namespace,
+			Action:    entities.PreviewActionCreate,
+			Manifest:  manifest,
+		}
+
+		if !namespaceExists {
+			preview.Warning = fmt.Sprintf("namespace %s does not exist yet and will be created; object was not validated", namespace)
+			previews = append(previews, preview)
+			continue
+		}
+
+		result, live, err := c.applyObject(ctx, object, true)
+		if err != nil {
+			preview.Error = err.Error()
+			previews = append(previews, preview)
+			continue
+		}
+
+		desired, err := manifestMap(result)
+		if err != nil {
+			return nil, err
+		}
+		normalizeManifest(desired)
+		preview.Manifest = desired
+
+		if live != nil {
+			current, err := manifestMap(live)
+			if err != nil {
+				return nil, err
+			}
+			normalizeManifest(current)
+
+			preview.Diff = diffManifests("", current, desired)
+			preview.Action = entities.PreviewActionUpdate
+			if len(preview.Diff) == 0 {
+				preview.Action = entities.PreviewActionUnchanged
+			}
+		}
+
+		previews = append(previews, preview)
+	}
+
+	return previews, nil
+}
+
+// applyObject server-side applies an object, optionally in dry-run mode, and returns the
+// resulting object and the previous live object, which is nil when it did not exist yet
+func (c *Client) applyObject(ctx context.Context, object runtime.Object, dryRun bool) (runtime.Object, runtime.Object, error) {
+	manifest, err := manifestMap(object)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	force := true
+	opts := metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	var result, live runtime.Object
+	var getErr error
+
+	switch o := object.(type) {
+	case *corev1.ConfigMap:
+		client := c.clientset.CoreV1().ConfigMaps(o.Namespace)
+		live, getErr = nilIfNotFound(client.Get(ctx, o.Name, metav1.GetOptions{}))
+		result, err = client.Patch(ctx, o.Name, types.ApplyPatchType, data, opts)
+	case *corev1.Service:
+		client := c.clientset.CoreV1().Services(o.Namespace)
+		live, getErr = nilIfNotFound(client.Get(ctx, o.Name, metav1.GetOptions{}))
+		result, err = client.Patch(ctx, o.Name, types.ApplyPatchType, data, opts)
+	case *appsv1.Deployment:
+		client := c.clientset.AppsV1().Deployments(o.Namespace)
+		live, getErr = nilIfNotFound(client.Get(ctx, o.Name, metav1.GetOptions{}))
+		result, err = client.Patch(ctx, o.Name, types.ApplyPatchType, data, opts)
+	case *appsv1.StatefulSet:
+		client := c.clientset.AppsV1().StatefulSets(o.Namespace)
+		live, getErr = nilIfNotFound(client.Get(ctx, o.Name, metav1.GetOptions{}))
+		result, err = client.Patch(ctx, o.Name, types.ApplyPatchType, data, opts)
+	case *networkingv1.Ingress:
+		client := c.clientset.NetworkingV1().Ingresses(o.Namespace)
+		live, getErr = nilIfNotFound(client.Get(ctx, o.Name, metav1.GetOptions{}))
+		result, err = client.Patch(ctx, o.Name, types.ApplyPatchType, data, opts)
+	case *autoscalingv2.HorizontalPodAutoscaler:
+		client := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(o.Namespace)
+		live, getErr = nilIfNotFound(client.Get(ctx, o.Name, metav1.GetOptions{}))
+		result, err = client.Patch(ctx, o.Name, types.ApplyPatchType, data, opts)
+	default:
+		return nil, nil, fmt.Errorf("unsupported object type %T", object)
+	}
+
+	if getErr != nil {
+		return nil, nil, getErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Typed clients drop apiVersion and kind from decoded objects
+	gvk := object.GetObjectKind().GroupVersionKind()
+	result.GetObjectKind().SetGroupVersionKind(gvk)
+	if live != nil {
+		live.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	return result, live, nil
+}
+
+// nilIfNotFound turns a NotFound lookup into a nil object without an error
+func nilIfNotFound[T runtime.Object](object T, err error) (runtime.Object, error) {
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// normalizeManifest strips server bookkeeping so live and desired objects compare cleanly
+func normalizeManifest(manifest map[string]interface{}) {
+	delete(manifest, "status")
+
+	metadata, ok := manifest["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp", "selfLink"} {
+		delete(metadata, field)
+	}
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		delete(annotations, "deployment.kubernetes.io/revision")
+		if len(annotations) == 0 {
+			delete(metadata, "annotations")
+		}
+	}
+}
+
+// diffManifests lists every leaf field whose value differs between live and desired
+func diffManifests(path string, live, desired interface{}) []entities.FieldDiff {
+	liveMap, liveIsMap := live.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	if liveIsMap && desiredIsMap {
+		keys := map[string]bool{}
+		for key := range liveMap {
+			keys[key] = true
+		}
+		for key := range desiredMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		diffs := []entities.FieldDiff{}
+		for _, key := range sorted {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			diffs = append(diffs, diffManifests(child, liveMap[key], desiredMap[key])...)
+		}
+		return diffs
+	}
+
+	liveList, liveIsList := live.([]interface{})
+	desiredList, desiredIsList := desired.([]interface{})
+	if liveIsList && desiredIsList && len(liveList) == len(desiredList) {
+		diffs := []entities.FieldDiff{}
+		for i := range liveList {
+			diffs = append(diffs, diffManifests(fmt.Sprintf("%s[%d]", path, i), liveList[i], desiredList[i])...)
+		}
+		return diffs
+	}
+
+	liveValue := describeValue(live)
+	desiredValue := describeValue(desired)
+	if liveValue == desiredValue {
+		return nil
+	}
+	return []entities.FieldDiff{{Path: path, Live: liveValue, Desired: desiredValue}}
+}
+
+func describeValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(out)
+}
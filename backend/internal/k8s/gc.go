@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ManagedLabelSelector selects every object created by the deployer
+const ManagedLabelSelector = "managed-by=espaze-node-deployer"
+
+// ListManagedObjects lists labelled objects across all namespaces. Namespaces and
+// PersistentVolumeClaims are left out because they hold data that must never be collected.
+func (c *Client) ListManagedObjects(ctx context.Context) ([]entities.ManagedObject, error) {
+	opts := metav1.ListOptions{LabelSelector: ManagedLabelSelector}
+	objects := []entities.ManagedObject{}
+
+	add := func(kind string, meta metav1.ObjectMeta) {
+		objects = append(objects, entities.ManagedObject{
+			Kind:      kind,
+			Namespace: meta.Namespace,
+			Name:      meta.Name,
+			App:       meta.Labels["app"],
+			CreatedAt: meta.CreationTimestamp.Time,
+		})
+	}
+
+	deployments, err := c.clientset.AppsV1().Deployments("").List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, item := range deployments.Items {
+		add("Deployment", item.ObjectMeta)
+	}
+
+	statefulSets, err := c.clientset.AppsV1().StatefulSets("").List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, item := range statefulSets.Items {
+		add("StatefulSet", item.ObjectMeta)
+	}
+
+	services, err := c.clientset.CoreV1().Services("").List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	for _, item := range services.Items {
+		add("Service", item.ObjectMeta)
+	}
+
+	ingresses, err := c.clientset.NetworkingV1().Ingresses("").List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	for _, item := range ingresses.Items {
+		add("Ingress", item.ObjectMeta)
+	}
+
+	configMaps, err := c.clientset.CoreV1().ConfigMaps("").List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configmaps: %w", err)
+	}
+	for _, item := range configMaps.Items {
+		add("ConfigMap", item.ObjectMeta)
+	}
+
+	hpas, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list horizontalpodautoscalers: %w", err)
+	}
+	for _, item := range hpas.Items {
+		add("HorizontalPodAutoscaler", item.ObjectMeta)
+	}
+
+	secrets, err := c.clientset.CoreV1().Secrets("").List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	for _, item := range secrets.Items {
+		add("Secret", item.ObjectMeta)
+	}
+
+	return objects, nil
+}
+
+// DeleteManagedObject deletes a single object returned by ListManagedObjects
+func (c *Client) DeleteManagedObject(ctx context.Context, object entities.ManagedObject) error {
+	propagationPolicy := metav1.DeletePropagationForeground
+	opts := metav1.DeleteOptions{PropagationPolicy: &propagationPolicy}
+
+	var err error
+	switch object.Kind {
+	case "Deployment":
+		err = c.clientset.AppsV1().Deployments(object.Namespace).Delete(ctx, object.Name, opts)
+	case "StatefulSet":
+		err = c.clientset.AppsV1().StatefulSets(object.Namespace).Delete(ctx, object.Name, opts)
+	case "Service":
+		err = c.clientset.CoreV1().Services(object.Namespace).Delete(ctx, object.Name, opts)
+	case "Ingress":
+		err = c.clientset.NetworkingV1().Ingresses(object.Namespace).Delete(ctx, object.Name, opts)
+	case "ConfigMap":
+		err = c.clientset.CoreV1().ConfigMaps(object.Namespace).Delete(ctx, object.Name, opts)
+	case "Secret":
+		err = c.clientset.CoreV1().Secrets(object.Namespace).Delete(ctx, object.Name, opts)
+	case "HorizontalPodAutoscaler":
+		err = c.clientset.AutoscalingV2().HorizontalPodAutoscalers(object.Namespace).Delete(ctx, object.Name, opts)
+	default:
+		return fmt.Errorf("unsupported kind: %s", object.Kind)
+	}
+
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,433 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+)
+
+// helmTimeout bounds how long Helm waits for a release's resources to become ready
+const helmTimeout = 10 * time.Minute
+
+// SetHelmBinary sets the helm executable used for chart deployments
+func (c *Client) SetHelmBinary(path string) {
+	c.helmBinary = path
+}
+
+// HelmReleaseName returns the release name used for a Helm-sourced deployment
+func HelmReleaseName(deployment *entities.Deployment) string {
+	if deployment.Source.ReleaseName != "" {
+		return deployment.Source.ReleaseName
+	}
+	return sanitizeName(deployment.Name)
+}
+
+// HelmInstall installs or upgrades a release from a chart directory. Values from the
+// chart's values files are applied first and the deployment configuration on top.
+//
+// An existing release must have been installed for this deployment, and the rendered
+// chart may only hold namespaced objects in the deployment's namespace, the same rules
+// ApplyManifests enforces for manifests.
+func (c *Client) HelmInstall(ctx context.Context, deployment *entities.Deployment, chartDir string) (*entities.HelmRelease, error) {
+	namespace := applicationNamespace(deployment)
+	release := HelmReleaseName(deployment)
+	if err := c.checkReleaseOwner(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	values, err := yaml.Marshal(helmValues(deployment))
+	if err != nil {
+		return nil, err
+	}
+	valuesFile := filepath.Join(chartDir, ".espaze-values.yaml")
+	if err := os.WriteFile(valuesFile, values, 0600); err != nil {
+		return nil, err
+	}
+
+	valuesArgs := []string{}
+	for _, file := range deployment.Source.ValuesFiles {
+		valuesPath, err := chartFilePath(chartDir, file)
+		if err != nil {
+			return nil, err
+		}
+		valuesArgs = append(valuesArgs, "--values", valuesPath)
+	}
+	valuesArgs = append(valuesArgs, "--values", valuesFile)
+
+	// Render the chart, hooks included, and check its objects before installing any
+	rendered, err := c.runHelm(ctx, append([]string{"template", release, chartDir, "--namespace", namespace}, valuesArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := decodeManifests("chart", bytes.NewReader(rendered))
+	if err != nil {
+		return nil, err
+	}
+	_, mapper, err := c.dynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := confineToNamespace(mapper, objects, namespace); err != nil {
+		return nil, err
+	}
+	if err := c.ensureNamespace(ctx, namespace); err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"upgrade", "--install", release, chartDir,
+		"--namespace", namespace,
+		"--create-namespace",
+		"--labels", DeploymentIDLabel + "=" + deployment.ID.Hex(),
+		"--wait",
+		"--timeout", helmTimeout.String(),
+		"--output", "json",
+	}
+	args = append(args, valuesArgs...)
+
+	out, err := c.runHelm(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseHelmRelease(out)
+}
+
+// chartFilePath resolves a file of the chart, following symlinks, and refuses one that
+// ends up outside the chart directory
+func chartFilePath(chartDir, file string) (string, error) {
+	root, err := filepath.EvalSymlinks(chartDir)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		return "", fmt.Errorf("values file %s not found in the chart", file)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("values file %s is outside the chart", file)
+	}
+	return resolved, nil
+}
+
+// HelmStatus returns the current state of a release
+func (c *Client) HelmStatus(ctx context.Context, namespace, release string) (*entities.HelmRelease, error) {
+	out, err := c.runHelm(ctx, "status", release, "--namespace", namespace, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseHelmRelease(out)
+}
+
+// HelmHistory lists the revisions of a release, oldest first
+func (c *Client) HelmHistory(ctx context.Context, namespace, release string) ([]entities.HelmRevision, error) {
+	out, err := c.runHelm(ctx, "history", release, "--namespace", namespace, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		Revision    int       `json:"revision"`
+		Updated     time.Time `json:"updated"`
+		Status      string    `json:"status"`
+		Chart       string    `json:"chart"`
+		AppVersion  string    `json:"app_version"`
+		Description string    `json:"description"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse helm history: %w", err)
+	}
+
+	history := make([]entities.HelmRevision, 0, len(raw))
+	for _, r := range raw {
+		history = append(history, entities.HelmRevision{
+			Revision:    r.Revision,
+			Updated:     r.Updated,
+			Status:      r.Status,
+			Chart:       r.Chart,
+			AppVersion:  r.AppVersion,
+			Description: r.Description,
+		})
+	}
+	return history, nil
+}
+
+// HelmRollback rolls a deployment's release back to a revision, or to the previous one
+// when revision is 0
+func (c *Client) HelmRollback(ctx context.Context, deployment *entities.Deployment, revision int) error {
+	if err := c.checkReleaseOwner(ctx, deployment); err != nil {
+		return err
+	}
+
+	namespace := applicationNamespace(deployment)
+	args := []string{"rollback", HelmReleaseName(deployment)}
+	if revision > 0 {
+		args = append(args, strconv.Itoa(revision))
+	}
+	args = append(args, "--namespace", namespace, "--wait", "--timeout", helmTimeout.String())
+
+	_, err := c.runHelm(ctx, args...)
+	return err
+}
+
+// HelmUninstall removes a deployment's release and waits for its resources to be
+// deleted. A release that is already gone counts as uninstalled, and a release of the
+// same name that was not installed for the deployment is left alone.
+func (c *Client) HelmUninstall(ctx context.Context, deployment *entities.Deployment) error {
+	if err := c.checkReleaseOwner(ctx, deployment); errors.Is(err, ErrReleaseNotOwned) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	_, err := c.runHelm(ctx, "uninstall", HelmReleaseName(deployment), "--namespace", applicationNamespace(deployment), "--wait", "--timeout", helmTimeout.String())
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		return nil
+	}
+	return err
+}
+
+// ErrReleaseNotOwned is returned for a Helm release that was not installed for the
+// deployment acting on it
+var ErrReleaseNotOwned = errors.New("helm release does not belong to this deployment")
+
+// checkReleaseOwner refuses a release that exists but was not installed for the
+// deployment. Helm keeps a release's labels on the Secret of each revision; the latest
+// revision must carry the deployment's ID label. Releases installed before the label
+// was set are accepted when the deployment recorded them.
+func (c *Client) checkReleaseOwner(ctx context.Context, deployment *entities.Deployment) error {
+	release := HelmReleaseName(deployment)
+	secrets, err := c.clientset.CoreV1().Secrets(applicationNamespace(deployment)).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("owner=helm,name=%s", release),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to look up helm release %s: %w", release, err)
+	}
+
+	var latest *corev1.Secret
+	latestVersion := 0
+	for i := range secrets.Items {
+		version, _ := strconv.Atoi(secrets.Items[i].Labels["version"])
+		if latest == nil || version > latestVersion {
+			latest, latestVersion = &secrets.Items[i], version
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+
+	owner, labelled := latest.Labels[DeploymentIDLabel]
+	switch {
+	case deployment.ID.IsZero():
+	case labelled && owner == deployment.ID.Hex():
+		return nil
+	case !labelled && deployment.HelmRelease != nil && deployment.HelmRelease.Name == release:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrReleaseNotOwned, release)
+}
+
+// RestartRelease restarts every Deployment and StatefulSet that belongs to a release
+func (c *Client) RestartRelease(ctx context.Context, namespace, release string) error {
+	opts := metav1.ListOptions{LabelSelector: fmt.Sprintf("app.kubernetes.io/instance=%s", release)}
+
+	deployments, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	statefulSets, err := c.clientset.AppsV1().StatefulSets(namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(deployments.Items) == 0 && len(statefulSets.Items) == 0 {
+		return fmt.Errorf("no workloads found for release %s", release)
+	}
+
+	for _, deployment := range deployments.Items {
+		if err := c.RestartDeployment(ctx, namespace, deployment.Name); err != nil {
+			return err
+		}
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if err := c.RestartDeployment(ctx, namespace, statefulSet.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// helmValues maps the deployment configuration onto the value names used by `helm create` charts
+func helmValues(deployment *entities.Deployment) map[string]interface{} {
+	config := deployment.Configuration
+
+	resources := map[string]interface{}{}
+	requests := map[string]string{}
+	limits := map[string]string{}
+	if config.CPURequest != "" {
+		requests["cpu"] = config.CPURequest
+	}
+	if config.MemoryRequest != "" {
+		requests["memory"] = config.MemoryRequest
+	}
+	if config.CPULimit != "" {
+		limits["cpu"] = config.CPULimit
+	}
+	if config.MemoryLimit != "" {
+		limits["memory"] = config.MemoryLimit
+	}
+	if len(requests) > 0 {
+		resources["requests"] = requests
+	}
+	if len(limits) > 0 {
+		resources["limits"] = limits
+	}
+
+	values := map[string]interface{}{
+		"replicaCount": config.Replicas,
+		"resources":    resources,
+	}
+
+	// Only an image given explicitly replaces the chart's own; the deployer's default
+	// image name means nothing to a chart
+	image := map[string]interface{}{}
+	if overrides := deployment.ConfigOverrides.BuildConfig; overrides.ImageName != "" {
+		image["repository"] = overrides.ImageName
+		if overrides.ImageTag != "" {
+			image["tag"] = overrides.ImageTag
+		}
+	}
+	if config.ImagePullPolicy != "" {
+		image["pullPolicy"] = config.ImagePullPolicy
+	}
+	if len(image) > 0 {
+		values["image"] = image
+	}
+
+	if config.ServicePort != 0 {
+		values["service"] = map[string]interface{}{"port": config.ServicePort}
+	}
+	if len(config.EnvironmentVars) > 0 {
+		values["env"] = config.EnvironmentVars
+	}
+	if config.AutoScaling.Enabled {
+		values["autoscaling"] = map[string]interface{}{
+			"enabled":                           true,
+			"minReplicas":                       config.AutoScaling.MinReplicas,
+			"maxReplicas":                       config.AutoScaling.MaxReplicas,
+			"targetCPUUtilizationPercentage":    config.AutoScaling.TargetCPUUtilization,
+			"targetMemoryUtilizationPercentage": config.AutoScaling.TargetMemoryUtilization,
+		}
+	}
+
+	return values
+}
+
+// runHelm runs the helm CLI against this client's cluster and returns its stdout
+func (c *Client) runHelm(ctx context.Context, args ...string) ([]byte, error) {
+	kubeconfig, err := c.writeKubeconfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write kubeconfig for helm: %w", err)
+	}
+	defer os.Remove(kubeconfig)
+
+	cmd := exec.CommandContext(ctx, c.helmBinary, append(args, "--kubeconfig", kubeconfig)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("helm %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// writeKubeconfig writes the client's connection settings to a temporary kubeconfig file
+func (c *Client) writeKubeconfig() (string, error) {
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = c.config.Host
+	cluster.CertificateAuthority = c.config.CAFile
+	cluster.CertificateAuthorityData = c.config.CAData
+	cluster.InsecureSkipTLSVerify = c.config.Insecure
+	cluster.TLSServerName = c.config.ServerName
+
+	authInfo := clientcmdapi.NewAuthInfo()
+	authInfo.Token = c.config.BearerToken
+	authInfo.TokenFile = c.config.BearerTokenFile
+	authInfo.ClientCertificate = c.config.CertFile
+	authInfo.ClientCertificateData = c.config.CertData
+	authInfo.ClientKey = c.config.KeyFile
+	authInfo.ClientKeyData = c.config.KeyData
+	authInfo.Username = c.config.Username
+	authInfo.Password = c.config.Password
+	authInfo.Exec = c.config.ExecProvider
+
+	kubeContext := clientcmdapi.NewContext()
+	kubeContext.Cluster = "cluster"
+	kubeContext.AuthInfo = "user"
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["cluster"] = cluster
+	kubeconfig.AuthInfos["user"] = authInfo
+	kubeconfig.Contexts["default"] = kubeContext
+	kubeconfig.CurrentContext = "default"
+
+	file, err := os.CreateTemp("", "espaze-kubeconfig-*")
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+
+	if err := clientcmd.WriteToFile(*kubeconfig, file.Name()); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+func parseHelmRelease(out []byte) (*entities.HelmRelease, error) {
+	var raw struct {
+		Name string `json:"name"`
+		Info struct {
+			LastDeployed time.Time `json:"last_deployed"`
+			Description  string    `json:"description"`
+			Status       string    `json:"status"`
+		} `json:"info"`
+		Chart struct {
+			Metadata struct {
+				Name       string `json:"name"`
+				Version    string `json:"version"`
+				AppVersion string `json:"appVersion"`
+			} `json:"metadata"`
+		} `json:"chart"`
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse helm release: %w", err)
+	}
+
+	return &entities.HelmRelease{
+		Name:           raw.Name,
+		Revision:       raw.Version,
+		Status:         raw.Info.Status,
+		ChartName:      raw.Chart.Metadata.Name,
+		ChartVersion:   raw.Chart.Metadata.Version,
+		AppVersion:     raw.Chart.Metadata.AppVersion,
+		Description:    raw.Info.Description,
+		LastDeployedAt: raw.Info.LastDeployed,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (c *Client) createHPA(ctx context.Context, namespace string, deployment *entities.Deployment) error {
+	hpa := buildHPA(namespace, deployment)
+	labelOwner(hpa, deployment)
+	_, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Create(ctx, hpa, metav1.CreateOptions{})
+	return err
+}
+
+// buildHPA builds the HorizontalPodAutoscaler for a deployment with autoscaling enabled
+func buildHPA(namespace string, deployment *entities.Deployment) *autoscalingv2.HorizontalPodAutoscaler {
+	name := sanitizeName(deployment.Name)
+	config := deployment.Configuration.AutoScaling
+
+	kind := string(entities.WorkloadKindDeployment)
+	if deployment.Configuration.WorkloadKind == entities.WorkloadKindStatefulSet {
+		kind = string(entities.WorkloadKindStatefulSet)
+	}
+
+	minReplicas := config.MinReplicas
+	if minReplicas < 1 {
+		minReplicas = 1
+	}
+	maxReplicas := config.MaxReplicas
+	if maxReplicas < minReplicas {
+		maxReplicas = minReplicas
+	}
+
+	metrics := []autoscalingv2.MetricSpec{}
+	if config.TargetCPUUtilization > 0 {
+		metrics = append(metrics, utilizationMetric(corev1.ResourceCPU, config.TargetCPUUtilization))
+	}
+	if config.TargetMemoryUtilization > 0 {
+		metrics = append(metrics, utilizationMetric(corev1.ResourceMemory, config.TargetMemoryUtilization))
+	}
+
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-hpa", name),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":        name,
+				"managed-by": "espaze-node-deployer",
+			},
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       kind,
+				Name:       name,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: maxReplicas,
+			Metrics:     metrics,
+		},
+	}
+}
+
+func utilizationMetric(name corev1.ResourceName, target int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &target,
+			},
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApplicationLogs returns the last lines of every pod matching selector, each under a
+// header naming the pod
+func (c *Client) ApplicationLogs(ctx context.Context, namespace, selector string, tailLines int64) (string, error) {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 {
+		return "", fmt.Errorf("no pods match %s", selector)
+	}
+
+	var out strings.Builder
+	for _, pod := range pods.Items {
+		fmt.Fprintf(&out, "==> %s <==\n", pod.Name)
+		logs, err := c.GetPodLogs(ctx, namespace, pod.Name, tailLines)
+		if err != nil {
+			fmt.Fprintf(&out, "failed to read logs: %v\n", err)
+			continue
+		}
+		out.WriteString(logs)
+		if !strings.HasSuffix(logs, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,444 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// DeploymentIDLabel records which deployment an object applied from manifests belongs to
+const DeploymentIDLabel = "espaze-deployment-id"
+
+// kustomizationFiles are the file names kustomize recognises as a kustomization root
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// SetKubectlBinary sets the kubectl executable used to build kustomizations
+func (c *Client) SetKubectlBinary(path string) {
+	c.kubectlBinary = path
+}
+
+// IsKustomization reports whether a file name marks a kustomization root
+func IsKustomization(name string) bool {
+	for _, file := range kustomizationFiles {
+		if name == file {
+			return true
+		}
+	}
+	return false
+}
+
+// IsManifestFile reports whether a file looks like a Kubernetes manifest
+func IsManifestFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// LoadManifests reads every object from a directory. A kustomization is built with
+// kubectl kustomize; otherwise all YAML and JSON files below the directory are read
+// in path order.
+func (c *Client) LoadManifests(ctx context.Context, dir string) ([]*unstructured.Unstructured, error) {
+	for _, file := range kustomizationFiles {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			out, err := c.runKustomize(ctx, dir)
+			if err != nil {
+				return nil, err
+			}
+			return decodeManifests("kustomization", bytes.NewReader(out))
+		}
+	}
+
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && IsManifestFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	objects := []*unstructured.Unstructured{}
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		decoded, err := decodeManifests(filepath.ToSlash(rel), file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, decoded...)
+	}
+
+	if len(objects) == 0 {
+		return nil, errors.New("no Kubernetes objects found in manifests")
+	}
+	return objects, nil
+}
+
+// DecodeManifests reads the objects of YAML or JSON documents, e.g. manifests handed to
+// an agent
+func DecodeManifests(source, data string) ([]*unstructured.Unstructured, error) {
+	return decodeManifests(source, strings.NewReader(data))
+}
+
+// ApplyManifests server-side applies the objects of a manifests deployment with the
+// deployer's labels and prunes objects applied by an earlier rollout that are no longer
+// part of the set. It returns the objects that now belong to the deployment.
+//
+// Only namespaced objects in the deployment's namespace are accepted, and an object
+// that already exists must carry the deployment's ID label, so manifests cannot reach
+// into other namespaces or take over objects they did not create.
+func (c *Client) ApplyManifests(ctx context.Context, deployment *entities.Deployment, objects []*unstructured.Unstructured) ([]entities.AppliedObject, error) {
+	dynamicClient, mapper, err := c.dynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := applicationNamespace(deployment)
+	if err := c.ensureNamespace(ctx, namespace); err != nil {
+		return nil, err
+	}
+
+	// Check every object before applying any
+	resources, err := confineToNamespace(mapper, objects, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i, object := range objects {
+		existing, err := dynamicClient.Resource(resources[i]).Namespace(namespace).Get(ctx, object.GetName(), metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get %s %s: %w", object.GetKind(), object.GetName(), err)
+		}
+		if err == nil && !ownedBy(existing, deployment) {
+			return nil, fmt.Errorf("%s %s already exists and does not belong to this deployment", object.GetKind(), object.GetName())
+		}
+	}
+
+	applied := make([]entities.AppliedObject, 0, len(objects))
+	current := map[entities.AppliedObject]bool{}
+
+	for i, object := range objects {
+		resource := resources[i]
+
+		labels := object.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels["managed-by"] = "espaze-node-deployer"
+		labels[DeploymentIDLabel] = deployment.ID.Hex()
+		if _, ok := labels["app"]; !ok {
+			labels["app"] = sanitizeName(deployment.Name)
+		}
+		object.SetLabels(labels)
+
+		_, err := dynamicClient.Resource(resource).Namespace(object.GetNamespace()).Apply(ctx, object.GetName(), object, metav1.ApplyOptions{
+			FieldManager: fieldManager,
+			Force:        true,
+		})
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply %s %s: %w", object.GetKind(), object.GetName(), err)
+		}
+
+		ref := entities.AppliedObject{
+			APIVersion: object.GetAPIVersion(),
+			Kind:       object.GetKind(),
+			Namespace:  object.GetNamespace(),
+			Name:       object.GetName(),
+		}
+		applied = append(applied, ref)
+		current[ref] = true
+	}
+
+	// Remove what the previous rollout applied and the manifests no longer contain
+	stale := []entities.AppliedObject{}
+	for _, ref := range deployment.AppliedObjects {
+		if !current[ref] {
+			stale = append(stale, ref)
+		}
+	}
+	if _, err := c.DeleteManifests(ctx, deployment, stale); err != nil {
+		return applied, fmt.Errorf("failed to prune objects: %w", err)
+	}
+
+	return applied, nil
+}
+
+// confineToNamespace refuses cluster-scoped objects and objects in another namespace,
+// places objects without a namespace in it and returns the resource of each object
+func confineToNamespace(mapper meta.RESTMapper, objects []*unstructured.Unstructured, namespace string) ([]schema.GroupVersionResource, error) {
+	resources := make([]schema.GroupVersionResource, len(objects))
+	for i, object := range objects {
+		resource, namespaced, err := resourceFor(mapper, object.GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+		if !namespaced {
+			return nil, fmt.Errorf("%s %s: cluster-scoped objects cannot be deployed", object.GetKind(), object.GetName())
+		}
+		if object.GetNamespace() == "" {
+			object.SetNamespace(namespace)
+		}
+		if object.GetNamespace() != namespace {
+			return nil, fmt.Errorf("%s %s: objects must be in namespace %s, not %s", object.GetKind(), object.GetName(), namespace, object.GetNamespace())
+		}
+		resources[i] = resource
+	}
+	return resources, nil
+}
+
+// ManifestsStatus reports whether each applied object exists and, for workloads, is ready
+func (c *Client) ManifestsStatus(ctx context.Context, objects []entities.AppliedObject) ([]entities.AppliedObjectStatus, error) {
+	dynamicClient, mapper, err := c.dynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]entities.AppliedObjectStatus, 0, len(objects))
+	for _, ref := range objects {
+		status := entities.AppliedObjectStatus{AppliedObject: ref}
+
+		live, err := getAppliedObject(ctx, dynamicClient, mapper, ref)
+		switch {
+		case apierrors.IsNotFound(err):
+			status.Message = "not found"
+		case err != nil:
+			status.Message = err.Error()
+		default:
+			status.Exists = true
+			status.Ready, status.Message = objectReadiness(live)
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// DeleteManifests deletes applied objects in reverse apply order and returns the ones
+// it deleted. Objects outside the deployment's namespace or no longer labelled with its
+// ID are left alone, and objects that are already gone count as deleted; every other
+// failure is returned.
+func (c *Client) DeleteManifests(ctx context.Context, deployment *entities.Deployment, objects []entities.AppliedObject) ([]entities.AppliedObject, error) {
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	dynamicClient, mapper, err := c.dynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	namespace := applicationNamespace(deployment)
+
+	propagationPolicy := metav1.DeletePropagationForeground
+	deleteOptions := metav1.DeleteOptions{PropagationPolicy: &propagationPolicy}
+
+	deleted := []entities.AppliedObject{}
+	var errs []error
+	for i := len(objects) - 1; i >= 0; i-- {
+		ref := objects[i]
+		if ref.Namespace != namespace {
+			continue
+		}
+		resource, namespaced, err := resourceFor(mapper, schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !namespaced {
+			continue
+		}
+
+		live, err := dynamicClient.Resource(resource).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get %s %s: %w", ref.Kind, ref.Name, err))
+			continue
+		}
+		if !ownedBy(live, deployment) {
+			continue
+		}
+
+		err = dynamicClient.Resource(resource).Namespace(ref.Namespace).Delete(ctx, ref.Name, deleteOptions)
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", ref.Kind, ref.Name, err))
+			continue
+		}
+		deleted = append(deleted, ref)
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+// ownedBy reports whether an object was created by the deployer for the deployment
+func ownedBy(object metav1.Object, deployment *entities.Deployment) bool {
+	labels := object.GetLabels()
+	return !deployment.ID.IsZero() && labels["managed-by"] == "espaze-node-deployer" && labels[DeploymentIDLabel] == deployment.ID.Hex()
+}
+
+// WaitForManifestsDeletion blocks until every applied object is gone or the timeout expires
+func (c *Client) WaitForManifestsDeletion(ctx context.Context, objects []entities.AppliedObject, timeout time.Duration) error {
+	dynamicClient, mapper, err := c.dynamicClient()
+	if err != nil {
+		return err
+	}
+
+	var remaining []string
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		remaining = remaining[:0]
+		for _, ref := range objects {
+			_, err := getAppliedObject(ctx, dynamicClient, mapper, ref)
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			remaining = append(remaining, fmt.Sprintf("%s/%s", ref.Kind, ref.Name))
+		}
+		return len(remaining) == 0, nil
+	})
+	if err != nil && len(remaining) > 0 {
+		return fmt.Errorf("objects still present: %s: %w", strings.Join(remaining, ", "), err)
+	}
+	return err
+}
+
+// dynamicClient returns a dynamic client and a REST mapper backed by the cluster's discovery API
+func (c *Client) dynamicClient() (dynamic.Interface, meta.RESTMapper, error) {
+	dynamicClient, err := dynamic.NewForConfig(c.config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(c.clientset.Discovery()))
+	return dynamicClient, mapper, nil
+}
+
+// resourceFor maps a kind to its resource and reports whether it is namespaced
+func resourceFor(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (schema.GroupVersionResource, bool, error) {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("unknown kind %s: %w", gvk, err)
+	}
+	return mapping.Resource, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+func getAppliedObject(ctx context.Context, dynamicClient dynamic.Interface, mapper meta.RESTMapper, ref entities.AppliedObject) (*unstructured.Unstructured, error) {
+	mapping, err := mapper.RESTMapping(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind).GroupKind())
+	if err != nil {
+		return nil, err
+	}
+	return dynamicClient.Resource(mapping.Resource).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+}
+
+// objectReadiness judges workloads by their replica counts; other objects are ready once they exist
+func objectReadiness(object *unstructured.Unstructured) (bool, string) {
+	readyField := "readyReplicas"
+	desiredField := "replicas"
+
+	switch object.GetKind() {
+	case "Deployment", "StatefulSet", "ReplicaSet":
+	case "DaemonSet":
+		readyField = "numberReady"
+		desiredField = "desiredNumberScheduled"
+	case "Job":
+		succeeded, _, _ := unstructured.NestedInt64(object.Object, "status", "succeeded")
+		if succeeded > 0 {
+			return true, ""
+		}
+		return false, "job has not completed"
+	default:
+		return true, ""
+	}
+
+	desired, found, _ := unstructured.NestedInt64(object.Object, "status", desiredField)
+	if !found {
+		desired, _, _ = unstructured.NestedInt64(object.Object, "spec", "replicas")
+	}
+	ready, _, _ := unstructured.NestedInt64(object.Object, "status", readyField)
+	if ready < desired {
+		return false, fmt.Sprintf("%d/%d ready", ready, desired)
+	}
+	return true, ""
+}
+
+// decodeManifests splits a stream of YAML or JSON documents into objects, expanding lists
+func decodeManifests(source string, reader io.Reader) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(reader, 4096)
+	objects := []*unstructured.Unstructured{}
+
+	for {
+		var document map[string]interface{}
+		if err := decoder.Decode(&document); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		if len(document) == 0 {
+			continue
+		}
+
+		object := &unstructured.Unstructured{Object: document}
+		if object.IsList() {
+			list, err := object.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+
+		if object.GetAPIVersion() == "" || object.GetKind() == "" || object.GetName() == "" {
+			return nil, fmt.Errorf("%s: every object needs apiVersion, kind and metadata.name", source)
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// runKustomize builds a kustomization with the kubectl CLI and returns the rendered YAML
+func (c *Client) runKustomize(ctx context.Context, dir string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, c.kubectlBinary, "kustomize", dir)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("kustomize build failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,358 @@
+package k8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// Placeholders swapped for template expressions when rendering a Helm chart
+const (
+	helmNamespacePlaceholder = "__ESPAZE_NAMESPACE__"
+	helmImagePlaceholder     = "__ESPAZE_IMAGE__"
+	helmReplicasPlaceholder  = "__ESPAZE_REPLICAS__"
+)
+
+// RenderApplication builds the objects DeployApplication would apply for a deployment, in apply order
+func RenderApplication(deployment *entities.Deployment) ([]runtime.Object, error) {
+	namespace := applicationNamespace(deployment)
+	name := sanitizeName(deployment.Name)
+	config := deployment.Configuration
+
+	objects := []runtime.Object{}
+
+	if len(config.EnvironmentVars) > 0 {
+		objects = append(objects, buildConfigMap(namespace, name, config.EnvironmentVars))
+	}
+
+	switch config.WorkloadKind {
+	case entities.WorkloadKindStatefulSet:
+		statefulSet, err := buildStatefulSet(namespace, deployment)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, buildHeadlessService(namespace, name, config.ContainerPort), statefulSet)
+	default:
+		objects = append(objects, buildDeployment(namespace, deployment))
+	}
+
+	objects = append(objects, buildService(namespace, name, config.ServicePort, config.ContainerPort))
+
+	if deployment.ContextPath != "" {
+		objects = append(objects, buildIngress(namespace, name, deployment.ContextPath, config.ServicePort))
+	}
+
+	if config.AutoScaling.Enabled {
+		objects = append(objects, buildHPA(namespace, deployment))
+	}
+
+	// Typed objects leave apiVersion and kind empty until they are sent to the API server
+	for _, object := range objects {
+		gvks, _, err := scheme.Scheme.ObjectKinds(object)
+		if err != nil {
+			return nil, err
+		}
+		object.GetObjectKind().SetGroupVersionKind(gvks[0])
+
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, err
+		}
+		labelOwner(accessor, deployment)
+	}
+
+	return objects, nil
+}
+
+// ApplicationObjects lists the objects RenderApplication builds for a deployment
+func ApplicationObjects(deployment *entities.Deployment) ([]entities.AppliedObject, error) {
+	objects, err := RenderApplication(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]entities.AppliedObject, 0, len(objects))
+	for _, object := range objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, err
+		}
+		gvk := object.GetObjectKind().GroupVersionKind()
+		refs = append(refs, entities.AppliedObject{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  accessor.GetNamespace(),
+			Name:       accessor.GetName(),
+		})
+	}
+	return refs, nil
+}
+
+// ExportManifests renders a deployment's objects as plain YAML, a Helm chart or a Kustomize base
+func ExportManifests(deployment *entities.Deployment, format entities.ManifestFormat) (*entities.ManifestExport, error) {
+	objects, err := RenderApplication(deployment)
+	if err != nil {
+		return nil, err
+	}
+	name := sanitizeName(deployment.Name)
+
+	switch format {
+	case entities.ManifestFormatYAML, "":
+		documents := make([]string, 0, len(objects))
+		for _, object := range objects {
+			manifest, err := manifestMap(object)
+			if err != nil {
+				return nil, err
+			}
+			out, err := yaml.Marshal(manifest)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, string(out))
+		}
+
+		return &entities.ManifestExport{
+			Format:      entities.ManifestFormatYAML,
+			FileName:    fmt.Sprintf("%s.yaml", name),
+			ContentType: "application/yaml",
+			Content:     []byte(strings.Join(documents, "---\n")),
+		}, nil
+
+	case entities.ManifestFormatKustomize:
+		files, err := renderKustomization(objects, applicationNamespace(deployment))
+		if err != nil {
+			return nil, err
+		}
+		return archiveManifests(format, name, fmt.Sprintf("%s-kustomize.tar.gz", name), files)
+
+	case entities.ManifestFormatHelm:
+		files, err := renderHelmChart(objects, deployment)
+		if err != nil {
+			return nil, err
+		}
+		return archiveManifests(format, name, fmt.Sprintf("%s-0.1.0.tgz", name), files)
+
+	default:
+		return nil, fmt.Errorf("unsupported manifest format: %s", format)
+	}
+}
+
+type manifestFile struct {
+	path    string
+	content []byte
+}
+
+// renderKustomization writes one file per object plus a kustomization.yaml listing them
+func renderKustomization(objects []runtime.Object, namespace string) ([]manifestFile, error) {
+	files := []manifestFile{}
+	resources := []string{}
+
+	for _, object := range objects {
+		manifest, err := manifestMap(object)
+		if err != nil {
+			return nil, err
+		}
+		out, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		path := manifestFileName(manifest)
+		resources = append(resources, path)
+		files = append(files, manifestFile{path: path, content: out})
+	}
+
+	kustomization, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"namespace":  namespace,
+		"resources":  resources,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]manifestFile{{path: "kustomization.yaml", content: kustomization}}, files...), nil
+}
+
+// renderHelmChart turns the objects into chart templates. The namespace, image and
+// replica count are lifted into values; everything else is rendered verbatim.
+func renderHelmChart(objects []runtime.Object, deployment *entities.Deployment) ([]manifestFile, error) {
+	name := sanitizeName(deployment.Name)
+	config := deployment.Configuration
+
+	appVersion := config.BuildConfig.ImageTag
+	if appVersion == "" {
+		appVersion = "latest"
+	}
+
+	chart, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion":  "v2",
+		"name":        name,
+		"description": fmt.Sprintf("Exported from espaze node deployer deployment %s", deployment.Name),
+		"type":        "application",
+		"version":     "0.1.0",
+		"appVersion":  appVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := yaml.Marshal(map[string]interface{}{
+		"replicaCount": config.Replicas,
+		"image":        containerImage(config),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := []manifestFile{
+		{path: "Chart.yaml", content: chart},
+		{path: "values.yaml", content: values},
+	}
+
+	replacer := strings.NewReplacer(
+		helmNamespacePlaceholder, "{{ .Release.Namespace }}",
+		helmImagePlaceholder, "{{ .Values.image | quote }}",
+		helmReplicasPlaceholder, "{{ .Values.replicaCount }}",
+	)
+
+	for _, object := range objects {
+		manifest, err := manifestMap(object)
+		if err != nil {
+			return nil, err
+		}
+
+		if metadata, ok := manifest["metadata"].(map[string]interface{}); ok {
+			metadata["namespace"] = helmNamespacePlaceholder
+		}
+		if kind := manifest["kind"]; kind == "Deployment" || kind == "StatefulSet" {
+			spec := manifest["spec"].(map[string]interface{})
+			spec["replicas"] = helmReplicasPlaceholder
+
+			podSpec := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})
+			containers := podSpec["containers"].([]interface{})
+			containers[0].(map[string]interface{})["image"] = helmImagePlaceholder
+		}
+
+		out, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, manifestFile{
+			path:    "templates/" + manifestFileName(manifest),
+			content: []byte(replacer.Replace(string(out))),
+		})
+	}
+
+	return files, nil
+}
+
+// archiveManifests packs files into a gzipped tarball under a top-level directory
+func archiveManifests(format entities.ManifestFormat, dir, fileName string, files []manifestFile) (*entities.ManifestExport, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	now := time.Now()
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    dir + "/" + file.path,
+			Mode:    0644,
+			Size:    int64(len(file.content)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(file.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return &entities.ManifestExport{
+		Format:      format,
+		FileName:    fileName,
+		ContentType: "application/gzip",
+		Content:     buf.Bytes(),
+	}, nil
+}
+
+// manifestMap converts a typed object to a plain map without server-populated fields
+func manifestMap(object runtime.Object) (map[string]interface{}, error) {
+	manifest, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return nil, err
+	}
+	pruneManifest(manifest)
+	return manifest, nil
+}
+
+// pruneManifest drops status blocks and empty creation timestamps at every level
+func pruneManifest(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, isObject := v["metadata"]; isObject {
+			delete(v, "status")
+		}
+		if timestamp, ok := v["creationTimestamp"]; ok && timestamp == nil {
+			delete(v, "creationTimestamp")
+		}
+		for _, child := range v {
+			pruneManifest(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			pruneManifest(child)
+		}
+	}
+}
+
+func manifestFileName(manifest map[string]interface{}) string {
+	kind, _ := manifest["kind"].(string)
+	name := ""
+	if metadata, ok := manifest["metadata"].(map[string]interface{}); ok {
+		name, _ = metadata["name"].(string)
+	}
+	return fmt.Sprintf("%s-%s.yaml", strings.ToLower(kind), name)
+}
+
+// systemNamespaces are namespaces of the cluster's own components besides kube-*
+var systemNamespaces = map[string]bool{
+	"ingress-nginx": true,
+}
+
+// IsSystemNamespace reports whether a namespace belongs to the cluster itself rather
+// than to applications
+func IsSystemNamespace(namespace string) bool {
+	return strings.HasPrefix(namespace, "kube-") || systemNamespaces[namespace]
+}
+
+// defaultApplicationNamespace is where deployments without a namespace of their own go.
+// The cluster setup creates it, so it is used whether or not it is labelled managed.
+const defaultApplicationNamespace = "espaze-node-deployer-apps"
+
+// applicationNamespace returns the namespace a deployment is applied to
+func applicationNamespace(deployment *entities.Deployment) string {
+	if deployment.Namespace == "" {
+		return defaultApplicationNamespace
+	}
+	return deployment.Namespace
+}
@@ -0,0 +1,76 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrMetricsUnavailable is returned when the cluster has no metrics API to ask
+var ErrMetricsUnavailable = errors.New("metrics API not available")
+
+// NodeCapacity sums what the cluster's nodes offer pods: allocatable CPU, memory and
+// ephemeral storage, and the pods the kubelets accept. Running pods and disk pressure
+// are filled in too; CPU and memory usage are left to NodeUsage.
+func (c *Client) NodeCapacity(ctx context.Context) (*entities.NodeResources, error) {
+	nodes, err := c.GetNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+
+	resources := &entities.NodeResources{}
+	cpuMillis := int64(0)
+	for _, node := range nodes.Items {
+		allocatable := node.Status.Allocatable
+		cpuMillis += allocatable.Cpu().MilliValue()
+		resources.MemoryTotal += allocatable.Memory().Value()
+		resources.DiskTotal += allocatable.StorageEphemeral().Value()
+		resources.PodsCapacity += int(allocatable.Pods().Value())
+
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeDiskPressure && condition.Status == corev1.ConditionTrue {
+				resources.DiskPressure = true
+			}
+		}
+	}
+
+	// Whole cores only, so a fraction reserved for the system is not offered to pods
+	resources.CPUCores = int(cpuMillis / 1000)
+	if resources.CPUCores == 0 && cpuMillis > 0 {
+		resources.CPUCores = 1
+	}
+
+	pods, err := c.GetPods(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning {
+			resources.PodsRunning++
+		}
+	}
+
+	return resources, nil
+}
+
+// NodeUsage sums the CPU (in millicores) and memory working set (in bytes) metrics-server
+// reports for the cluster's nodes
+func (c *Client) NodeUsage(ctx context.Context) (cpuMillis, memoryBytes int64, err error) {
+	if c.metricsClientset == nil {
+		return 0, 0, ErrMetricsUnavailable
+	}
+
+	metrics, err := c.metricsClientset.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrMetricsUnavailable, err)
+	}
+	for _, node := range metrics.Items {
+		cpuMillis += node.Usage.Cpu().MilliValue()
+		memoryBytes += node.Usage.Memory().Value()
+	}
+	return cpuMillis, memoryBytes, nil
+}
@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// RegistryCredentialLabel marks pull Secrets with the registry credential they hold
+const RegistryCredentialLabel = "espaze-registry-credential"
+
+// ApplyPullSecret creates or updates a kubernetes.io/dockerconfigjson Secret in a
+// deployment's namespace, creating the namespace first if needed
+func (c *Client) ApplyPullSecret(ctx context.Context, deployment *entities.Deployment, name, credentialID string, dockerConfigJSON []byte) error {
+	namespace := applicationNamespace(deployment)
+	if err := c.ensureNamespace(ctx, namespace); err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"managed-by":            "espaze-node-deployer",
+				RegistryCredentialLabel: credentialID,
+			},
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: dockerConfigJSON,
+		},
+	}
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+
+	force := true
+	_, err = c.clientset.CoreV1().Secrets(namespace).Patch(ctx, name, types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply pull secret: %w", err)
+	}
+	return nil
+}
+
+// DeletePullSecret deletes a pull Secret; a Secret that is already gone is not an error
+func (c *Client) DeletePullSecret(ctx context.Context, namespace, name string) error {
+	err := c.clientset.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pull secret: %w", err)
+	}
+	return nil
+}
+
+// ensureNamespace creates a managed namespace unless it already exists. Deployments
+// are kept out of the cluster's own namespaces and out of existing namespaces the
+// deployer does not manage, where they would land among objects of others.
+func (c *Client) ensureNamespace(ctx context.Context, namespace string) error {
+	if IsSystemNamespace(namespace) {
+		return fmt.Errorf("namespace %s belongs to the cluster and cannot be deployed to", namespace)
+	}
+	existing, err := c.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err == nil {
+		if namespace != defaultApplicationNamespace && existing.Labels["managed-by"] != "espaze-node-deployer" {
+			return fmt.Errorf("namespace %s already exists and is not managed by the deployer", namespace)
+		}
+		return nil
+	}
+	if err := c.CreateNamespace(ctx, namespace, map[string]string{
+		"managed-by": "espaze-node-deployer",
+	}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
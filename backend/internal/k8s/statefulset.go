@@ -0,0 +1,157 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func (c *Client) createStatefulSet(ctx context.Context, namespace string, deployment *entities.Deployment) error {
+	statefulSet, err := buildStatefulSet(namespace, deployment)
+	if err != nil {
+		return err
+	}
+	labelOwner(statefulSet, deployment)
+
+	_, err = c.clientset.AppsV1().StatefulSets(namespace).Create(ctx, statefulSet, metav1.CreateOptions{})
+	return err
+}
+
+func buildStatefulSet(namespace string, deployment *entities.Deployment) (*appsv1.StatefulSet, error) {
+	name := sanitizeName(deployment.Name)
+	config := deployment.Configuration
+
+	template := buildPodTemplate(name, config)
+
+	// Build per-replica volume claims and mount them into the container
+	claims := make([]corev1.PersistentVolumeClaim, 0, len(config.StatefulSet.VolumeClaimTemplates))
+	for _, vct := range config.StatefulSet.VolumeClaimTemplates {
+		claim, err := buildVolumeClaim(vct)
+		if err != nil {
+			return nil, err
+		}
+		claims = append(claims, claim)
+
+		template.Spec.Containers[0].VolumeMounts = append(template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      vct.Name,
+			MountPath: vct.MountPath,
+		})
+	}
+
+	podManagementPolicy := appsv1.OrderedReadyPodManagement
+	if config.StatefulSet.PodManagementPolicy == string(appsv1.ParallelPodManagement) {
+		podManagementPolicy = appsv1.ParallelPodManagement
+	}
+
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":        name,
+				"managed-by": "espaze-node-deployer",
+				"repo":       repoLabel(deployment.GitHubRepo.FullName),
+			},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &config.Replicas,
+			ServiceName: fmt.Sprintf("%s-headless", name),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": name,
+				},
+			},
+			Template:             template,
+			VolumeClaimTemplates: claims,
+			PodManagementPolicy:  podManagementPolicy,
+		},
+	}, nil
+}
+
+func buildVolumeClaim(vct entities.VolumeClaimTemplate) (corev1.PersistentVolumeClaim, error) {
+	storageSize := vct.StorageSize
+	if storageSize == "" {
+		storageSize = "1Gi"
+	}
+	size, err := resource.ParseQuantity(storageSize)
+	if err != nil {
+		return corev1.PersistentVolumeClaim{}, fmt.Errorf("invalid storage size for volume %s: %w", vct.Name, err)
+	}
+
+	accessMode := corev1.ReadWriteOnce
+	if vct.AccessMode != "" {
+		accessMode = corev1.PersistentVolumeAccessMode(vct.AccessMode)
+	}
+
+	claim := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: vct.Name,
+			Labels: map[string]string{
+				"managed-by": "espaze-node-deployer",
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	if vct.StorageClassName != "" {
+		claim.Spec.StorageClassName = &vct.StorageClassName
+	}
+
+	return claim, nil
+}
+
+// createHeadlessService creates the governing service that gives StatefulSet pods stable DNS names
+func (c *Client) createHeadlessService(ctx context.Context, namespace, name string, targetPort int32, owner *entities.Deployment) error {
+	service := buildHeadlessService(namespace, name, targetPort)
+	labelOwner(service, owner)
+	_, err := c.clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+	return err
+}
+
+func buildHeadlessService(namespace, name string, targetPort int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-headless", name),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":        name,
+				"managed-by": "espaze-node-deployer",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector: map[string]string{
+				"app": name,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Protocol:   corev1.ProtocolTCP,
+					Port:       targetPort,
+					TargetPort: intstr.FromInt(int(targetPort)),
+				},
+			},
+			PublishNotReadyAddresses: true,
+		},
+	}
+}
+
+// scaleStatefulSet sets a StatefulSet's replica count. The controller removes replicas
+// itself, one at a time from the highest ordinal down under the OrderedReady policy,
+// so the request does not wait for pods to terminate.
+func (c *Client) scaleStatefulSet(ctx context.Context, statefulSet *appsv1.StatefulSet, replicas int32) error {
+	statefulSet.Spec.Replicas = &replicas
+	_, err := c.clientset.AppsV1().StatefulSets(statefulSet.Namespace).Update(ctx, statefulSet, metav1.UpdateOptions{})
+	return err
+}
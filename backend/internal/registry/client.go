@@ -0,0 +1,330 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrImageNotFound is returned when a registry does not know an image or tag
+	ErrImageNotFound = errors.New("image not found")
+	// ErrCredentialsRejected is returned when a registry refuses the credentials given
+	ErrCredentialsRejected = errors.New("registry rejected the credentials")
+)
+
+const (
+	dockerHubRegistry = "docker.io"
+	dockerHubEndpoint = "registry-1.docker.io"
+)
+
+// manifestMediaTypes are the manifest formats accepted when resolving a tag, so
+// multi-arch images resolve to their index digest
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference
+type Reference struct {
+	Registry   string // e.g. docker.io, ghcr.io, localhost:5000
+	Repository string // e.g. library/nginx
+	Tag        string
+	Digest     string // sha256:...
+}
+
+// Name returns the reference without tag or digest, in the form used in pod specs
+func (r Reference) Name() string {
+	if r.Registry == dockerHubRegistry {
+		return strings.TrimPrefix(r.Repository, "library/")
+	}
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the full reference
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Pinned reports whether the reference names an exact digest
+func (r Reference) Pinned() bool {
+	return r.Digest != ""
+}
+
+// ParseReference parses an image reference such as nginx:1.27, ghcr.io/org/app@sha256:...
+// or localhost:5000/app:v1. A reference without tag or digest means :latest.
+func ParseReference(image string) (Reference, error) {
+	ref := Reference{}
+	if image == "" || strings.ContainsAny(image, " \t\n") {
+		return ref, fmt.Errorf("invalid image reference %q", image)
+	}
+
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !strings.HasPrefix(ref.Digest, "sha256:") || len(ref.Digest) != len("sha256:")+64 {
+			return ref, fmt.Errorf("invalid digest in image reference %q", image)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	// The first component is a registry host if it looks like one
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		ref.Repository = parts[1]
+	} else {
+		ref.Registry = dockerHubRegistry
+		ref.Repository = name
+		if !strings.Contains(name, "/") {
+			ref.Repository = "library/" + name
+		}
+	}
+
+	if ref.Repository == "" || ref.Repository != strings.ToLower(ref.Repository) {
+		return ref, fmt.Errorf("invalid repository in image reference %q", image)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// Credentials authenticate against a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client talks to container registries over the Docker Registry HTTP API V2
+type Client struct {
+	httpClient *http.Client
+	insecure   map[string]bool
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		insecure:   map[string]bool{},
+	}
+}
+
+// SetInsecureRegistries marks registries that are reached over plain HTTP
+func (c *Client) SetInsecureRegistries(registries []string) {
+	for _, registry := range registries {
+		if registry = strings.TrimSpace(registry); registry != "" {
+			c.insecure[registry] = true
+		}
+	}
+}
+
+// ResolveDigest returns the digest a reference's tag currently points to. Pinned
+// references are returned as they are. creds may be nil for public images.
+func (c *Client) ResolveDigest(ctx context.Context, ref Reference, creds *Credentials) (string, error) {
+	if ref.Pinned() {
+		return ref.Digest, nil
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", c.endpoint(ref.Registry), ref.Repository, ref.Tag)
+
+	resp, err := c.do(ctx, http.MethodHead, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	// Authenticate as the registry asks and try again
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), creds)
+		if err != nil {
+			return "", err
+		}
+		if resp, err = c.do(ctx, http.MethodHead, manifestURL, authorization); err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%s: %w", ref, ErrImageNotFound)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", fmt.Errorf("access to image %s denied", ref)
+	default:
+		return "", fmt.Errorf("registry returned %s for image %s", resp.Status, ref)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for image %s", ref)
+	}
+	return digest, nil
+}
+
+// CheckCredentials logs in to a registry's API root the way docker login does. A
+// registry that lets anyone in accepts any credentials.
+func (c *Client) CheckCredentials(ctx context.Context, registry string, creds *Credentials) error {
+	rootURL := c.endpoint(registry) + "/v2/"
+
+	resp, err := c.do(ctx, http.MethodGet, rootURL, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), creds)
+		if err != nil {
+			return err
+		}
+		if resp, err = c.do(ctx, http.MethodGet, rootURL, authorization); err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrCredentialsRejected
+	default:
+		return fmt.Errorf("registry returned %s", resp.Status)
+	}
+}
+
+func (c *Client) endpoint(registry string) string {
+	if registry == dockerHubRegistry {
+		registry = dockerHubEndpoint
+	}
+	if c.insecure[registry] {
+		return "http://" + registry
+	}
+	return "https://" + registry
+}
+
+func (c *Client) do(ctx context.Context, method, target, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach registry: %w", err)
+	}
+	return resp, nil
+}
+
+// authorize answers a WWW-Authenticate challenge with an Authorization header value.
+// Bearer challenges are exchanged for a token; basic challenges use creds directly.
+func (c *Client) authorize(ctx context.Context, challenge string, creds *Credentials) (string, error) {
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if creds == nil {
+			return "", errors.New("registry requires credentials")
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(creds.Username, creds.Password)
+		return req.Header.Get("Authorization"), nil
+
+	case "bearer":
+		realm := params["realm"]
+		if realm == "" {
+			return "", errors.New("registry sent a bearer challenge without a realm")
+		}
+		query := url.Values{}
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		if scope := params["scope"]; scope != "" {
+			query.Set("scope", scope)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		if creds != nil {
+			req.SetBasicAuth(creds.Username, creds.Password)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to get registry token: %w", err)
+		}
+		defer resp.Body.Close()
+		if creds != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			return "", ErrCredentialsRejected
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("registry token request failed: %s", resp.Status)
+		}
+
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("failed to decode registry token: %w", err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+
+	default:
+		return "", fmt.Errorf("unsupported registry authentication scheme %q", scheme)
+	}
+}
+
+// parseChallenge splits `Bearer realm="...",service="..."` into its scheme and parameters
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+
+	for rest != "" {
+		var pair string
+		rest = strings.TrimLeft(rest, ", ")
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				break
+			}
+			pair, rest = value[1:end+1], value[end+2:]
+		} else {
+			pair, rest, _ = strings.Cut(value, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = pair
+	}
+	return scheme, params
+}
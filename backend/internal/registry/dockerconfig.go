@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// dockerHubConfigKey is the key Docker Hub credentials are stored under in a Docker config
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// NormalizeHost reduces a registry given as a URL or host to the host used in image
+// references, so Docker Hub's aliases all become docker.io
+func NormalizeHost(registry string) string {
+	host := strings.TrimSpace(strings.ToLower(registry))
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHubRegistry
+	}
+	return host
+}
+
+// DockerConfigJSON renders credentials for one registry in the format of a
+// kubernetes.io/dockerconfigjson Secret
+func DockerConfigJSON(registry string, creds Credentials) ([]byte, error) {
+	key := NormalizeHost(registry)
+	if key == dockerHubRegistry {
+		key = dockerHubConfigKey
+	}
+
+	type authEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	config := map[string]map[string]authEntry{
+		"auths": {
+			key: {
+				Username: creds.Username,
+				Password: creds.Password,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password)),
+			},
+		},
+	}
+	return json.Marshal(config)
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AddonRepository interface {
+	Create(ctx context.Context, addon *entities.Addon) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.Addon, error)
+	GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*entities.Addon, error)
+	GetAll(ctx context.Context, filters map[string]interface{}) ([]*entities.Addon, error)
+	Update(ctx context.Context, id primitive.ObjectID, update map[string]interface{}) error
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, status entities.AddonStatus) error
+	AddBinding(ctx context.Context, id primitive.ObjectID, binding entities.AddonBinding) error
+	RemoveBinding(ctx context.Context, id, deploymentID primitive.ObjectID) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type addonRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAddonRepository(db *mongo.Database) AddonRepository {
+	collection := db.Collection("addons")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "node_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "node_id", Value: 1}, {Key: "namespace", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "bindings.deployment_id", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(ctx, indexes)
+
+	return &addonRepository{collection: collection}
+}
+
+func (r *addonRepository) Create(ctx context.Context, addon *entities.Addon) error {
+	addon.ID = primitive.NewObjectID()
+	addon.CreatedAt = time.Now()
+	addon.UpdatedAt = time.Now()
+
+	if addon.Status == "" {
+		addon.Status = entities.AddonStatusProvisioning
+	}
+	if addon.Bindings == nil {
+		addon.Bindings = []entities.AddonBinding{}
+	}
+
+	_, err := r.collection.InsertOne(ctx, addon)
+	return err
+}
+
+func (r *addonRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.Addon, error) {
+	var addon entities.Addon
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&addon)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &addon, nil
+}
+
+func (r *addonRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*entities.Addon, error) {
+	filter := bson.M{"user_id": userID}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var addons []*entities.Addon
+	if err = cursor.All(ctx, &addons); err != nil {
+		return nil, err
+	}
+
+	return addons, nil
+}
+
+func (r *addonRepository) GetAll(ctx context.Context, filters map[string]interface{}) ([]*entities.Addon, error) {
+	filter := bson.M{}
+	for key, value := range filters {
+		filter[key] = value
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var addons []*entities.Addon
+	if err = cursor.All(ctx, &addons); err != nil {
+		return nil, err
+	}
+
+	return addons, nil
+}
+
+func (r *addonRepository) Update(ctx context.Context, id primitive.ObjectID, update map[string]interface{}) error {
+	update["updated_at"] = time.Now()
+
+	updateDoc := bson.M{"$set": update}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, updateDoc)
+	return err
+}
+
+func (r *addonRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, status entities.AddonStatus) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":     status,
+			"updated_at": time.Now(),
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *addonRepository) AddBinding(ctx context.Context, id primitive.ObjectID, binding entities.AddonBinding) error {
+	update := bson.M{
+		"$push": bson.M{"bindings": binding},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *addonRepository) RemoveBinding(ctx context.Context, id, deploymentID primitive.ObjectID) error {
+	update := bson.M{
+		"$pull": bson.M{"bindings": bson.M{"deployment_id": deploymentID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *addonRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BootstrapTokenRepository interface {
+	Create(ctx context.Context, token *entities.BootstrapToken) error
+	GetAll(ctx context.Context) ([]*entities.BootstrapToken, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	Use(ctx context.Context, tokenHash string) (*entities.BootstrapToken, error)
+}
+
+type bootstrapTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBootstrapTokenRepository(db *mongo.Database) BootstrapTokenRepository {
+	collection := db.Collection("bootstrap_tokens")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// MongoDB removes tokens once they expire
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	collection.Indexes().CreateMany(ctx, indexes)
+
+	return &bootstrapTokenRepository{collection: collection}
+}
+
+func (r *bootstrapTokenRepository) Create(ctx context.Context, token *entities.BootstrapToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+func (r *bootstrapTokenRepository) GetAll(ctx context.Context) ([]*entities.BootstrapToken, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*entities.BootstrapToken
+	if err = cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *bootstrapTokenRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// Use counts one use of an unexpired token that has uses left. It returns nil if there
+// is no such token, so two agents cannot both take a token's last use.
+func (r *bootstrapTokenRepository) Use(ctx context.Context, tokenHash string) (*entities.BootstrapToken, error) {
+	now := time.Now()
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": now},
+		"$or": bson.A{
+			bson.M{"max_uses": 0},
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}},
+		},
+	}
+	update := bson.M{
+		"$inc": bson.M{"uses": 1},
+		"$set": bson.M{"last_used_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var token entities.BootstrapToken
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
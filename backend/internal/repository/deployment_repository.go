@@ -52,6 +52,9 @@ func NewDeploymentRepository(db *mongo.Database) DeploymentRepository {
 		{
 			Keys: bson.D{{Key: "context_path", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "fleet_id", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "created_at", Value: -1}},
 		},
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type EvacuationRepository interface {
+	Create(ctx context.Context, evacuation *entities.Evacuation) error
+	GetLatestByNodeID(ctx context.Context, nodeID primitive.ObjectID) (*entities.Evacuation, error)
+	Update(ctx context.Context, id primitive.ObjectID, update map[string]interface{}) error
+}
+
+type evacuationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewEvacuationRepository(db *mongo.Database) EvacuationRepository {
+	collection := db.Collection("evacuations")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "node_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(ctx, indexes)
+
+	return &evacuationRepository{collection: collection}
+}
+
+func (r *evacuationRepository) Create(ctx context.Context, evacuation *entities.Evacuation) error {
+	evacuation.ID = primitive.NewObjectID()
+	evacuation.CreatedAt = time.Now()
+	evacuation.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, evacuation)
+	return err
+}
+
+// GetLatestByNodeID returns the node's most recent evacuation, or nil if it never had one
+func (r *evacuationRepository) GetLatestByNodeID(ctx context.Context, nodeID primitive.ObjectID) (*entities.Evacuation, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var evacuation entities.Evacuation
+	err := r.collection.FindOne(ctx, bson.M{"node_id": nodeID}, opts).Decode(&evacuation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &evacuation, nil
+}
+
+func (r *evacuationRepository) Update(ctx context.Context, id primitive.ObjectID, update map[string]interface{}) error {
+	update["updated_at"] = time.Now()
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FleetRepository interface {
+	Create(ctx context.Context, fleet *entities.Fleet) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.Fleet, error)
+	GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*entities.Fleet, error)
+	Update(ctx context.Context, id primitive.ObjectID, update map[string]interface{}) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type fleetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewFleetRepository(db *mongo.Database) FleetRepository {
+	collection := db.Collection("fleets")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(ctx, indexes)
+
+	return &fleetRepository{collection: collection}
+}
+
+func (r *fleetRepository) Create(ctx context.Context, fleet *entities.Fleet) error {
+	fleet.ID = primitive.NewObjectID()
+	fleet.CreatedAt = time.Now()
+	fleet.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, fleet)
+	return err
+}
+
+func (r *fleetRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.Fleet, error) {
+	var fleet entities.Fleet
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&fleet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &fleet, nil
+}
+
+func (r *fleetRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*entities.Fleet, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var fleets []*entities.Fleet
+	if err = cursor.All(ctx, &fleets); err != nil {
+		return nil, err
+	}
+
+	return fleets, nil
+}
+
+func (r *fleetRepository) Update(ctx context.Context, id primitive.ObjectID, update map[string]interface{}) error {
+	update["updated_at"] = time.Now()
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
+
+func (r *fleetRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
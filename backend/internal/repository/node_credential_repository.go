@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NodeCredentialRepository interface {
+	Save(ctx context.Context, credential *entities.NodeCredential) error
+	GetByNodeID(ctx context.Context, nodeID primitive.ObjectID) (*entities.NodeCredential, error)
+	Update(ctx context.Context, nodeID primitive.ObjectID, update map[string]interface{}) error
+	Delete(ctx context.Context, nodeID primitive.ObjectID) error
+}
+
+type nodeCredentialRepository struct {
+	collection *mongo.Collection
+}
+
+func NewNodeCredentialRepository(db *mongo.Database) NodeCredentialRepository {
+	collection := db.Collection("node_credentials")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "node_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	collection.Indexes().CreateMany(ctx, indexes)
+
+	return &nodeCredentialRepository{collection: collection}
+}
+
+// Save replaces the credentials of a node, creating them if it had none
+func (r *nodeCredentialRepository) Save(ctx context.Context, credential *entities.NodeCredential) error {
+	existing, err := r.GetByNodeID(ctx, credential.NodeID)
+	if err != nil {
+		return err
+	}
+
+	credential.ID = primitive.NewObjectID()
+	credential.CreatedAt = time.Now()
+	if existing != nil {
+		credential.ID = existing.ID
+		credential.CreatedAt = existing.CreatedAt
+	}
+	credential.UpdatedAt = time.Now()
+
+	opts := options.Replace().SetUpsert(true)
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"node_id": credential.NodeID}, credential, opts)
+	return err
+}
+
+func (r *nodeCredentialRepository) GetByNodeID(ctx context.Context, nodeID primitive.ObjectID) (*entities.NodeCredential, error) {
+	var credential entities.NodeCredential
+	err := r.collection.FindOne(ctx, bson.M{"node_id": nodeID}).Decode(&credential)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *nodeCredentialRepository) Update(ctx context.Context, nodeID primitive.ObjectID, update map[string]interface{}) error {
+	update["updated_at"] = time.Now()
+
+	updateDoc := bson.M{"$set": update}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"node_id": nodeID}, updateDoc)
+	return err
+}
+
+func (r *nodeCredentialRepository) Delete(ctx context.Context, nodeID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"node_id": nodeID})
+	return err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NodeEventRepository interface {
+	Create(ctx context.Context, event *entities.NodeEvent) error
+	GetByNodeID(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeEvent, error)
+}
+
+type nodeEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewNodeEventRepository(db *mongo.Database) NodeEventRepository {
+	collection := db.Collection("node_events")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "node_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(ctx, indexes)
+
+	return &nodeEventRepository{collection: collection}
+}
+
+func (r *nodeEventRepository) Create(ctx context.Context, event *entities.NodeEvent) error {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, event)
+	return err
+}
+
+// GetByNodeID returns the newest events of a node first
+func (r *nodeEventRepository) GetByNodeID(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, bson.M{"node_id": nodeID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*entities.NodeEvent
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NodeOperationRepository interface {
+	Create(ctx context.Context, operation *entities.NodeOperation) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.NodeOperation, error)
+	GetByNodeID(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeOperation, error)
+	Claim(ctx context.Context, nodeID primitive.ObjectID, staleBefore time.Time) (*entities.NodeOperation, error)
+	FailStale(ctx context.Context, nodeID primitive.ObjectID, staleBefore time.Time, maxAttempts int) error
+	Complete(ctx context.Context, id primitive.ObjectID, result *entities.NodeOperationResult) (bool, error)
+}
+
+type nodeOperationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewNodeOperationRepository(db *mongo.Database) NodeOperationRepository {
+	collection := db.Collection("node_operations")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "node_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "node_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(ctx, indexes)
+
+	return &nodeOperationRepository{collection: collection}
+}
+
+func (r *nodeOperationRepository) Create(ctx context.Context, operation *entities.NodeOperation) error {
+	operation.ID = primitive.NewObjectID()
+	operation.Status = entities.NodeOperationPending
+	operation.CreatedAt = time.Now()
+	operation.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, operation)
+	return err
+}
+
+func (r *nodeOperationRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.NodeOperation, error) {
+	var operation entities.NodeOperation
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&operation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// GetByNodeID returns the newest operations of a node first
+func (r *nodeOperationRepository) GetByNodeID(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeOperation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, bson.M{"node_id": nodeID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var operations []*entities.NodeOperation
+	if err = cursor.All(ctx, &operations); err != nil {
+		return nil, err
+	}
+
+	return operations, nil
+}
+
+// Claim hands the node's oldest pending operation to its agent, or one that was claimed
+// before staleBefore and never reported back. It returns nil if there is none.
+func (r *nodeOperationRepository) Claim(ctx context.Context, nodeID primitive.ObjectID, staleBefore time.Time) (*entities.NodeOperation, error) {
+	filter := bson.M{
+		"node_id": nodeID,
+		"$or": bson.A{
+			bson.M{"status": entities.NodeOperationPending},
+			bson.M{"status": entities.NodeOperationRunning, "claimed_at": bson.M{"$lt": staleBefore}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     entities.NodeOperationRunning,
+			"claimed_at": time.Now(),
+			"updated_at": time.Now(),
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var operation entities.NodeOperation
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&operation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// FailStale gives up on operations the agent claimed maxAttempts times without ever
+// reporting a result
+func (r *nodeOperationRepository) FailStale(ctx context.Context, nodeID primitive.ObjectID, staleBefore time.Time, maxAttempts int) error {
+	filter := bson.M{
+		"node_id":    nodeID,
+		"status":     entities.NodeOperationRunning,
+		"claimed_at": bson.M{"$lt": staleBefore},
+		"attempts":   bson.M{"$gte": maxAttempts},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       entities.NodeOperationFailed,
+			"error":        "the agent never reported a result",
+			"completed_at": time.Now(),
+			"updated_at":   time.Now(),
+		},
+	}
+
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// Complete records the agent's result. It reports false if the operation had already
+// finished, so a result is only acted on once.
+func (r *nodeOperationRepository) Complete(ctx context.Context, id primitive.ObjectID, result *entities.NodeOperationResult) (bool, error) {
+	status := entities.NodeOperationFailed
+	if result.Success {
+		status = entities.NodeOperationSucceeded
+	}
+
+	filter := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": bson.A{entities.NodeOperationPending, entities.NodeOperationRunning}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       status,
+			"applied":      result.Applied,
+			"output":       result.Output,
+			"error":        result.Error,
+			"completed_at": time.Now(),
+			"updated_at":   time.Now(),
+		},
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
@@ -19,9 +19,12 @@ type NodeRepository interface {
 	Update(ctx context.Context, id primitive.ObjectID, update *entities.NodeUpdateRequest) error
 	UpdateResources(ctx context.Context, id primitive.ObjectID, resources *entities.NodeResources) error
 	UpdateLastSeen(ctx context.Context, id primitive.ObjectID) error
+	RotateCredential(ctx context.Context, id primitive.ObjectID) (int, error)
+	Revoke(ctx context.Context, id primitive.ObjectID) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	GetNodesByStatus(ctx context.Context, status entities.NodeStatus) ([]*entities.Node, error)
-	GetNodesByLocation(ctx context.Context, latitude, longitude, radiusKm float64) ([]*entities.Node, error)
+	GetNodesByLocation(ctx context.Context, latitude, longitude, radiusKm float64, limit int64, filters map[string]interface{}) ([]*entities.NodeDistance, error)
+	GetNodesWithin(ctx context.Context, geometry *entities.GeoGeometry, filters map[string]interface{}) ([]*entities.Node, error)
 	GetNodeStats(ctx context.Context) (map[string]interface{}, error)
 }
 
@@ -48,7 +51,7 @@ func NewNodeRepository(db *mongo.Database) NodeRepository {
 			Keys: bson.D{{Key: "status", Value: 1}},
 		},
 		{
-			Keys: bson.D{{Key: "location.latitude", Value: 1}, {Key: "location.longitude", Value: 1}},
+			Keys: bson.D{{Key: "geo_location", Value: "2dsphere"}},
 		},
 		{
 			Keys: bson.D{{Key: "last_seen_at", Value: -1}},
@@ -56,15 +59,39 @@ func NewNodeRepository(db *mongo.Database) NodeRepository {
 	}
 	
 	collection.Indexes().CreateMany(ctx, indexes)
+
+	// Replaced by the 2dsphere index
+	collection.Indexes().DropOne(ctx, "location.latitude_1_location.longitude_1")
+	backfillGeoLocations(ctx, collection)
 	
 	return &nodeRepository{collection: collection}
 }
 
+// backfillGeoLocations gives nodes stored before locations were indexed their point
+func backfillGeoLocations(ctx context.Context, collection *mongo.Collection) {
+	cursor, err := collection.Find(ctx, bson.M{"geo_location": bson.M{"$exists": false}})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var node entities.Node
+		if err := cursor.Decode(&node); err != nil {
+			continue
+		}
+		if point := entities.NewGeoPoint(node.Location); point != nil {
+			collection.UpdateOne(ctx, bson.M{"_id": node.ID}, bson.M{"$set": bson.M{"geo_location": point}})
+		}
+	}
+}
+
 func (r *nodeRepository) Create(ctx context.Context, node *entities.Node) error {
 	node.ID = primitive.NewObjectID()
 	node.CreatedAt = time.Now()
 	node.UpdatedAt = time.Now()
 	node.LastSeenAt = time.Now()
+	node.GeoLocation = entities.NewGeoPoint(node.Location)
 	
 	if node.Status == "" {
 		node.Status = entities.NodeStatusOnline
@@ -130,8 +157,21 @@ func (r *nodeRepository) Update(ctx context.Context, id primitive.ObjectID, upda
 		updateDoc["$set"].(bson.M)["status"] = update.Status
 	}
 	
+	if update.PublicIP != "" {
+		updateDoc["$set"].(bson.M)["public_ip"] = update.PublicIP
+	}
+
+	if update.PrivateIP != "" {
+		updateDoc["$set"].(bson.M)["private_ip"] = update.PrivateIP
+	}
+
 	if update.Location != nil {
 		updateDoc["$set"].(bson.M)["location"] = update.Location
+		if point := entities.NewGeoPoint(*update.Location); point != nil {
+			updateDoc["$set"].(bson.M)["geo_location"] = point
+		} else {
+			updateDoc["$unset"] = bson.M{"geo_location": ""}
+		}
 	}
 	
 	if update.Resources != nil {
@@ -141,6 +181,14 @@ func (r *nodeRepository) Update(ctx context.Context, id primitive.ObjectID, upda
 	if update.ClusterInfo != nil {
 		updateDoc["$set"].(bson.M)["cluster_info"] = update.ClusterInfo
 	}
+
+	if update.Metadata != nil {
+		updateDoc["$set"].(bson.M)["metadata"] = update.Metadata
+	}
+
+	if update.AccessMode != "" {
+		updateDoc["$set"].(bson.M)["access_mode"] = update.AccessMode
+	}
 	
 	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, updateDoc)
 	return err
@@ -170,6 +218,35 @@ func (r *nodeRepository) UpdateLastSeen(ctx context.Context, id primitive.Object
 	return err
 }
 
+// RotateCredential starts a new credential generation and returns it
+func (r *nodeRepository) RotateCredential(ctx context.Context, id primitive.ObjectID) (int, error) {
+	update := bson.M{
+		"$inc": bson.M{"credential_generation": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var node entities.Node
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&node); err != nil {
+		return 0, err
+	}
+	return node.CredentialGeneration, nil
+}
+
+// Revoke invalidates the node's credentials and marks it revoked
+func (r *nodeRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$inc": bson.M{"credential_generation": 1},
+		"$set": bson.M{
+			"revoked_at": time.Now(),
+			"updated_at": time.Now(),
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
 func (r *nodeRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
@@ -191,33 +268,70 @@ func (r *nodeRepository) GetNodesByStatus(ctx context.Context, status entities.N
 	return nodes, nil
 }
 
-func (r *nodeRepository) GetNodesByLocation(ctx context.Context, latitude, longitude, radiusKm float64) ([]*entities.Node, error) {
-	// Simple bounding box calculation (not perfect but good enough)
-	latDelta := radiusKm / 111.0 // 1 degree latitude ≈ 111 km
-	lonDelta := radiusKm / (111.0 * cosApprox(latitude))
-	
-	filter := bson.M{
-		"location.latitude": bson.M{
-			"$gte": latitude - latDelta,
-			"$lte": latitude + latDelta,
-		},
-		"location.longitude": bson.M{
-			"$gte": longitude - lonDelta,
-			"$lte": longitude + lonDelta,
+// GetNodesByLocation returns nodes nearest first with their distance. A radius or limit
+// of 0 leaves the search unbounded.
+func (r *nodeRepository) GetNodesByLocation(ctx context.Context, latitude, longitude, radiusKm float64, limit int64, filters map[string]interface{}) ([]*entities.NodeDistance, error) {
+	geoNear := bson.M{
+		"near": entities.GeoPoint{
+			Type:        "Point",
+			Coordinates: []float64{longitude, latitude},
 		},
+		"key":                "geo_location",
+		"distanceField":      "distance_km",
+		"distanceMultiplier": 0.001, // meters to km
+		"spherical":          true,
 	}
-	
-	cursor, err := r.collection.Find(ctx, filter)
+	if radiusKm > 0 {
+		geoNear["maxDistance"] = radiusKm * 1000
+	}
+	if len(filters) > 0 {
+		query := bson.M{}
+		for key, value := range filters {
+			query[key] = value
+		}
+		geoNear["query"] = query
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$geoNear", Value: geoNear}}}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
-	
+
+	var nodes []*entities.NodeDistance
+	if err = cursor.All(ctx, &nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// GetNodesWithin returns the nodes located inside a polygon or multipolygon
+func (r *nodeRepository) GetNodesWithin(ctx context.Context, geometry *entities.GeoGeometry, filters map[string]interface{}) ([]*entities.Node, error) {
+	filter := bson.M{
+		"geo_location": bson.M{"$geoWithin": bson.M{"$geometry": geometry}},
+	}
+	for key, value := range filters {
+		filter[key] = value
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "node_name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
 	var nodes []*entities.Node
 	if err = cursor.All(ctx, &nodes); err != nil {
 		return nil, err
 	}
-	
+
 	return nodes, nil
 }
 
@@ -253,14 +367,3 @@ func (r *nodeRepository) GetNodeStats(ctx context.Context) (map[string]interface
 	
 	return stats, nil
 }
-
-// Helper function for approximate cosine
-func cosApprox(degrees float64) float64 {
-	radians := degrees * 3.14159265359 / 180.0
-	// Simple approximation
-	if radians < -1.5708 || radians > 1.5708 {
-		return 0.0
-	}
-	return 1.0 - (radians * radians / 2.0)
-}
-
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RegistryCredentialRepository interface {
+	Create(ctx context.Context, credential *entities.RegistryCredential) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.RegistryCredential, error)
+	GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*entities.RegistryCredential, error)
+	GetByRegistry(ctx context.Context, userID primitive.ObjectID, registry string) (*entities.RegistryCredential, error)
+	Update(ctx context.Context, id primitive.ObjectID, update map[string]interface{}) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type registryCredentialRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRegistryCredentialRepository(db *mongo.Database) RegistryCredentialRepository {
+	collection := db.Collection("registry_credentials")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "registry", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	collection.Indexes().CreateMany(ctx, indexes)
+
+	return &registryCredentialRepository{collection: collection}
+}
+
+func (r *registryCredentialRepository) Create(ctx context.Context, credential *entities.RegistryCredential) error {
+	credential.ID = primitive.NewObjectID()
+	credential.CreatedAt = time.Now()
+	credential.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, credential)
+	return err
+}
+
+func (r *registryCredentialRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.RegistryCredential, error) {
+	var credential entities.RegistryCredential
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&credential)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *registryCredentialRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*entities.RegistryCredential, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "registry", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var credentials []*entities.RegistryCredential
+	if err = cursor.All(ctx, &credentials); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+func (r *registryCredentialRepository) GetByRegistry(ctx context.Context, userID primitive.ObjectID, registry string) (*entities.RegistryCredential, error) {
+	var credential entities.RegistryCredential
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "registry": registry}).Decode(&credential)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *registryCredentialRepository) Update(ctx context.Context, id primitive.ObjectID, update map[string]interface{}) error {
+	update["updated_at"] = time.Now()
+
+	updateDoc := bson.M{"$set": update}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, updateDoc)
+	return err
+}
+
+func (r *registryCredentialRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
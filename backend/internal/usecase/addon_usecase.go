@@ -0,0 +1,316 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type AddonUseCase interface {
+	GetCatalog() []entities.AddonDefinition
+	CreateAddon(ctx context.Context, userID, nodeID primitive.ObjectID, req *entities.AddonRequest) (*entities.Addon, error)
+	GetAddon(ctx context.Context, id primitive.ObjectID) (*entities.Addon, error)
+	GetAddonsByUser(ctx context.Context, userID primitive.ObjectID) ([]*entities.Addon, error)
+	BindAddon(ctx context.Context, id primitive.ObjectID, req *entities.AddonBindRequest) error
+	UnbindAddon(ctx context.Context, id, deploymentID primitive.ObjectID) error
+	DeleteAddon(ctx context.Context, id primitive.ObjectID, retainData bool) error
+}
+
+type addonUseCase struct {
+	addonRepo      repository.AddonRepository
+	deploymentRepo repository.DeploymentRepository
+	clusters       *k8s.ClusterManager
+}
+
+func NewAddonUseCase(
+	addonRepo repository.AddonRepository,
+	deploymentRepo repository.DeploymentRepository,
+	clusters *k8s.ClusterManager,
+) AddonUseCase {
+	return &addonUseCase{
+		addonRepo:      addonRepo,
+		deploymentRepo: deploymentRepo,
+		clusters:       clusters,
+	}
+}
+
+func (uc *addonUseCase) GetCatalog() []entities.AddonDefinition {
+	return k8s.AddonCatalog()
+}
+
+func (uc *addonUseCase) CreateAddon(ctx context.Context, userID, nodeID primitive.ObjectID, req *entities.AddonRequest) (*entities.Addon, error) {
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	definition, ok := k8s.GetAddonDefinition(req.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown add-on type: %s", req.Type)
+	}
+
+	if req.Version == "" {
+		req.Version = definition.DefaultVersion
+	}
+	if req.StorageSize == "" {
+		req.StorageSize = definition.DefaultStorage
+	}
+	if _, err := resource.ParseQuantity(req.StorageSize); err != nil {
+		return nil, fmt.Errorf("invalid storage size: %w", err)
+	}
+	if req.Namespace == "" {
+		req.Namespace = "espaze-node-deployer-apps"
+	}
+
+	addon := &entities.Addon{
+		NodeID:      nodeID,
+		UserID:      userID,
+		Name:        req.Name,
+		Type:        req.Type,
+		Version:     req.Version,
+		Namespace:   req.Namespace,
+		StorageSize: req.StorageSize,
+		Status:      entities.AddonStatusProvisioning,
+	}
+
+	k8sClient, err := uc.clusters.Client(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkNamesFree(ctx, k8sClient, addon); err != nil {
+		return nil, err
+	}
+
+	// The names are known up front, so a failed or interrupted provisioning can be cleaned up
+	addon.KubernetesInfo = k8s.AddonInfo(addon)
+	if err := uc.addonRepo.Create(ctx, addon); err != nil {
+		return nil, err
+	}
+
+	// Provision in Kubernetes asynchronously
+	go func() {
+		provisionCtx := context.Background()
+
+		if err := k8sClient.ProvisionAddon(provisionCtx, addon); err != nil {
+			log.Printf("Add-on %s: provisioning failed: %v", addon.ID.Hex(), err)
+			// The names were free, so whatever exists now was created by this attempt
+			if err := k8sClient.DeleteAddon(provisionCtx, addon.Namespace, addon.Name, false); err != nil {
+				log.Printf("Add-on %s: failed to clean up: %v", addon.ID.Hex(), err)
+			}
+			uc.addonRepo.UpdateStatus(provisionCtx, addon.ID, entities.AddonStatusFailed)
+			return
+		}
+
+		update := map[string]interface{}{
+			"kubernetes_info": addon.KubernetesInfo,
+			"status":          entities.AddonStatusReady,
+		}
+		uc.addonRepo.Update(provisionCtx, addon.ID, update)
+	}()
+
+	return addon, nil
+}
+
+// checkNamesFree refuses an add-on whose objects would take over those of another
+// add-on or of anything else already in the namespace
+func (uc *addonUseCase) checkNamesFree(ctx context.Context, k8sClient *k8s.Client, addon *entities.Addon) error {
+	name := k8s.AddonInfo(addon).StatefulSetName
+
+	// Another add-on may still be provisioning, before its objects exist
+	others, err := uc.addonRepo.GetAll(ctx, map[string]interface{}{
+		"node_id":   addon.NodeID,
+		"namespace": addon.Namespace,
+	})
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if k8s.AddonInfo(other).StatefulSetName == name {
+			return fmt.Errorf("add-on %s already uses the name %s in namespace %s", other.Name, name, addon.Namespace)
+		}
+	}
+
+	existing, err := k8sClient.ExistingAddonObjects(ctx, addon)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("namespace %s already has objects named for this add-on: %s", addon.Namespace, strings.Join(existing, ", "))
+	}
+	return nil
+}
+
+func (uc *addonUseCase) GetAddon(ctx context.Context, id primitive.ObjectID) (*entities.Addon, error) {
+	addon, err := uc.addonRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if addon == nil {
+		return nil, errors.New("add-on not found")
+	}
+	return addon, nil
+}
+
+func (uc *addonUseCase) GetAddonsByUser(ctx context.Context, userID primitive.ObjectID) ([]*entities.Addon, error) {
+	return uc.addonRepo.GetByUserID(ctx, userID)
+}
+
+func (uc *addonUseCase) BindAddon(ctx context.Context, id primitive.ObjectID, req *entities.AddonBindRequest) error {
+	addon, err := uc.GetAddon(ctx, id)
+	if err != nil {
+		return err
+	}
+	if addon.Status != entities.AddonStatusReady {
+		return fmt.Errorf("add-on is not ready (status: %s)", addon.Status)
+	}
+
+	deploymentID, err := primitive.ObjectIDFromHex(req.DeploymentID)
+	if err != nil {
+		return errors.New("invalid deployment ID")
+	}
+	deployment, err := uc.deploymentRepo.GetByID(ctx, deploymentID)
+	if err != nil {
+		return err
+	}
+	if deployment == nil {
+		return errors.New("deployment not found")
+	}
+
+	// The Secret can only be referenced from pods in the same cluster and namespace
+	if deployment.NodeID != addon.NodeID || deployment.Namespace != addon.Namespace {
+		return errors.New("add-on and deployment must be on the same node and namespace")
+	}
+
+	for _, binding := range addon.Bindings {
+		if binding.DeploymentID == deploymentID {
+			return errors.New("add-on is already bound to this deployment")
+		}
+	}
+
+	envVar := req.EnvVar
+	if envVar == "" {
+		definition, _ := k8s.GetAddonDefinition(addon.Type)
+		envVar = definition.DefaultEnvVar
+	}
+	for _, existing := range deployment.Configuration.SecretEnvVars {
+		if existing.Name == envVar {
+			return fmt.Errorf("deployment already has a secret env var named %s", envVar)
+		}
+	}
+
+	secretEnvVars := append(deployment.Configuration.SecretEnvVars, entities.SecretEnvVar{
+		Name:       envVar,
+		SecretName: addon.KubernetesInfo.SecretName,
+		SecretKey:  k8s.AddonSecretKeyURL,
+	})
+
+	if err := uc.applySecretEnvVars(ctx, deployment, secretEnvVars); err != nil {
+		return err
+	}
+
+	return uc.addonRepo.AddBinding(ctx, id, entities.AddonBinding{
+		DeploymentID: deploymentID,
+		EnvVar:       envVar,
+		BoundAt:      time.Now(),
+	})
+}
+
+func (uc *addonUseCase) UnbindAddon(ctx context.Context, id, deploymentID primitive.ObjectID) error {
+	addon, err := uc.GetAddon(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var binding *entities.AddonBinding
+	for i := range addon.Bindings {
+		if addon.Bindings[i].DeploymentID == deploymentID {
+			binding = &addon.Bindings[i]
+			break
+		}
+	}
+	if binding == nil {
+		return errors.New("add-on is not bound to this deployment")
+	}
+
+	deployment, err := uc.deploymentRepo.GetByID(ctx, deploymentID)
+	if err != nil {
+		return err
+	}
+
+	// The deployment may already be gone, in which case only the binding is removed
+	if deployment != nil {
+		secretEnvVars := []entities.SecretEnvVar{}
+		for _, existing := range deployment.Configuration.SecretEnvVars {
+			if existing.Name == binding.EnvVar && existing.SecretName == addon.KubernetesInfo.SecretName {
+				continue
+			}
+			secretEnvVars = append(secretEnvVars, existing)
+		}
+
+		if err := uc.applySecretEnvVars(ctx, deployment, secretEnvVars); err != nil {
+			return err
+		}
+	}
+
+	return uc.addonRepo.RemoveBinding(ctx, id, deploymentID)
+}
+
+func (uc *addonUseCase) DeleteAddon(ctx context.Context, id primitive.ObjectID, retainData bool) error {
+	addon, err := uc.GetAddon(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to delete while live deployments still reference the credentials
+	for _, binding := range addon.Bindings {
+		deployment, err := uc.deploymentRepo.GetByID(ctx, binding.DeploymentID)
+		if err != nil {
+			return err
+		}
+		if deployment != nil {
+			return fmt.Errorf("add-on is still bound to deployment %s, unbind it first", deployment.Name)
+		}
+	}
+
+	k8sClient, err := uc.clusters.Client(ctx, addon.NodeID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.addonRepo.UpdateStatus(ctx, id, entities.AddonStatusDeleting); err != nil {
+		return err
+	}
+
+	if err := k8sClient.DeleteAddon(ctx, addon.Namespace, addon.Name, retainData); err != nil {
+		uc.addonRepo.UpdateStatus(ctx, id, entities.AddonStatusFailed)
+		return fmt.Errorf("failed to delete from Kubernetes: %w", err)
+	}
+
+	return uc.addonRepo.Delete(ctx, id)
+}
+
+// applySecretEnvVars stores the secret env vars on the deployment and rolls them out
+func (uc *addonUseCase) applySecretEnvVars(ctx context.Context, deployment *entities.Deployment, secretEnvVars []entities.SecretEnvVar) error {
+	if deployment.KubernetesInfo.DeploymentName != "" {
+		k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+		if err != nil {
+			return err
+		}
+		if err := k8sClient.SetSecretEnvVars(ctx, deployment.Namespace, deployment.KubernetesInfo.DeploymentName, secretEnvVars); err != nil {
+			return fmt.Errorf("failed to update deployment env: %w", err)
+		}
+	}
+
+	update := map[string]interface{}{
+		"configuration.secret_env_vars": secretEnvVars,
+	}
+	return uc.deploymentRepo.Update(ctx, deployment.ID, update)
+}
@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// agentOperationTimeout bounds how long a request waits for a node's agent to answer
+const agentOperationTimeout = 30 * time.Second
+
+// errAgentSource is returned for work that needs the server to reach the cluster itself
+var errAgentSource = errors.New("helm and manifests deployments need direct access to the node's cluster")
+
+// agentManaged reports whether a node's cluster is only reached through its agent
+func (uc *deploymentUseCase) agentManaged(ctx context.Context, nodeID primitive.ObjectID) bool {
+	if nodeID.IsZero() {
+		return false
+	}
+	node, err := uc.nodeRepo.GetByID(ctx, nodeID)
+	return err == nil && node != nil && node.AccessMode == entities.NodeAccessAgent
+}
+
+// agentSupports reports whether deployments from a source can be run by an agent. Only
+// objects rendered on the server are handed to agents.
+func agentSupports(sourceType entities.SourceType) bool {
+	return sourceType != entities.SourceTypeHelm && sourceType != entities.SourceTypeManifests
+}
+
+// rolloutViaAgent queues an apply of the deployment's objects on its node. The result is
+// recorded by operationFinished once the agent reports back. Registry credentials are
+// not synced to agent-managed nodes; pull secrets have to exist on their cluster.
+func (uc *deploymentUseCase) rolloutViaAgent(ctx context.Context, deployment *entities.Deployment) {
+	export, err := k8s.ExportManifests(deployment, entities.ManifestFormatYAML)
+	if err == nil {
+		err = uc.operations.Enqueue(ctx, &entities.NodeOperation{
+			NodeID:       deployment.NodeID,
+			DeploymentID: deployment.ID,
+			Type:         entities.NodeOperationApply,
+			Namespace:    deployment.Namespace,
+			Name:         deployment.Name,
+			Manifests:    string(export.Content),
+			Objects:      deployment.AppliedObjects,
+		})
+	}
+	if err != nil {
+		log.Printf("Agent rollout: deployment %s: %v", deployment.ID.Hex(), err)
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+	}
+}
+
+// deleteViaAgent queues the deletion of the deployment's objects; the record is removed
+// once the agent reports them gone
+func (uc *deploymentUseCase) deleteViaAgent(ctx context.Context, deployment *entities.Deployment) error {
+	if !agentSupports(deployment.Source.Type) {
+		return errAgentSource
+	}
+
+	objects := deployment.AppliedObjects
+	if len(objects) == 0 {
+		var err error
+		if objects, err = k8s.ApplicationObjects(deployment); err != nil {
+			return err
+		}
+	}
+
+	update := map[string]interface{}{
+		"status":         entities.DeploymentStatusDeleting,
+		"deletion_error": "",
+	}
+	if err := uc.deploymentRepo.Update(ctx, deployment.ID, update); err != nil {
+		return err
+	}
+
+	return uc.operations.Enqueue(ctx, &entities.NodeOperation{
+		NodeID:       deployment.NodeID,
+		DeploymentID: deployment.ID,
+		Type:         entities.NodeOperationDelete,
+		Namespace:    deployment.Namespace,
+		Name:         deployment.Name,
+		Objects:      objects,
+	})
+}
+
+// runViaAgent queues an operation for the deployment's node and waits for the result
+func (uc *deploymentUseCase) runViaAgent(ctx context.Context, deployment *entities.Deployment, operation *entities.NodeOperation) (*entities.NodeOperation, error) {
+	if !agentSupports(deployment.Source.Type) {
+		return nil, errAgentSource
+	}
+
+	operation.NodeID = deployment.NodeID
+	operation.DeploymentID = deployment.ID
+	operation.Namespace = deployment.Namespace
+	if err := uc.operations.Enqueue(ctx, operation); err != nil {
+		return nil, err
+	}
+
+	done, err := uc.operations.Wait(ctx, operation.ID, agentOperationTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if done.Status == entities.NodeOperationFailed {
+		return nil, fmt.Errorf("%s failed on the node: %s", done.Type, done.Error)
+	}
+	return done, nil
+}
+
+// scaleWorkload scales the deployment's workload directly or through the node's agent
+func (uc *deploymentUseCase) scaleWorkload(ctx context.Context, deployment *entities.Deployment, replicas int32) error {
+	if uc.agentManaged(ctx, deployment.NodeID) {
+		_, err := uc.runViaAgent(ctx, deployment, &entities.NodeOperation{
+			Type:     entities.NodeOperationScale,
+			Name:     deployment.KubernetesInfo.DeploymentName,
+			Replicas: replicas,
+		})
+		return err
+	}
+
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return err
+	}
+	return k8sClient.ScaleDeployment(ctx, deployment.Namespace, deployment.KubernetesInfo.DeploymentName, replicas)
+}
+
+// restartWorkload restarts the deployment's pods directly or through the node's agent
+func (uc *deploymentUseCase) restartWorkload(ctx context.Context, deployment *entities.Deployment) error {
+	if uc.agentManaged(ctx, deployment.NodeID) {
+		_, err := uc.runViaAgent(ctx, deployment, &entities.NodeOperation{
+			Type: entities.NodeOperationRestart,
+			Name: deployment.KubernetesInfo.DeploymentName,
+		})
+		return err
+	}
+
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return err
+	}
+	return k8sClient.RestartDeployment(ctx, deployment.Namespace, deployment.KubernetesInfo.DeploymentName)
+}
+
+// GetDeploymentLogs returns the last lines of each of the deployment's pods
+func (uc *deploymentUseCase) GetDeploymentLogs(ctx context.Context, id primitive.ObjectID, tailLines int64) (string, error) {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if tailLines <= 0 {
+		tailLines = 100
+	}
+
+	selector := deployment.KubernetesInfo.PodSelector
+	if selector == "" {
+		selector = k8s.ApplicationInfo(deployment).PodSelector
+	}
+
+	if uc.agentManaged(ctx, deployment.NodeID) {
+		done, err := uc.runViaAgent(ctx, deployment, &entities.NodeOperation{
+			Type:      entities.NodeOperationLogs,
+			Selector:  selector,
+			TailLines: tailLines,
+		})
+		if err != nil {
+			return "", err
+		}
+		return done.Output, nil
+	}
+
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return "", err
+	}
+	return k8sClient.ApplicationLogs(ctx, deployment.Namespace, selector, tailLines)
+}
+
+// operationFinished records the outcome of rollouts and deletions run by agents
+func (uc *deploymentUseCase) operationFinished(ctx context.Context, operation entities.NodeOperation) {
+	if operation.DeploymentID.IsZero() {
+		return
+	}
+	deployment, err := uc.deploymentRepo.GetByID(ctx, operation.DeploymentID)
+	if err != nil || deployment == nil {
+		return
+	}
+	succeeded := operation.Status == entities.NodeOperationSucceeded
+
+	switch operation.Type {
+	case entities.NodeOperationApply:
+		// A deletion started in the meantime wins
+		if deployment.Status == entities.DeploymentStatusDeleting {
+			return
+		}
+		if !succeeded {
+			uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+			return
+		}
+
+		update := map[string]interface{}{
+			"kubernetes_info": k8s.ApplicationInfo(deployment),
+			"applied_objects": operation.Applied,
+			"deployed_at":     time.Now(),
+			"status":          entities.DeploymentStatusRunning,
+		}
+		uc.deploymentRepo.Update(ctx, deployment.ID, update)
+
+		// An unresolved tag leaves the pod spec unchanged, so restart to pull it again
+		if deployment.Source.Type == entities.SourceTypeImage && deployment.ImageDigest == "" {
+			deployment.KubernetesInfo = k8s.ApplicationInfo(deployment)
+			if err := uc.operations.Enqueue(ctx, &entities.NodeOperation{
+				NodeID:       deployment.NodeID,
+				DeploymentID: deployment.ID,
+				Type:         entities.NodeOperationRestart,
+				Namespace:    deployment.Namespace,
+				Name:         deployment.KubernetesInfo.DeploymentName,
+			}); err != nil {
+				log.Printf("Agent rollout: deployment %s: %v", deployment.ID.Hex(), err)
+			}
+		}
+
+	case entities.NodeOperationDelete:
+		if !succeeded {
+			uc.recordDeletionError(ctx, deployment.ID, errors.New(operation.Error))
+			return
+		}
+		uc.deploymentRepo.Delete(ctx, deployment.ID)
+	}
+}
@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/github"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// AppManifestError lists every problem found in an espaze.yaml
+type AppManifestError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *AppManifestError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Path, strings.Join(e.Problems, "; "))
+}
+
+// loadAppManifest reads and validates the app manifest of a repository. A missing file
+// at the default path means the repository has none; a missing configured path is an error.
+func (uc *deploymentUseCase) loadAppManifest(ctx context.Context, githubToken string, repo entities.GitHubRepository, path string) (*entities.AppManifest, error) {
+	configured := path != ""
+	if !configured {
+		path = entities.DefaultAppManifestPath
+	}
+
+	content, err := uc.githubClient.GetFileContent(ctx, githubToken, repo.Owner, repo.Name, path, repo.Branch)
+	if errors.Is(err, github.ErrFileNotFound) && !configured {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return parseAppManifest(path, content)
+}
+
+// parseAppManifest decodes an app manifest, rejecting unknown fields, and validates it
+func parseAppManifest(path, content string) (*entities.AppManifest, error) {
+	var manifest entities.AppManifest
+	if err := yaml.UnmarshalStrict([]byte(content), &manifest); err != nil {
+		return nil, &AppManifestError{Path: path, Problems: []string{err.Error()}}
+	}
+
+	problems := []string{}
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if manifest.Version != 0 && manifest.Version != 1 {
+		addProblem("version: unsupported version %d", manifest.Version)
+	}
+	if manifest.Port < 0 || manifest.Port > 65535 {
+		addProblem("port: must be between 1 and 65535")
+	}
+	if manifest.ServicePort < 0 || manifest.ServicePort > 65535 {
+		addProblem("servicePort: must be between 1 and 65535")
+	}
+	if manifest.Replicas != nil && *manifest.Replicas < 0 {
+		addProblem("replicas: must not be negative")
+	}
+
+	if r := manifest.Resources; r != nil {
+		quantities := []struct{ field, value string }{
+			{"resources.requests.cpu", r.Requests.CPU},
+			{"resources.requests.memory", r.Requests.Memory},
+			{"resources.limits.cpu", r.Limits.CPU},
+			{"resources.limits.memory", r.Limits.Memory},
+		}
+		for _, q := range quantities {
+			if q.value == "" {
+				continue
+			}
+			if _, err := resource.ParseQuantity(q.value); err != nil {
+				addProblem("%s: invalid quantity %q", q.field, q.value)
+			}
+		}
+	}
+
+	for key := range manifest.Env {
+		for _, msg := range validation.IsEnvVarName(key) {
+			addProblem("env.%s: %s", key, msg)
+		}
+	}
+
+	if hc := manifest.HealthCheck; hc != nil && hc.Enabled {
+		if !strings.HasPrefix(hc.Path, "/") {
+			addProblem("healthCheck.path: must start with /")
+		}
+		if hc.Port < 0 || hc.Port > 65535 {
+			addProblem("healthCheck.port: must be between 1 and 65535")
+		}
+	}
+
+	if as := manifest.AutoScaling; as != nil && as.Enabled {
+		if as.MaxReplicas < 1 {
+			addProblem("autoScaling.maxReplicas: must be at least 1")
+		}
+		if as.MinReplicas > as.MaxReplicas {
+			addProblem("autoScaling.minReplicas: must not exceed maxReplicas")
+		}
+		if as.TargetCPUUtilization < 0 || as.TargetCPUUtilization > 100 {
+			addProblem("autoScaling.targetCPUUtilization: must be between 1 and 100")
+		}
+		if as.TargetMemoryUtilization < 0 || as.TargetMemoryUtilization > 100 {
+			addProblem("autoScaling.targetMemoryUtilization: must be between 1 and 100")
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &AppManifestError{Path: path, Problems: problems}
+	}
+	return &manifest, nil
+}
+
+// mergeAppManifest applies an app manifest on top of config and then re-applies the
+// values given explicitly through the API, so the precedence is:
+// API request > espaze.yaml > existing configuration and defaults
+//
+// Zero and false overrides only count when explicit marks them as given.
+func mergeAppManifest(config entities.DeploymentConfig, manifest *entities.AppManifest, overrides entities.DeploymentConfig, explicit entities.ExplicitSettings) entities.DeploymentConfig {
+	if manifest == nil {
+		return config
+	}
+
+	if manifest.Port != 0 {
+		config.ContainerPort = manifest.Port
+	}
+	if manifest.ServicePort != 0 {
+		config.ServicePort = manifest.ServicePort
+	}
+	if manifest.Replicas != nil {
+		config.Replicas = *manifest.Replicas
+	}
+	if r := manifest.Resources; r != nil {
+		if r.Requests.CPU != "" {
+			config.CPURequest = r.Requests.CPU
+		}
+		if r.Requests.Memory != "" {
+			config.MemoryRequest = r.Requests.Memory
+		}
+		if r.Limits.CPU != "" {
+			config.CPULimit = r.Limits.CPU
+		}
+		if r.Limits.Memory != "" {
+			config.MemoryLimit = r.Limits.Memory
+		}
+	}
+	if manifest.Env != nil {
+		config.EnvironmentVars = copyStringMap(manifest.Env)
+	}
+	if manifest.HealthCheck != nil {
+		config.HealthCheck = *manifest.HealthCheck
+	}
+	if manifest.AutoScaling != nil {
+		config.AutoScaling = *manifest.AutoScaling
+	}
+	if b := manifest.Build; b != nil {
+		if b.Dockerfile != "" {
+			config.BuildConfig.Dockerfile = b.Dockerfile
+		}
+		if b.Context != "" {
+			config.BuildConfig.BuildContext = b.Context
+		}
+		if b.Args != nil {
+			config.BuildConfig.BuildArgs = copyStringMap(b.Args)
+		}
+	}
+
+	// Explicit API values win over the file
+	if overrides.ContainerPort != 0 {
+		config.ContainerPort = overrides.ContainerPort
+	}
+	if overrides.ServicePort != 0 {
+		config.ServicePort = overrides.ServicePort
+	}
+	if overrides.Replicas != 0 || explicit.Replicas {
+		config.Replicas = overrides.Replicas
+	}
+	if overrides.CPURequest != "" {
+		config.CPURequest = overrides.CPURequest
+	}
+	if overrides.MemoryRequest != "" {
+		config.MemoryRequest = overrides.MemoryRequest
+	}
+	if overrides.CPULimit != "" {
+		config.CPULimit = overrides.CPULimit
+	}
+	if overrides.MemoryLimit != "" {
+		config.MemoryLimit = overrides.MemoryLimit
+	}
+	if len(overrides.EnvironmentVars) > 0 {
+		if config.EnvironmentVars == nil {
+			config.EnvironmentVars = map[string]string{}
+		}
+		for key, value := range overrides.EnvironmentVars {
+			config.EnvironmentVars[key] = value
+		}
+	}
+	if overrides.HealthCheck.Enabled {
+		config.HealthCheck = overrides.HealthCheck
+	}
+	if overrides.AutoScaling.Enabled || explicit.AutoScaling {
+		config.AutoScaling = overrides.AutoScaling
+	}
+	if overrides.BuildConfig.Dockerfile != "" {
+		config.BuildConfig.Dockerfile = overrides.BuildConfig.Dockerfile
+	}
+	if overrides.BuildConfig.BuildContext != "" {
+		config.BuildConfig.BuildContext = overrides.BuildConfig.BuildContext
+	}
+	if len(overrides.BuildConfig.BuildArgs) > 0 {
+		if config.BuildConfig.BuildArgs == nil {
+			config.BuildConfig.BuildArgs = map[string]string{}
+		}
+		for key, value := range overrides.BuildConfig.BuildArgs {
+			config.BuildConfig.BuildArgs[key] = value
+		}
+	}
+
+	return config
+}
+
+func copyStringMap(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for key, value := range in {
+		out[key] = value
+	}
+	return out
+}
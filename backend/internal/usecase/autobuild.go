@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/builder"
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// generateDockerfile detects the stack in the build context of a repository without a
+// Dockerfile and stores a generated Dockerfile in the build configuration
+func (uc *deploymentUseCase) generateDockerfile(ctx context.Context, githubToken string, repo entities.GitHubRepository, config *entities.DeploymentConfig, buildContext string) error {
+	project, err := uc.inspectProject(ctx, githubToken, repo, buildContext)
+	if err != nil {
+		return err
+	}
+	project.Port = config.ContainerPort
+
+	stack, dockerfile, err := builder.Generate(project)
+	if errors.Is(err, builder.ErrUnknownStack) {
+		return errors.New("repository has no Dockerfile and its stack could not be detected; provide a Dockerfile")
+	}
+	if err != nil {
+		return err
+	}
+
+	build := &config.BuildConfig
+	build.Dockerfile = ""
+	build.DockerfileContent = dockerfile
+	build.Stack = string(stack)
+	build.BuildContext = buildContext
+	return nil
+}
+
+// inspectProject lists the build context and reads the files stack detection needs
+func (uc *deploymentUseCase) inspectProject(ctx context.Context, githubToken string, repo entities.GitHubRepository, buildContext string) (*builder.Project, error) {
+	dir := buildContext
+	if dir == "." {
+		dir = ""
+	}
+
+	entries, err := uc.githubClient.ListDirectory(ctx, githubToken, repo.Owner, repo.Name, dir, repo.Branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", buildContext, err)
+	}
+
+	project := &builder.Project{
+		Name:     repo.Name,
+		Language: repo.Language,
+		Contents: map[string]string{},
+	}
+	for _, entry := range entries {
+		project.Files = append(project.Files, entry.Name)
+
+		if entry.Name == "cmd" && entry.Type == "dir" {
+			commands, err := uc.githubClient.ListDirectory(ctx, githubToken, repo.Owner, repo.Name, entry.Path, repo.Branch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s: %w", entry.Path, err)
+			}
+			for _, command := range commands {
+				if command.Type == "dir" {
+					project.Commands = append(project.Commands, command.Name)
+				}
+			}
+		}
+	}
+
+	for _, marker := range builder.MarkerFiles {
+		if !contains(project.Files, marker) {
+			continue
+		}
+		content, err := uc.githubClient.GetFileContent(ctx, githubToken, repo.Owner, repo.Name, path.Join(dir, marker), repo.Branch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", marker, err)
+		}
+		project.Contents[marker] = content
+	}
+
+	return project, nil
+}
+
+// GetDockerfile returns the repository, generated or hand-written Dockerfile a deployment
+// is built with
+func (uc *deploymentUseCase) GetDockerfile(ctx context.Context, id primitive.ObjectID) (*entities.DeploymentDockerfile, error) {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	build := deployment.Configuration.BuildConfig
+	if build.DockerfileContent == "" && build.Dockerfile == "" {
+		return nil, errors.New("deployment is not built from a Dockerfile")
+	}
+
+	return &entities.DeploymentDockerfile{
+		Path:       build.Dockerfile,
+		Stack:      build.Stack,
+		Content:    build.DockerfileContent,
+		Overridden: build.DockerfileContent != "" && build.Stack == "",
+	}, nil
+}
+
+// SetDockerfile replaces the Dockerfile a deployment is built with. Empty content drops
+// the override and goes back to the repository's Dockerfile, or a generated one. The
+// change is built on the next redeploy.
+func (uc *deploymentUseCase) SetDockerfile(ctx context.Context, id primitive.ObjectID, content string) (*entities.DeploymentDockerfile, error) {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch deployment.Source.Type {
+	case "", entities.SourceTypeDockerfile:
+	default:
+		return nil, fmt.Errorf("%s deployments are not built from a Dockerfile", deployment.Source.Type)
+	}
+
+	config := &deployment.Configuration
+	if content != "" {
+		config.BuildConfig.Dockerfile = ""
+		config.BuildConfig.DockerfileContent = content
+		config.BuildConfig.Stack = ""
+	} else {
+		token, err := uc.githubTokenRepo.GetByUserID(ctx, deployment.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, errors.New("a GitHub token is required to generate a Dockerfile")
+		}
+
+		config.BuildConfig.DockerfileContent = ""
+		if err := uc.locateDockerfile(ctx, token.Token, deployment.GitHubRepo, config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := uc.deploymentRepo.Update(ctx, id, map[string]interface{}{"configuration.build_config": config.BuildConfig}); err != nil {
+		return nil, err
+	}
+	return uc.GetDockerfile(ctx, id)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
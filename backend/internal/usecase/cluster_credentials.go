@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/encryption"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"k8s.io/client-go/rest"
+)
+
+// NodeCredentialLoader reads and decrypts the stored cluster credentials of a node for
+// the cluster manager. Nodes reached through their agent have no usable credentials,
+// and a node that does not exist has no cluster at all rather than the default one.
+func NodeCredentialLoader(nodeRepo repository.NodeRepository, credentialRepo repository.NodeCredentialRepository, cipher *encryption.Cipher) k8s.CredentialLoader {
+	return func(ctx context.Context, nodeID primitive.ObjectID) (*rest.Config, error) {
+		node, err := nodeRepo.GetByID(ctx, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		if node == nil {
+			return nil, ErrNodeNotFound
+		}
+		if node.AccessMode == entities.NodeAccessAgent {
+			return nil, k8s.ErrAgentManaged
+		}
+
+		credential, err := credentialRepo.GetByNodeID(ctx, nodeID)
+		if err != nil || credential == nil {
+			return nil, err
+		}
+		return clusterConfig(cipher, credential)
+	}
+}
+
+// sameCluster reports whether deployments on both nodes land in the same cluster, where
+// objects of the same name would overwrite each other. Nodes without credentials of
+// their own all use the default cluster; nodes reached through their agent each have a
+// cluster of their own.
+func sameCluster(ctx context.Context, clusters *k8s.ClusterManager, a, b *entities.Node) (bool, error) {
+	if a.ID == b.ID {
+		return true, nil
+	}
+	if a.AccessMode == entities.NodeAccessAgent || b.AccessMode == entities.NodeAccessAgent {
+		return false, nil
+	}
+
+	clientA, err := clusters.Client(ctx, a.ID)
+	if err != nil {
+		return false, err
+	}
+	clientB, err := clusters.Client(ctx, b.ID)
+	if err != nil {
+		return false, err
+	}
+	return clientA == clientB, nil
+}
+
+// SetClusterCredentials stores the kubeconfig or service account token of a node's
+// cluster and connects to it. The credentials are kept even if the cluster cannot be
+// reached yet.
+func (uc *nodeUseCase) SetClusterCredentials(ctx context.Context, nodeID primitive.ObjectID, req *entities.NodeCredentialRequest) (*entities.NodeCredential, error) {
+	if _, err := uc.GetNode(ctx, nodeID); err != nil {
+		return nil, err
+	}
+
+	credential := &entities.NodeCredential{
+		NodeID:   nodeID,
+		Type:     req.Type,
+		Insecure: req.Insecure,
+	}
+
+	// Build the config once from the plaintext to reject unusable credentials early
+	var err error
+	switch req.Type {
+	case entities.NodeCredentialTypeKubeconfig:
+		if _, err = k8s.RESTConfigFromKubeconfig([]byte(req.Kubeconfig), req.Context); err != nil {
+			return nil, err
+		}
+		credential.Context = req.Context
+		credential.Kubeconfig, err = uc.cipher.Encrypt(req.Kubeconfig)
+	case entities.NodeCredentialTypeToken:
+		if _, err = k8s.RESTConfigFromToken(req.Server, req.Token, []byte(req.CACertificate), req.Insecure); err != nil {
+			return nil, err
+		}
+		credential.Server = req.Server
+		credential.CACertificate = req.CACertificate
+		credential.Token, err = uc.cipher.Encrypt(req.Token)
+	default:
+		return nil, fmt.Errorf("unsupported credential type: %s", req.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.credentialRepo.Save(ctx, credential); err != nil {
+		return nil, err
+	}
+	uc.clusters.Invalidate(nodeID)
+
+	if _, err := uc.clusters.Client(ctx, nodeID); err != nil {
+		return nil, fmt.Errorf("credentials saved, but the cluster is unreachable: %w", err)
+	}
+
+	now := time.Now()
+	if err := uc.credentialRepo.Update(ctx, nodeID, map[string]interface{}{"verified_at": now}); err != nil {
+		return nil, err
+	}
+	credential.VerifiedAt = &now
+	return credential, nil
+}
+
+func (uc *nodeUseCase) GetClusterCredentials(ctx context.Context, nodeID primitive.ObjectID) (*entities.NodeCredential, error) {
+	credential, err := uc.credentialRepo.GetByNodeID(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, errors.New("node has no cluster credentials")
+	}
+	return credential, nil
+}
+
+// DeleteClusterCredentials removes the credentials of a node, which moves it back to the
+// default cluster
+func (uc *nodeUseCase) DeleteClusterCredentials(ctx context.Context, nodeID primitive.ObjectID) error {
+	if err := uc.credentialRepo.Delete(ctx, nodeID); err != nil {
+		return err
+	}
+	uc.clusters.Invalidate(nodeID)
+	return nil
+}
+
+// clusterConfig decrypts stored credentials into a REST config
+func clusterConfig(cipher *encryption.Cipher, credential *entities.NodeCredential) (*rest.Config, error) {
+	switch credential.Type {
+	case entities.NodeCredentialTypeKubeconfig:
+		kubeconfig, err := cipher.Decrypt(credential.Kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		return k8s.RESTConfigFromKubeconfig([]byte(kubeconfig), credential.Context)
+	case entities.NodeCredentialTypeToken:
+		token, err := cipher.Decrypt(credential.Token)
+		if err != nil {
+			return nil, err
+		}
+		return k8s.RESTConfigFromToken(credential.Server, token, []byte(credential.CACertificate), credential.Insecure)
+	}
+	return nil, fmt.Errorf("unsupported credential type: %s", credential.Type)
+}
+
+// agentNodes returns the IDs of the nodes whose cluster is only reached through their
+// agent. Background passes skip them up front instead of failing on each of their
+// deployments with k8s.ErrAgentManaged.
+func agentNodes(ctx context.Context, nodeRepo repository.NodeRepository) (map[primitive.ObjectID]bool, error) {
+	nodes, err := nodeRepo.GetAll(ctx, map[string]interface{}{"access_mode": entities.NodeAccessAgent})
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[primitive.ObjectID]bool, len(nodes))
+	for _, node := range nodes {
+		ids[node.ID] = true
+	}
+	return ids, nil
+}
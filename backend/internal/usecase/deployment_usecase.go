@@ -10,10 +10,15 @@ import (
 	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
 	"github.com/espazeindia/espazeNodeDeployer/internal/github"
 	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/registry"
 	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/encryption"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// deletionTimeout bounds how long a deletion waits for the cluster to remove every object
+const deletionTimeout = 5 * time.Minute
+
 type DeploymentUseCase interface {
 	CreateDeployment(ctx context.Context, userID, nodeID primitive.ObjectID, req *entities.DeploymentRequest, githubToken string) (*entities.Deployment, error)
 	GetDeployment(ctx context.Context, id primitive.ObjectID) (*entities.Deployment, error)
@@ -21,32 +26,65 @@ type DeploymentUseCase interface {
 	GetDeploymentsByUser(ctx context.Context, userID primitive.ObjectID) ([]*entities.Deployment, error)
 	GetAllDeployments(ctx context.Context, filters map[string]interface{}) ([]*entities.Deployment, error)
 	UpdateDeployment(ctx context.Context, id primitive.ObjectID, req *entities.DeploymentUpdateRequest) error
-	DeleteDeployment(ctx context.Context, id primitive.ObjectID, k8sClient *k8s.Client) error
-	RestartDeployment(ctx context.Context, id primitive.ObjectID, k8sClient *k8s.Client) error
-	ScaleDeployment(ctx context.Context, id primitive.ObjectID, replicas int32, k8sClient *k8s.Client) error
-	UpdateDeploymentMetrics(ctx context.Context, id primitive.ObjectID, k8sClient *k8s.Client) error
+	RedeployDeployment(ctx context.Context, id primitive.ObjectID, githubToken string) (*entities.Deployment, error)
+	DeleteDeployment(ctx context.Context, id primitive.ObjectID) error
+	RestartDeployment(ctx context.Context, id primitive.ObjectID) error
+	ScaleDeployment(ctx context.Context, id primitive.ObjectID, replicas int32) error
+	UpdateDeploymentMetrics(ctx context.Context, id primitive.ObjectID) error
 	GetDeploymentStats(ctx context.Context, nodeID *primitive.ObjectID) (map[string]interface{}, error)
+	GetDeploymentLogs(ctx context.Context, id primitive.ObjectID, tailLines int64) (string, error)
+	ExportManifests(ctx context.Context, id primitive.ObjectID, format entities.ManifestFormat) (*entities.ManifestExport, error)
+	PreviewDeployment(ctx context.Context, userID, nodeID primitive.ObjectID, req *entities.DeploymentRequest, githubToken string) (*entities.DeploymentPreview, error)
+	PreviewUpdate(ctx context.Context, id primitive.ObjectID, req *entities.DeploymentUpdateRequest) (*entities.DeploymentPreview, error)
+	RollbackDeployment(ctx context.Context, id primitive.ObjectID, revision int) error
+	GetReleaseStatus(ctx context.Context, id primitive.ObjectID) (*entities.HelmReleaseStatus, error)
+	GetManifestStatus(ctx context.Context, id primitive.ObjectID) (*entities.ManifestStatus, error)
+	GetDockerfile(ctx context.Context, id primitive.ObjectID) (*entities.DeploymentDockerfile, error)
+	SetDockerfile(ctx context.Context, id primitive.ObjectID, content string) (*entities.DeploymentDockerfile, error)
+	HandlePush(ctx context.Context, push *entities.PushEvent) ([]*entities.Deployment, error)
+	StartImageWatcher(ctx context.Context, interval time.Duration)
+	CheckImageUpdates(ctx context.Context) error
 }
 
 type deploymentUseCase struct {
 	deploymentRepo  repository.DeploymentRepository
-	k8sClient       *k8s.Client
+	clusters        *k8s.ClusterManager
 	githubClient    *github.Client
 	githubTokenRepo repository.GitHubTokenRepository
+	registryClient  *registry.Client
+	credentialRepo  repository.RegistryCredentialRepository
+	cipher          *encryption.Cipher
+	nodeRepo        repository.NodeRepository
+	operations      NodeOperationUseCase
+	placement       PlacementUseCase
 }
 
 func NewDeploymentUseCase(
 	deploymentRepo repository.DeploymentRepository,
-	k8sClient *k8s.Client,
+	clusters *k8s.ClusterManager,
 	githubClient *github.Client,
 	githubTokenRepo repository.GitHubTokenRepository,
+	registryClient *registry.Client,
+	credentialRepo repository.RegistryCredentialRepository,
+	cipher *encryption.Cipher,
+	nodeRepo repository.NodeRepository,
+	operations NodeOperationUseCase,
+	placement PlacementUseCase,
 ) DeploymentUseCase {
-	return &deploymentUseCase{
+	uc := &deploymentUseCase{
 		deploymentRepo:  deploymentRepo,
-		k8sClient:       k8sClient,
+		clusters:        clusters,
 		githubClient:    githubClient,
 		githubTokenRepo: githubTokenRepo,
-	}
+		registryClient:  registryClient,
+		credentialRepo:  credentialRepo,
+		cipher:          cipher,
+		nodeRepo:        nodeRepo,
+		operations:      operations,
+		placement:       placement,
+	}
+	operations.Subscribe(uc.operationFinished)
+	return uc
 }
 
 func (uc *deploymentUseCase) CreateDeployment(
@@ -56,66 +94,54 @@ func (uc *deploymentUseCase) CreateDeployment(
 	githubToken string,
 ) (*entities.Deployment, error) {
 	// Validate request
-	if err := uc.validateDeploymentRequest(req); err != nil {
+	if err := validateDeploymentRequest(req); err != nil {
+		return nil, err
+	}
+	if !agentSupports(req.Source.Type) && uc.agentManaged(ctx, nodeID) {
+		return nil, errAgentSource
+	}
+	if err := uc.checkMaintenance(ctx, nodeID); err != nil {
 		return nil, err
 	}
 
+	// Prebuilt images need nothing from GitHub
+	if req.Source.Type == entities.SourceTypeImage {
+		return uc.createImageDeployment(ctx, userID, nodeID, req)
+	}
+
 	// Get GitHub repository info
 	repo, err := uc.githubClient.GetRepository(ctx, githubToken, req.GitHubRepo.Owner, req.GitHubRepo.Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repository: %w", err)
 	}
 
-	// Check for Dockerfile
-	hasDockerfile, dockerfilePath, err := uc.githubClient.CheckDockerfile(
-		ctx,
-		githubToken,
-		req.GitHubRepo.Owner,
-		req.GitHubRepo.Name,
-		req.GitHubRepo.Branch,
-	)
-	if err != nil || !hasDockerfile {
-		return nil, errors.New("repository must contain a Dockerfile")
+	// Merge espaze.yaml from the repository with the request
+	overrides, err := uc.applyAppManifest(ctx, req, githubToken)
+	if err != nil {
+		return nil, err
 	}
 
 	// Set default configuration if not provided
-	if req.Configuration.Replicas == 0 {
-		req.Configuration.Replicas = 2
-	}
-	if req.Configuration.MemoryRequest == "" {
-		req.Configuration.MemoryRequest = "256Mi"
-	}
-	if req.Configuration.MemoryLimit == "" {
-		req.Configuration.MemoryLimit = "512Mi"
-	}
-	if req.Configuration.CPURequest == "" {
-		req.Configuration.CPURequest = "250m"
-	}
-	if req.Configuration.CPULimit == "" {
-		req.Configuration.CPULimit = "500m"
-	}
-	if req.Configuration.ContainerPort == 0 {
-		req.Configuration.ContainerPort = 8080
-	}
-	if req.Configuration.ServicePort == 0 {
-		req.Configuration.ServicePort = 80
-	}
-	if req.Configuration.ImagePullPolicy == "" {
-		req.Configuration.ImagePullPolicy = "IfNotPresent"
-	}
-	if req.Configuration.RestartPolicy == "" {
-		req.Configuration.RestartPolicy = "Always"
-	}
+	applyConfigDefaults(&req.Configuration, req.GitHubRepo, req.Explicit)
 
-	// Set build configuration
-	if req.Configuration.BuildConfig.Dockerfile == "" {
-		req.Configuration.BuildConfig.Dockerfile = dockerfilePath
-	}
-	if req.Configuration.BuildConfig.ImageName == "" {
-		req.Configuration.BuildConfig.ImageName = fmt.Sprintf("%s/%s", req.GitHubRepo.Owner, req.GitHubRepo.Name)
-	}
-	if req.Configuration.BuildConfig.ImageTag == "" {
-		req.Configuration.BuildConfig.ImageTag = "latest"
+	// Check for the chart, manifests or Dockerfile the deployment is built from
+	switch req.Source.Type {
+	case entities.SourceTypeHelm:
+		if err := uc.checkHelmChart(ctx, githubToken, req.GitHubRepo, req.Source); err != nil {
+			return nil, err
+		}
+	case entities.SourceTypeManifests:
+		if err := uc.checkManifestDir(ctx, githubToken, req.GitHubRepo, req.Source); err != nil {
+			return nil, err
+		}
+	default:
+		req.Source.Type = entities.SourceTypeDockerfile
+
+		// The repository's language helps pick a stack when there is no Dockerfile
+		req.GitHubRepo.Language = repo.Language
+		if err := uc.locateDockerfile(ctx, githubToken, req.GitHubRepo, &req.Configuration); err != nil {
+			return nil, err
+		}
 	}
 
 	// Create deployment entity
@@ -136,7 +162,13 @@ func (uc *deploymentUseCase) CreateDeployment(
 			Language:    repo.Language,
 			Description: repo.Description,
 		},
-		Configuration: req.Configuration,
+		Source:           req.Source,
+		Configuration:    req.Configuration,
+		ConfigOverrides:  overrides,
+		ExplicitSettings: req.Explicit,
+		ManifestPath:     req.ManifestPath,
+		FleetID:          fleetOf(req),
+		DeployedCommit:   uc.branchHead(ctx, githubToken, req.GitHubRepo),
 		Metrics: entities.DeploymentMetrics{
 			DesiredPods: int(req.Configuration.Replicas),
 		},
@@ -145,6 +177,9 @@ func (uc *deploymentUseCase) CreateDeployment(
 	if deployment.Namespace == "" {
 		deployment.Namespace = "espaze-node-deployer-apps"
 	}
+	if err := uc.place(ctx, deployment, req.Placement); err != nil {
+		return nil, err
+	}
 
 	// Save to database
 	if err := uc.deploymentRepo.Create(ctx, deployment); err != nil {
@@ -154,6 +189,15 @@ func (uc *deploymentUseCase) CreateDeployment(
 	// Deploy to Kubernetes asynchronously
 	go func() {
 		deployCtx := context.Background()
+
+		switch deployment.Source.Type {
+		case entities.SourceTypeHelm:
+			uc.rolloutHelmRelease(deployCtx, deployment, githubToken)
+			return
+		case entities.SourceTypeManifests:
+			uc.rolloutManifests(deployCtx, deployment, githubToken)
+			return
+		}
 		
 		// Update status to building
 		uc.deploymentRepo.UpdateStatus(deployCtx, deployment.ID, entities.DeploymentStatusBuilding)
@@ -167,17 +211,36 @@ func (uc *deploymentUseCase) CreateDeployment(
 		
 		// Update status to deploying
 		uc.deploymentRepo.UpdateStatus(deployCtx, deployment.ID, entities.DeploymentStatusDeploying)
-		
+
+		// Nodes behind NAT pull the rollout through their agent
+		if uc.agentManaged(deployCtx, deployment.NodeID) {
+			uc.rolloutViaAgent(deployCtx, deployment)
+			return
+		}
+
+		k8sClient, err := uc.clusters.Client(deployCtx, deployment.NodeID)
+		if err != nil {
+			uc.deploymentRepo.UpdateStatus(deployCtx, deployment.ID, entities.DeploymentStatusFailed)
+			return
+		}
+
+		// Sync registry credentials the image needs
+		if err := uc.syncPullSecrets(deployCtx, deployment); err != nil {
+			uc.deploymentRepo.UpdateStatus(deployCtx, deployment.ID, entities.DeploymentStatusFailed)
+			return
+		}
+
 		// Deploy to Kubernetes
-		if err := uc.k8sClient.DeployApplication(deployCtx, deployment); err != nil {
+		if err := k8sClient.DeployApplication(deployCtx, deployment); err != nil {
 			uc.deploymentRepo.UpdateStatus(deployCtx, deployment.ID, entities.DeploymentStatusFailed)
 			return
 		}
 		
 		// Update deployment with Kubernetes info
 		update := map[string]interface{}{
-			"kubernetes_info": deployment.KubernetesInfo,
-			"deployed_at":     time.Now(),
+			"kubernetes_info":                  deployment.KubernetesInfo,
+			"configuration.image_pull_secrets": deployment.Configuration.ImagePullSecrets,
+			"deployed_at":                      time.Now(),
 		}
 		uc.deploymentRepo.Update(deployCtx, deployment.ID, update)
 		
@@ -199,6 +262,18 @@ func (uc *deploymentUseCase) GetDeployment(ctx context.Context, id primitive.Obj
 	return deployment, nil
 }
 
+// ExportManifests renders the objects that DeployApplication applies for a deployment
+func (uc *deploymentUseCase) ExportManifests(ctx context.Context, id primitive.ObjectID, format entities.ManifestFormat) (*entities.ManifestExport, error) {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !rendersObjects(deployment.Source) {
+		return nil, fmt.Errorf("%s deployments are not rendered by the deployer", deployment.Source.Type)
+	}
+	return k8s.ExportManifests(deployment, format)
+}
+
 func (uc *deploymentUseCase) GetDeploymentsByNode(ctx context.Context, nodeID primitive.ObjectID) ([]*entities.Deployment, error) {
 	return uc.deploymentRepo.GetByNodeID(ctx, nodeID)
 }
@@ -222,27 +297,326 @@ func (uc *deploymentUseCase) UpdateDeployment(ctx context.Context, id primitive.
 
 	update := make(map[string]interface{})
 
+	isHelm := deployment.Source.Type == entities.SourceTypeHelm
+	if deployment.Source.Type == entities.SourceTypeManifests && (req.Replicas != nil || req.EnvironmentVars != nil || req.AutoScaling != nil) {
+		return errors.New("manifests deployments are configured through the manifests in their repository")
+	}
+
 	if req.Replicas != nil {
 		update["configuration.replicas"] = *req.Replicas
+		update["config_overrides.replicas"] = *req.Replicas
+		update["explicit_settings.replicas"] = true
+		deployment.Configuration.Replicas = *req.Replicas
 		
-		// Update in Kubernetes
-		if err := uc.k8sClient.ScaleDeployment(ctx, deployment.Namespace, deployment.KubernetesInfo.DeploymentName, *req.Replicas); err != nil {
-			return fmt.Errorf("failed to scale deployment: %w", err)
+		// Update in Kubernetes; Helm releases pick it up on upgrade below
+		if !isHelm {
+			if err := uc.scaleWorkload(ctx, deployment, *req.Replicas); err != nil {
+				return fmt.Errorf("failed to scale deployment: %w", err)
+			}
 		}
 	}
 
 	if req.EnvironmentVars != nil {
 		update["configuration.environment_vars"] = req.EnvironmentVars
+		update["config_overrides.environment_vars"] = req.EnvironmentVars
+		deployment.Configuration.EnvironmentVars = req.EnvironmentVars
 	}
 
 	if req.AutoScaling != nil {
 		update["configuration.auto_scaling"] = req.AutoScaling
+		update["config_overrides.auto_scaling"] = req.AutoScaling
+		update["explicit_settings.auto_scaling"] = true
+		deployment.Configuration.AutoScaling = *req.AutoScaling
 	}
 
-	return uc.deploymentRepo.Update(ctx, id, update)
+	if req.ReconcilePolicy != nil {
+		switch *req.ReconcilePolicy {
+		case entities.ReconcilePolicyOff, entities.ReconcilePolicyReport, entities.ReconcilePolicyAutoRepair:
+			update["configuration.reconcile_policy"] = *req.ReconcilePolicy
+		default:
+			return fmt.Errorf("unsupported reconcile policy: %s", *req.ReconcilePolicy)
+		}
+	}
+
+	if req.Image != nil {
+		if deployment.Source.Type != entities.SourceTypeImage {
+			return errors.New("image can only be changed on image deployments")
+		}
+		if _, err := registry.ParseReference(*req.Image); err != nil {
+			return err
+		}
+	}
+
+	if err := uc.deploymentRepo.Update(ctx, id, update); err != nil {
+		return err
+	}
+
+	// A new image reference is resolved and rolled out like a redeploy
+	if req.Image != nil {
+		deployment.Source.Image = *req.Image
+		return uc.redeployImage(ctx, deployment)
+	}
+
+	// Helm releases take configuration changes through an upgrade
+	if isHelm && (req.Replicas != nil || req.EnvironmentVars != nil || req.AutoScaling != nil) {
+		return uc.upgradeHelmRelease(ctx, deployment)
+	}
+	return nil
 }
 
-func (uc *deploymentUseCase) DeleteDeployment(ctx context.Context, id primitive.ObjectID, k8sClient *k8s.Client) error {
+// RedeployDeployment re-reads espaze.yaml, merges it with the stored configuration
+// and rolls the result out
+func (uc *deploymentUseCase) RedeployDeployment(ctx context.Context, id primitive.ObjectID, githubToken string) (*entities.Deployment, error) {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Status == entities.DeploymentStatusDeleting {
+		return nil, errors.New("deployment is being deleted")
+	}
+
+	// Image deployments pick up wherever their tag points now
+	if deployment.Source.Type == entities.SourceTypeImage {
+		if err := uc.redeployImage(ctx, deployment); err != nil {
+			return nil, err
+		}
+		return deployment, nil
+	}
+	if githubToken == "" {
+		return nil, errors.New("GitHub token is required")
+	}
+
+	manifest, err := uc.loadAppManifest(ctx, githubToken, deployment.GitHubRepo, deployment.ManifestPath)
+	if err != nil {
+		return nil, err
+	}
+	deployment.Configuration = mergeAppManifest(deployment.Configuration, manifest, deployment.ConfigOverrides, deployment.ExplicitSettings)
+	applyConfigDefaults(&deployment.Configuration, deployment.GitHubRepo, deployment.ExplicitSettings)
+
+	// Pick up an added or removed Dockerfile, or regenerate the generated one
+	if deployment.Source.Type == "" || deployment.Source.Type == entities.SourceTypeDockerfile {
+		if err := uc.locateDockerfile(ctx, githubToken, deployment.GitHubRepo, &deployment.Configuration); err != nil {
+			return nil, err
+		}
+	}
+
+	update := map[string]interface{}{
+		"configuration":   deployment.Configuration,
+		"deployed_commit": uc.branchHead(ctx, githubToken, deployment.GitHubRepo),
+		"status":          entities.DeploymentStatusUpdating,
+	}
+	if err := uc.deploymentRepo.Update(ctx, id, update); err != nil {
+		return nil, err
+	}
+
+	// Roll out to Kubernetes asynchronously
+	go func() {
+		deployCtx := context.Background()
+
+		switch deployment.Source.Type {
+		case entities.SourceTypeHelm:
+			uc.rolloutHelmRelease(deployCtx, deployment, githubToken)
+			return
+		case entities.SourceTypeManifests:
+			uc.rolloutManifests(deployCtx, deployment, githubToken)
+			return
+		}
+
+		if uc.agentManaged(deployCtx, deployment.NodeID) {
+			uc.rolloutViaAgent(deployCtx, deployment)
+			return
+		}
+
+		k8sClient, err := uc.clusters.Client(deployCtx, deployment.NodeID)
+		if err != nil {
+			uc.deploymentRepo.UpdateStatus(deployCtx, deployment.ID, entities.DeploymentStatusFailed)
+			return
+		}
+		if err := uc.syncPullSecrets(deployCtx, deployment); err != nil {
+			uc.deploymentRepo.UpdateStatus(deployCtx, deployment.ID, entities.DeploymentStatusFailed)
+			return
+		}
+		if err := k8sClient.ApplyApplication(deployCtx, deployment); err != nil {
+			uc.deploymentRepo.UpdateStatus(deployCtx, deployment.ID, entities.DeploymentStatusFailed)
+			return
+		}
+
+		update := map[string]interface{}{
+			"kubernetes_info":                  deployment.KubernetesInfo,
+			"configuration.image_pull_secrets": deployment.Configuration.ImagePullSecrets,
+			"deployed_at":                      time.Now(),
+			"status":                           entities.DeploymentStatusRunning,
+		}
+		uc.deploymentRepo.Update(deployCtx, deployment.ID, update)
+	}()
+
+	deployment.Status = entities.DeploymentStatusUpdating
+	return deployment, nil
+}
+
+// applyAppManifest merges the repository's espaze.yaml into the request configuration
+// and returns the values that were given explicitly in the request
+func (uc *deploymentUseCase) applyAppManifest(ctx context.Context, req *entities.DeploymentRequest, githubToken string) (entities.DeploymentConfig, error) {
+	overrides := req.Configuration
+
+	manifest, err := uc.loadAppManifest(ctx, githubToken, req.GitHubRepo, req.ManifestPath)
+	if err != nil {
+		return overrides, err
+	}
+	req.Configuration = mergeAppManifest(req.Configuration, manifest, overrides, req.Explicit)
+
+	return overrides, nil
+}
+
+// PreviewDeployment validates and renders a new deployment and has the API server
+// dry-run it, without storing anything
+func (uc *deploymentUseCase) PreviewDeployment(ctx context.Context, userID, nodeID primitive.ObjectID, req *entities.DeploymentRequest, githubToken string) (*entities.DeploymentPreview, error) {
+	if err := validateDeploymentRequest(req); err != nil {
+		return nil, err
+	}
+	if !rendersObjects(req.Source) {
+		return nil, fmt.Errorf("dry run is not supported for %s deployments", req.Source.Type)
+	}
+
+	// espaze.yaml can only be read with a GitHub token
+	overrides := req.Configuration
+	if githubToken != "" && req.Source.Type != entities.SourceTypeImage {
+		var err error
+		if overrides, err = uc.applyAppManifest(ctx, req, githubToken); err != nil {
+			return nil, err
+		}
+	}
+	applyConfigDefaults(&req.Configuration, req.GitHubRepo, req.Explicit)
+
+	deployment := &entities.Deployment{
+		NodeID:      nodeID,
+		UserID:      userID,
+		Name:        req.Name,
+		ContextPath: req.ContextPath,
+		Namespace:   req.Namespace,
+		Status:      entities.DeploymentStatusPending,
+		GitHubRepo: entities.GitHubRepository{
+			Owner:    req.GitHubRepo.Owner,
+			Name:     req.GitHubRepo.Name,
+			FullName: fmt.Sprintf("%s/%s", req.GitHubRepo.Owner, req.GitHubRepo.Name),
+			Branch:   req.GitHubRepo.Branch,
+		},
+		Source:          req.Source,
+		Configuration:   req.Configuration,
+		ConfigOverrides: overrides,
+		ManifestPath:    req.ManifestPath,
+	}
+	if deployment.Namespace == "" {
+		deployment.Namespace = "espaze-node-deployer-apps"
+	}
+	if req.Source.Type == entities.SourceTypeImage {
+		deployment.GitHubRepo = entities.GitHubRepository{}
+		if err := uc.resolveImage(ctx, deployment); err != nil {
+			return nil, err
+		}
+	}
+	if err := uc.place(ctx, deployment, req.Placement); err != nil {
+		return nil, err
+	}
+
+	return uc.preview(ctx, deployment)
+}
+
+// fleetOf returns the fleet a deployment is created for, if any
+func fleetOf(req *entities.DeploymentRequest) *primitive.ObjectID {
+	if req.FleetID.IsZero() {
+		return nil
+	}
+	fleetID := req.FleetID
+	return &fleetID
+}
+
+// checkMaintenance refuses new deployments on a node that does not exist or is in
+// maintenance. The scheduler only places on online nodes already.
+func (uc *deploymentUseCase) checkMaintenance(ctx context.Context, nodeID primitive.ObjectID) error {
+	if nodeID.IsZero() {
+		return nil
+	}
+	node, err := uc.nodeRepo.GetByID(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	if node.Status == entities.NodeStatusMaintenance {
+		return ErrNodeInMaintenance
+	}
+	return nil
+}
+
+// place has the scheduler choose the node of a deployment created without one and
+// records the decision on it
+func (uc *deploymentUseCase) place(ctx context.Context, deployment *entities.Deployment, req *entities.PlacementRequest) error {
+	if !deployment.NodeID.IsZero() {
+		return nil
+	}
+
+	decision, err := uc.placement.Place(ctx, req, deployment)
+	if err != nil {
+		return err
+	}
+	deployment.NodeID = decision.NodeID
+	deployment.Placement = decision
+	return nil
+}
+
+// PreviewUpdate shows what an update would change in the cluster without applying it
+func (uc *deploymentUseCase) PreviewUpdate(ctx context.Context, id primitive.ObjectID, req *entities.DeploymentUpdateRequest) (*entities.DeploymentPreview, error) {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !rendersObjects(deployment.Source) {
+		return nil, fmt.Errorf("dry run is not supported for %s deployments", deployment.Source.Type)
+	}
+
+	if req.Replicas != nil {
+		deployment.Configuration.Replicas = *req.Replicas
+	}
+	if req.EnvironmentVars != nil {
+		deployment.Configuration.EnvironmentVars = req.EnvironmentVars
+	}
+	if req.AutoScaling != nil {
+		deployment.Configuration.AutoScaling = *req.AutoScaling
+	}
+
+	return uc.preview(ctx, deployment)
+}
+
+func (uc *deploymentUseCase) preview(ctx context.Context, deployment *entities.Deployment) (*entities.DeploymentPreview, error) {
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := k8sClient.DryRunApplication(ctx, deployment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dry-run deployment: %w", err)
+	}
+
+	valid := true
+	for _, object := range objects {
+		if object.Error != "" {
+			valid = false
+		}
+	}
+
+	return &entities.DeploymentPreview{
+		Deployment: deployment,
+		Objects:    objects,
+		Valid:      valid,
+	}, nil
+}
+
+// DeleteDeployment moves the deployment to the deleting state, deletes its objects and
+// removes the record once the cluster confirms they are gone. It can be retried.
+func (uc *deploymentUseCase) DeleteDeployment(ctx context.Context, id primitive.ObjectID) error {
 	deployment, err := uc.deploymentRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -250,17 +624,60 @@ func (uc *deploymentUseCase) DeleteDeployment(ctx context.Context, id primitive.
 	if deployment == nil {
 		return errors.New("deployment not found")
 	}
+	if uc.agentManaged(ctx, deployment.NodeID) {
+		return uc.deleteViaAgent(ctx, deployment)
+	}
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return err
+	}
+
+	update := map[string]interface{}{
+		"status":         entities.DeploymentStatusDeleting,
+		"deletion_error": "",
+	}
+	if err := uc.deploymentRepo.Update(ctx, id, update); err != nil {
+		return err
+	}
+
+	switch deployment.Source.Type {
+	case entities.SourceTypeHelm:
+		uc.uninstallHelmRelease(deployment, k8sClient)
+		return nil
+	case entities.SourceTypeManifests:
+		return uc.deleteManifests(ctx, deployment, k8sClient)
+	}
 
 	// Delete from Kubernetes
-	if err := k8sClient.DeleteApplication(ctx, deployment.Namespace, deployment.KubernetesInfo.DeploymentName); err != nil {
+	if err := k8sClient.DeleteApplication(ctx, deployment); err != nil {
+		uc.recordDeletionError(ctx, id, err)
 		return fmt.Errorf("failed to delete from Kubernetes: %w", err)
 	}
 
-	// Delete from database
-	return uc.deploymentRepo.Delete(ctx, id)
+	// Wait for the objects to disappear before finalizing
+	go func() {
+		finalizeCtx := context.Background()
+
+		if err := k8sClient.WaitForApplicationDeletion(finalizeCtx, deployment, deletionTimeout); err != nil {
+			uc.recordDeletionError(finalizeCtx, id, err)
+			return
+		}
+
+		// Delete from database
+		uc.deploymentRepo.Delete(finalizeCtx, id)
+	}()
+
+	return nil
+}
+
+func (uc *deploymentUseCase) recordDeletionError(ctx context.Context, id primitive.ObjectID, err error) {
+	update := map[string]interface{}{
+		"deletion_error": err.Error(),
+	}
+	uc.deploymentRepo.Update(ctx, id, update)
 }
 
-func (uc *deploymentUseCase) RestartDeployment(ctx context.Context, id primitive.ObjectID, k8sClient *k8s.Client) error {
+func (uc *deploymentUseCase) RestartDeployment(ctx context.Context, id primitive.ObjectID) error {
 	deployment, err := uc.deploymentRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -268,11 +685,21 @@ func (uc *deploymentUseCase) RestartDeployment(ctx context.Context, id primitive
 	if deployment == nil {
 		return errors.New("deployment not found")
 	}
+	if agentSupports(deployment.Source.Type) {
+		return uc.restartWorkload(ctx, deployment)
+	}
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return err
+	}
 
-	return k8sClient.RestartDeployment(ctx, deployment.Namespace, deployment.KubernetesInfo.DeploymentName)
+	if deployment.Source.Type == entities.SourceTypeHelm {
+		return k8sClient.RestartRelease(ctx, deployment.Namespace, k8s.HelmReleaseName(deployment))
+	}
+	return uc.restartManifests(ctx, deployment, k8sClient)
 }
 
-func (uc *deploymentUseCase) ScaleDeployment(ctx context.Context, id primitive.ObjectID, replicas int32, k8sClient *k8s.Client) error {
+func (uc *deploymentUseCase) ScaleDeployment(ctx context.Context, id primitive.ObjectID, replicas int32) error {
 	deployment, err := uc.deploymentRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -281,18 +708,38 @@ func (uc *deploymentUseCase) ScaleDeployment(ctx context.Context, id primitive.O
 		return errors.New("deployment not found")
 	}
 
-	if err := k8sClient.ScaleDeployment(ctx, deployment.Namespace, deployment.KubernetesInfo.DeploymentName, replicas); err != nil {
+	if deployment.Source.Type == entities.SourceTypeManifests {
+		return errors.New("replicas of manifests deployments are set in their manifests")
+	}
+
+	// Helm releases are scaled through an upgrade so the chart stays the source of truth
+	if deployment.Source.Type == entities.SourceTypeHelm {
+		update := map[string]interface{}{
+			"configuration.replicas":     replicas,
+			"config_overrides.replicas":  replicas,
+			"explicit_settings.replicas": true,
+		}
+		if err := uc.deploymentRepo.Update(ctx, id, update); err != nil {
+			return err
+		}
+		deployment.Configuration.Replicas = replicas
+		return uc.upgradeHelmRelease(ctx, deployment)
+	}
+
+	if err := uc.scaleWorkload(ctx, deployment, replicas); err != nil {
 		return err
 	}
 
-	// Update database
+	// Recorded as an override too, so the next rollout or evacuation keeps the count
 	update := map[string]interface{}{
-		"configuration.replicas": replicas,
+		"configuration.replicas":     replicas,
+		"config_overrides.replicas":  replicas,
+		"explicit_settings.replicas": true,
 	}
 	return uc.deploymentRepo.Update(ctx, id, update)
 }
 
-func (uc *deploymentUseCase) UpdateDeploymentMetrics(ctx context.Context, id primitive.ObjectID, k8sClient *k8s.Client) error {
+func (uc *deploymentUseCase) UpdateDeploymentMetrics(ctx context.Context, id primitive.ObjectID) error {
 	deployment, err := uc.deploymentRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -300,6 +747,10 @@ func (uc *deploymentUseCase) UpdateDeploymentMetrics(ctx context.Context, id pri
 	if deployment == nil {
 		return errors.New("deployment not found")
 	}
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return err
+	}
 
 	// Get pods
 	pods, err := k8sClient.GetPods(ctx, deployment.Namespace)
@@ -332,19 +783,109 @@ func (uc *deploymentUseCase) GetDeploymentStats(ctx context.Context, nodeID *pri
 	return uc.deploymentRepo.GetDeploymentStats(ctx, nodeID)
 }
 
-func (uc *deploymentUseCase) validateDeploymentRequest(req *entities.DeploymentRequest) error {
+func validateDeploymentRequest(req *entities.DeploymentRequest) error {
 	if req.Name == "" {
 		return errors.New("name is required")
 	}
 	if req.ContextPath == "" {
 		return errors.New("context path is required")
 	}
-	if req.GitHubRepo.Owner == "" || req.GitHubRepo.Name == "" {
-		return errors.New("GitHub repository owner and name are required")
+	if k8s.IsSystemNamespace(req.Namespace) {
+		return fmt.Errorf("namespace %s belongs to the cluster and cannot be deployed to", req.Namespace)
+	}
+	if req.Source.Type == entities.SourceTypeImage {
+		if _, err := registry.ParseReference(req.Source.Image); err != nil {
+			return err
+		}
+	} else {
+		if req.GitHubRepo.Owner == "" || req.GitHubRepo.Name == "" {
+			return errors.New("GitHub repository owner and name are required")
+		}
+		if req.GitHubRepo.Branch == "" {
+			return errors.New("GitHub branch is required")
+		}
 	}
-	if req.GitHubRepo.Branch == "" {
-		return errors.New("GitHub branch is required")
+	switch req.Configuration.WorkloadKind {
+	case "", entities.WorkloadKindDeployment:
+	case entities.WorkloadKindStatefulSet:
+		for _, vct := range req.Configuration.StatefulSet.VolumeClaimTemplates {
+			if vct.Name == "" || vct.MountPath == "" {
+				return errors.New("volume claim templates require a name and mount path")
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported workload kind: %s", req.Configuration.WorkloadKind)
+	}
+	switch req.Source.Type {
+	case "", entities.SourceTypeDockerfile, entities.SourceTypeHelm, entities.SourceTypeManifests, entities.SourceTypeImage:
+	default:
+		return fmt.Errorf("unsupported source type: %s", req.Source.Type)
+	}
+	// Values files are read from the chart directory on the server
+	for i, file := range req.Source.ValuesFiles {
+		cleaned, err := cleanRepoPath(file)
+		if err != nil {
+			return fmt.Errorf("invalid values file: %w", err)
+		}
+		if cleaned == "." {
+			return fmt.Errorf("invalid values file: %q", file)
+		}
+		req.Source.ValuesFiles[i] = cleaned
 	}
 	return nil
 }
 
+// rendersObjects reports whether the deployer renders the deployment's objects itself,
+// as opposed to a chart or manifests from the repository
+func rendersObjects(source entities.SourceConfig) bool {
+	switch source.Type {
+	case "", entities.SourceTypeDockerfile, entities.SourceTypeImage:
+		return true
+	}
+	return false
+}
+
+// applyConfigDefaults fills in configuration the request left empty. A replica count
+// set to 0 explicitly is kept.
+func applyConfigDefaults(config *entities.DeploymentConfig, repo entities.GitHubRepository, explicit entities.ExplicitSettings) {
+	if config.Replicas == 0 && !explicit.Replicas {
+		config.Replicas = 2
+	}
+	if config.MemoryRequest == "" {
+		config.MemoryRequest = "256Mi"
+	}
+	if config.MemoryLimit == "" {
+		config.MemoryLimit = "512Mi"
+	}
+	if config.CPURequest == "" {
+		config.CPURequest = "250m"
+	}
+	if config.CPULimit == "" {
+		config.CPULimit = "500m"
+	}
+	if config.ContainerPort == 0 {
+		config.ContainerPort = 8080
+	}
+	if config.ServicePort == 0 {
+		config.ServicePort = 80
+	}
+	if config.ImagePullPolicy == "" {
+		config.ImagePullPolicy = "IfNotPresent"
+	}
+	if config.RestartPolicy == "" {
+		config.RestartPolicy = "Always"
+	}
+	if config.WorkloadKind == "" {
+		config.WorkloadKind = entities.WorkloadKindDeployment
+	}
+	if config.ReconcilePolicy == "" {
+		config.ReconcilePolicy = entities.ReconcilePolicyReport
+	}
+
+	if config.BuildConfig.ImageName == "" {
+		config.BuildConfig.ImageName = fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+	}
+	if config.BuildConfig.ImageTag == "" {
+		config.BuildConfig.ImageTag = "latest"
+	}
+}
@@ -0,0 +1,459 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNodeInMaintenance is returned for new deployments on a node in maintenance
+	ErrNodeInMaintenance = errors.New("node is in maintenance")
+	// ErrNodeNotInMaintenance is returned when evacuating a node that is not in maintenance
+	ErrNodeNotInMaintenance = errors.New("node is not in maintenance")
+)
+
+const (
+	// replacementTimeout bounds the wait for a replacement to become healthy
+	replacementTimeout = 15 * time.Minute
+	replacementPoll    = 5 * time.Second
+)
+
+type EvacuationUseCase interface {
+	Evacuate(ctx context.Context, nodeID primitive.ObjectID) (*entities.Evacuation, error)
+	GetEvacuation(ctx context.Context, nodeID primitive.ObjectID) (*entities.Evacuation, error)
+}
+
+type evacuationUseCase struct {
+	evacuationRepo repository.EvacuationRepository
+	nodeRepo       repository.NodeRepository
+	deploymentRepo repository.DeploymentRepository
+	fleetRepo      repository.FleetRepository
+	tokenRepo      repository.GitHubTokenRepository
+	deployments    DeploymentUseCase
+	clusters       *k8s.ClusterManager
+
+	mu      sync.Mutex
+	running map[primitive.ObjectID]bool // Nodes this process is evacuating
+}
+
+func NewEvacuationUseCase(
+	evacuationRepo repository.EvacuationRepository,
+	nodeRepo repository.NodeRepository,
+	deploymentRepo repository.DeploymentRepository,
+	fleetRepo repository.FleetRepository,
+	tokenRepo repository.GitHubTokenRepository,
+	deployments DeploymentUseCase,
+	clusters *k8s.ClusterManager,
+) EvacuationUseCase {
+	return &evacuationUseCase{
+		evacuationRepo: evacuationRepo,
+		nodeRepo:       nodeRepo,
+		deploymentRepo: deploymentRepo,
+		fleetRepo:      fleetRepo,
+		tokenRepo:      tokenRepo,
+		deployments:    deployments,
+		clusters:       clusters,
+		running:        map[primitive.ObjectID]bool{},
+	}
+}
+
+// Evacuate starts moving every deployment off a node in maintenance and returns the
+// evacuation, whose progress is stored as it goes. A node already being evacuated
+// returns its running evacuation.
+func (uc *evacuationUseCase) Evacuate(ctx context.Context, nodeID primitive.ObjectID) (*entities.Evacuation, error) {
+	node, err := uc.nodeRepo.GetByID(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, ErrNodeNotFound
+	}
+	if node.Status != entities.NodeStatusMaintenance {
+		return nil, ErrNodeNotInMaintenance
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	latest, err := uc.evacuationRepo.GetLatestByNodeID(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Status == entities.EvacuationStatusRunning {
+		if uc.running[nodeID] {
+			return latest, nil
+		}
+		// Nothing here runs it any more, so the server restarted halfway through
+		if err := uc.finish(ctx, latest, entities.EvacuationStatusFailed, "interrupted by a server restart"); err != nil {
+			return nil, err
+		}
+	}
+
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{"node_id": nodeID})
+	if err != nil {
+		return nil, err
+	}
+
+	evacuation := &entities.Evacuation{
+		NodeID:     nodeID,
+		NodeName:   node.NodeName,
+		Status:     entities.EvacuationStatusRunning,
+		Migrations: []entities.Migration{},
+	}
+	for _, deployment := range deployments {
+		if deployment.Status == entities.DeploymentStatusDeleting {
+			continue
+		}
+		evacuation.Migrations = append(evacuation.Migrations, entities.Migration{
+			DeploymentID: deployment.ID,
+			Name:         deployment.Name,
+			Status:       entities.MigrationStatusPending,
+		})
+	}
+	if len(evacuation.Migrations) == 0 {
+		evacuation.Status = entities.EvacuationStatusCompleted
+		now := time.Now()
+		evacuation.FinishedAt = &now
+	}
+	if err := uc.evacuationRepo.Create(ctx, evacuation); err != nil {
+		return nil, err
+	}
+
+	if evacuation.Status == entities.EvacuationStatusRunning {
+		uc.running[nodeID] = true
+		progress := *evacuation
+		progress.Migrations = append([]entities.Migration(nil), evacuation.Migrations...)
+		go uc.run(context.Background(), &progress)
+	}
+	return evacuation, nil
+}
+
+func (uc *evacuationUseCase) GetEvacuation(ctx context.Context, nodeID primitive.ObjectID) (*entities.Evacuation, error) {
+	return uc.evacuationRepo.GetLatestByNodeID(ctx, nodeID)
+}
+
+// run migrates the deployments one at a time. It stops early when the node leaves
+// maintenance, leaving the remaining deployments where they are.
+func (uc *evacuationUseCase) run(ctx context.Context, evacuation *entities.Evacuation) {
+	defer func() {
+		uc.mu.Lock()
+		delete(uc.running, evacuation.NodeID)
+		uc.mu.Unlock()
+	}()
+
+	status := entities.EvacuationStatusCompleted
+	for i := range evacuation.Migrations {
+		migration := &evacuation.Migrations[i]
+
+		node, err := uc.nodeRepo.GetByID(ctx, evacuation.NodeID)
+		if err != nil || node == nil || node.Status != entities.NodeStatusMaintenance {
+			for j := i; j < len(evacuation.Migrations); j++ {
+				evacuation.Migrations[j].Status = entities.MigrationStatusCancelled
+			}
+			status = entities.EvacuationStatusCancelled
+			break
+		}
+
+		uc.migrate(ctx, evacuation, migration)
+		if migration.Status == entities.MigrationStatusFailed {
+			status = entities.EvacuationStatusFailed
+		}
+	}
+
+	message := ""
+	if status == entities.EvacuationStatusFailed {
+		message = "some deployments could not be moved and are still on the node"
+	}
+	if err := uc.finish(ctx, evacuation, status, message); err != nil {
+		log.Printf("Evacuation %s: %v", evacuation.ID.Hex(), err)
+	}
+	log.Printf("Evacuation of node %s (%s) %s", evacuation.NodeName, evacuation.NodeID.Hex(), status)
+}
+
+// migrate deploys a replacement of one deployment through the scheduler on a node
+// outside the original's cluster, waits for it to become healthy and then deletes the
+// original. A replacement that does not become healthy is deleted again and the
+// original keeps running.
+func (uc *evacuationUseCase) migrate(ctx context.Context, evacuation *entities.Evacuation, migration *entities.Migration) {
+	started := time.Now()
+	migration.StartedAt = &started
+	defer func() {
+		finished := time.Now()
+		migration.FinishedAt = &finished
+		uc.saveProgress(ctx, evacuation)
+	}()
+
+	original, err := uc.deploymentRepo.GetByID(ctx, migration.DeploymentID)
+	if err != nil {
+		migration.Status = entities.MigrationStatusFailed
+		migration.Error = err.Error()
+		return
+	}
+	if original == nil || original.NodeID != evacuation.NodeID || original.Status == entities.DeploymentStatusDeleting {
+		migration.Status = entities.MigrationStatusSkipped
+		migration.Error = "deployment was deleted or moved in the meantime"
+		return
+	}
+	if reason := unmovable(original); reason != "" {
+		migration.Status = entities.MigrationStatusFailed
+		migration.Error = reason
+		return
+	}
+
+	req, err := uc.replacementRequest(ctx, original)
+	if err != nil {
+		migration.Status = entities.MigrationStatusFailed
+		migration.Error = err.Error()
+		return
+	}
+	if req.Placement == nil {
+		req.Placement = &entities.PlacementRequest{}
+	}
+	if req.Placement.ExcludeNodes, err = uc.occupiedNodes(ctx, original); err != nil {
+		migration.Status = entities.MigrationStatusFailed
+		migration.Error = err.Error()
+		return
+	}
+
+	githubToken := ""
+	if original.Source.Type != entities.SourceTypeImage {
+		token, err := uc.tokenRepo.GetByUserID(ctx, original.UserID)
+		if err != nil || token == nil {
+			migration.Status = entities.MigrationStatusFailed
+			migration.Error = "a GitHub token is required to redeploy the deployment elsewhere"
+			return
+		}
+		githubToken = token.Token
+	}
+
+	replacement, err := uc.deployments.CreateDeployment(ctx, original.UserID, primitive.NilObjectID, req, githubToken)
+	if err != nil {
+		migration.Status = entities.MigrationStatusFailed
+		migration.Error = fmt.Sprintf("failed to deploy a replacement: %v", err)
+		return
+	}
+	migration.Status = entities.MigrationStatusDeploying
+	migration.ReplacementID = replacement.ID
+	migration.TargetNodeID = replacement.NodeID
+	if replacement.Placement != nil {
+		migration.TargetNodeName = replacement.Placement.NodeName
+	}
+	uc.saveProgress(ctx, evacuation)
+
+	if err := uc.waitHealthy(ctx, replacement); err != nil {
+		migration.Status = entities.MigrationStatusFailed
+		migration.Error = fmt.Sprintf("replacement did not become healthy: %v", err)
+		if err := uc.deployments.DeleteDeployment(ctx, replacement.ID); err != nil {
+			migration.Error += fmt.Sprintf("; deleting it failed too: %v", err)
+		}
+		return
+	}
+
+	if err := uc.deployments.DeleteDeployment(ctx, original.ID); err != nil {
+		migration.Status = entities.MigrationStatusFailed
+		migration.Error = fmt.Sprintf("replacement is running but the original could not be deleted: %v", err)
+		return
+	}
+	uc.moveFleetMember(ctx, original, replacement, migration.TargetNodeName)
+	migration.Status = entities.MigrationStatusCompleted
+}
+
+// unmovable explains why a deployment cannot be redeployed elsewhere without losing
+// something, or returns "" when it can. Secrets referenced by environment variables
+// exist only in the original's cluster, and a StatefulSet's volumes stay behind with it.
+func unmovable(deployment *entities.Deployment) string {
+	if len(deployment.Configuration.SecretEnvVars) > 0 {
+		return "deployment reads environment variables from secrets that exist only on this node's cluster; move it by hand"
+	}
+	if deployment.Configuration.WorkloadKind == entities.WorkloadKindStatefulSet {
+		return "deployment is a StatefulSet whose volumes cannot be moved; move its data by hand"
+	}
+	return ""
+}
+
+// replacementRequest rebuilds the request a deployment was created from. Explicitly
+// given configuration is kept and espaze.yaml is applied again on creation; the
+// original placement constraints, or a fleet's label selector, still apply.
+func (uc *evacuationUseCase) replacementRequest(ctx context.Context, deployment *entities.Deployment) (*entities.DeploymentRequest, error) {
+	req := &entities.DeploymentRequest{
+		Name:          deployment.Name,
+		ContextPath:   deployment.ContextPath,
+		GitHubRepo:    deployment.GitHubRepo,
+		Source:        deployment.Source,
+		Configuration: deployment.ConfigOverrides,
+		Namespace:     deployment.Namespace,
+		ManifestPath:  deployment.ManifestPath,
+		Explicit:      deployment.ExplicitSettings,
+	}
+	req.Configuration.ReconcilePolicy = deployment.Configuration.ReconcilePolicy
+
+	// A Dockerfile given by hand is not in the repository
+	build := deployment.Configuration.BuildConfig
+	if build.DockerfileContent != "" && build.Stack == "" {
+		req.Configuration.BuildConfig.DockerfileContent = build.DockerfileContent
+	}
+
+	if deployment.Placement != nil {
+		placement := deployment.Placement.Request
+		req.Placement = &placement
+	}
+
+	if deployment.FleetID != nil {
+		req.FleetID = *deployment.FleetID
+
+		fleet, err := uc.fleetRepo.GetByID(ctx, *deployment.FleetID)
+		if err != nil {
+			return nil, err
+		}
+		if fleet != nil && fleet.Target.Type == entities.FleetTargetSelector {
+			req.Placement = &entities.PlacementRequest{NodeSelector: fleet.Target.NodeSelector}
+		}
+	}
+
+	return req, nil
+}
+
+// occupiedNodes lists the nodes a replacement must not be placed on: those sharing the
+// original's cluster, and for a fleet child those sharing a cluster with another child.
+// A replacement with the same name and namespace would be applied onto the objects
+// already there, and deleting the original would remove it as well. Nodes whose
+// cluster cannot be resolved are listed too.
+func (uc *evacuationUseCase) occupiedNodes(ctx context.Context, deployment *entities.Deployment) ([]primitive.ObjectID, error) {
+	occupied := []primitive.ObjectID{deployment.NodeID}
+	if deployment.FleetID != nil {
+		siblings, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{"fleet_id": *deployment.FleetID})
+		if err != nil {
+			return nil, err
+		}
+		for _, sibling := range siblings {
+			occupied = append(occupied, sibling.NodeID)
+		}
+	}
+
+	nodes, err := uc.nodeRepo.GetAll(ctx, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*entities.Node, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+
+	excluded := []primitive.ObjectID{}
+	for _, node := range nodes {
+		for _, id := range occupied {
+			other, ok := byID[id]
+			if !ok {
+				continue
+			}
+			if same, err := sameCluster(ctx, uc.clusters, node, other); err != nil || same {
+				excluded = append(excluded, node.ID)
+				break
+			}
+		}
+	}
+	return excluded, nil
+}
+
+// waitHealthy waits until a deployment runs with all its pods ready. Pod readiness is
+// only checked where the cluster can be reached directly.
+func (uc *evacuationUseCase) waitHealthy(ctx context.Context, deployment *entities.Deployment) error {
+	ctx, cancel := context.WithTimeout(ctx, replacementTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(replacementPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not healthy after %s", replacementTimeout)
+		case <-ticker.C:
+		}
+
+		current, err := uc.deploymentRepo.GetByID(ctx, deployment.ID)
+		if err != nil {
+			continue
+		}
+		if current == nil {
+			return errors.New("deployment was deleted")
+		}
+
+		switch current.Status {
+		case entities.DeploymentStatusFailed, entities.DeploymentStatusStopped, entities.DeploymentStatusDeleting:
+			return fmt.Errorf("deployment is %s", current.Status)
+		case entities.DeploymentStatusRunning:
+			if uc.agentManaged(ctx, current.NodeID) {
+				return nil
+			}
+			if err := uc.deployments.UpdateDeploymentMetrics(ctx, current.ID); err != nil {
+				continue
+			}
+			if current, err = uc.deploymentRepo.GetByID(ctx, current.ID); err != nil || current == nil {
+				continue
+			}
+			if current.Metrics.ReadyPods >= int(current.Configuration.Replicas) {
+				return nil
+			}
+		}
+	}
+}
+
+func (uc *evacuationUseCase) agentManaged(ctx context.Context, nodeID primitive.ObjectID) bool {
+	node, err := uc.nodeRepo.GetByID(ctx, nodeID)
+	return err == nil && node != nil && node.AccessMode == entities.NodeAccessAgent
+}
+
+// moveFleetMember points a fleet's member at the node and deployment that replaced it
+func (uc *evacuationUseCase) moveFleetMember(ctx context.Context, original, replacement *entities.Deployment, nodeName string) {
+	if original.FleetID == nil {
+		return
+	}
+	fleet, err := uc.fleetRepo.GetByID(ctx, *original.FleetID)
+	if err != nil || fleet == nil {
+		return
+	}
+
+	for i := range fleet.Members {
+		member := &fleet.Members[i]
+		if member.DeploymentID != original.ID {
+			continue
+		}
+		member.NodeID = replacement.NodeID
+		member.NodeName = nodeName
+		member.DeploymentID = replacement.ID
+		member.DistanceKm = nil
+	}
+	if err := uc.fleetRepo.Update(ctx, fleet.ID, map[string]interface{}{"members": fleet.Members}); err != nil {
+		log.Printf("Evacuation: fleet %s: %v", fleet.ID.Hex(), err)
+	}
+}
+
+func (uc *evacuationUseCase) saveProgress(ctx context.Context, evacuation *entities.Evacuation) {
+	if err := uc.evacuationRepo.Update(ctx, evacuation.ID, map[string]interface{}{"migrations": evacuation.Migrations}); err != nil {
+		log.Printf("Evacuation %s: %v", evacuation.ID.Hex(), err)
+	}
+}
+
+func (uc *evacuationUseCase) finish(ctx context.Context, evacuation *entities.Evacuation, status entities.EvacuationStatus, message string) error {
+	now := time.Now()
+	evacuation.Status = status
+	evacuation.Error = message
+	evacuation.FinishedAt = &now
+
+	return uc.evacuationRepo.Update(ctx, evacuation.ID, map[string]interface{}{
+		"status":      status,
+		"error":       message,
+		"migrations":  evacuation.Migrations,
+		"finished_at": now,
+	})
+}
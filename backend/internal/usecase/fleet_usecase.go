@@ -0,0 +1,368 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrFleetNotFound = errors.New("fleet not found")
+
+type FleetUseCase interface {
+	CreateFleet(ctx context.Context, userID primitive.ObjectID, req *entities.FleetRequest, githubToken string) (*entities.FleetView, error)
+	GetFleet(ctx context.Context, id primitive.ObjectID) (*entities.FleetView, error)
+	GetFleetsByUser(ctx context.Context, userID primitive.ObjectID) ([]*entities.FleetView, error)
+	DeleteFleet(ctx context.Context, id primitive.ObjectID) error
+}
+
+type fleetUseCase struct {
+	fleetRepo      repository.FleetRepository
+	nodeRepo       repository.NodeRepository
+	deploymentRepo repository.DeploymentRepository
+	deployments    DeploymentUseCase
+	placement      PlacementUseCase
+	clusters       *k8s.ClusterManager
+}
+
+func NewFleetUseCase(
+	fleetRepo repository.FleetRepository,
+	nodeRepo repository.NodeRepository,
+	deploymentRepo repository.DeploymentRepository,
+	deployments DeploymentUseCase,
+	placement PlacementUseCase,
+	clusters *k8s.ClusterManager,
+) FleetUseCase {
+	return &fleetUseCase{
+		fleetRepo:      fleetRepo,
+		nodeRepo:       nodeRepo,
+		deploymentRepo: deploymentRepo,
+		deployments:    deployments,
+		placement:      placement,
+		clusters:       clusters,
+	}
+}
+
+// CreateFleet picks the target's eligible nodes and creates a child deployment of the
+// spec on each. A child that cannot be created is recorded on its member; the others
+// go ahead.
+func (uc *fleetUseCase) CreateFleet(ctx context.Context, userID primitive.ObjectID, req *entities.FleetRequest, githubToken string) (*entities.FleetView, error) {
+	if req.Name == "" {
+		req.Name = req.Spec.Name
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if err := validateFleetTarget(&req.Target); err != nil {
+		return nil, err
+	}
+	if err := validateDeploymentRequest(&req.Spec); err != nil {
+		return nil, err
+	}
+	req.Spec.Placement = nil
+
+	members, rejected, err := uc.selectNodes(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, &PlacementError{Rejected: rejected}
+	}
+
+	fleet := &entities.Fleet{
+		UserID:   userID,
+		Name:     req.Name,
+		Spec:     req.Spec,
+		Target:   req.Target,
+		Members:  members,
+		Rejected: rejected,
+	}
+	if err := uc.fleetRepo.Create(ctx, fleet); err != nil {
+		return nil, err
+	}
+
+	for i := range fleet.Members {
+		member := &fleet.Members[i]
+
+		spec, err := cloneSpec(&req.Spec)
+		if err == nil {
+			spec.FleetID = fleet.ID
+			var child *entities.Deployment
+			if child, err = uc.deployments.CreateDeployment(ctx, userID, member.NodeID, spec, githubToken); err == nil {
+				member.DeploymentID = child.ID
+			}
+		}
+		if err != nil {
+			member.Error = err.Error()
+		}
+	}
+
+	if err := uc.fleetRepo.Update(ctx, fleet.ID, map[string]interface{}{"members": fleet.Members}); err != nil {
+		return nil, err
+	}
+	return uc.view(ctx, fleet)
+}
+
+// selectNodes resolves the fleet's target to the nodes that can take its spec. Every
+// child is deployed under the spec's name, so only one node per cluster becomes a
+// member; the children would otherwise share one set of objects.
+func (uc *fleetUseCase) selectNodes(ctx context.Context, req *entities.FleetRequest) ([]entities.FleetMember, []entities.PlacementRejection, error) {
+	target := req.Target
+	distances := map[primitive.ObjectID]float64{}
+
+	var nodes []*entities.Node
+	var err error
+	switch target.Type {
+	case entities.FleetTargetNearest:
+		var nearby []*entities.NodeDistance
+		nearby, err = uc.nodeRepo.GetNodesByLocation(ctx, target.Near.Latitude, target.Near.Longitude, 0, 0, map[string]interface{}{
+			"status": entities.NodeStatusOnline,
+		})
+		for _, node := range nearby {
+			node := node
+			nodes = append(nodes, &node.Node)
+			distances[node.ID] = node.DistanceKm
+		}
+	case entities.FleetTargetRegion:
+		if target.Area != nil {
+			nodes, err = uc.nodeRepo.GetNodesWithin(ctx, target.Area, map[string]interface{}{})
+		} else {
+			nodes, err = uc.nodeRepo.GetAll(ctx, map[string]interface{}{"location.region": target.Region})
+		}
+	case entities.FleetTargetSelector:
+		filters := map[string]interface{}{}
+		for key, value := range target.NodeSelector {
+			filters["metadata.labels."+key] = value
+		}
+		nodes, err = uc.nodeRepo.GetAll(ctx, filters)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The spec's requests decide which nodes have room for it
+	probe := &entities.Deployment{
+		Source:        req.Spec.Source,
+		Configuration: req.Spec.Configuration,
+	}
+	applyConfigDefaults(&probe.Configuration, req.Spec.GitHubRepo, entities.ExplicitSettings{})
+
+	eligible, rejected, err := uc.placement.Filter(ctx, nodes, probe)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	members := []entities.FleetMember{}
+	memberNodes := []*entities.Node{}
+	for _, node := range eligible {
+		if target.Type == entities.FleetTargetNearest && len(members) == target.Count {
+			break
+		}
+		if reason := uc.clusterTaken(ctx, node, memberNodes); reason != "" {
+			rejected = append(rejected, entities.PlacementRejection{
+				NodeID:   node.ID,
+				NodeName: node.NodeName,
+				Reason:   reason,
+			})
+			continue
+		}
+		memberNodes = append(memberNodes, node)
+		member := entities.FleetMember{NodeID: node.ID, NodeName: node.NodeName}
+		if distance, ok := distances[node.ID]; ok {
+			member.DistanceKm = &distance
+		}
+		members = append(members, member)
+	}
+
+	// Of the nearest nodes, only those closer than the farthest member were passed over
+	if target.Type == entities.FleetTargetNearest && len(members) == target.Count {
+		farthest := *members[len(members)-1].DistanceKm
+		closer := []entities.PlacementRejection{}
+		for _, rejection := range rejected {
+			if distances[rejection.NodeID] < farthest {
+				closer = append(closer, rejection)
+			}
+		}
+		rejected = closer
+	}
+
+	return members, rejected, nil
+}
+
+// clusterTaken returns why a node cannot join the fleet because a member already runs
+// in its cluster, or "" if it can
+func (uc *fleetUseCase) clusterTaken(ctx context.Context, node *entities.Node, members []*entities.Node) string {
+	for _, member := range members {
+		same, err := sameCluster(ctx, uc.clusters, node, member)
+		if err != nil {
+			return fmt.Sprintf("cluster cannot be resolved: %v", err)
+		}
+		if same {
+			return fmt.Sprintf("shares a cluster with member %s", member.NodeName)
+		}
+	}
+	return ""
+}
+
+func (uc *fleetUseCase) GetFleet(ctx context.Context, id primitive.ObjectID) (*entities.FleetView, error) {
+	fleet, err := uc.fleetRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if fleet == nil {
+		return nil, ErrFleetNotFound
+	}
+	return uc.view(ctx, fleet)
+}
+
+func (uc *fleetUseCase) GetFleetsByUser(ctx context.Context, userID primitive.ObjectID) ([]*entities.FleetView, error) {
+	fleets, err := uc.fleetRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*entities.FleetView, 0, len(fleets))
+	for _, fleet := range fleets {
+		view, err := uc.view(ctx, fleet)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// DeleteFleet deletes every child deployment and then the fleet. If a child cannot be
+// deleted the fleet is kept so the deletion can be retried.
+func (uc *fleetUseCase) DeleteFleet(ctx context.Context, id primitive.ObjectID) error {
+	fleet, err := uc.fleetRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if fleet == nil {
+		return ErrFleetNotFound
+	}
+
+	children, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{"fleet_id": fleet.ID})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, child := range children {
+		if err := uc.deployments.DeleteDeployment(ctx, child.ID); err != nil {
+			errs = append(errs, fmt.Errorf("deployment %s: %w", child.ID.Hex(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return uc.fleetRepo.Delete(ctx, fleet.ID)
+}
+
+// view aggregates the states and metrics of the fleet's child deployments
+func (uc *fleetUseCase) view(ctx context.Context, fleet *entities.Fleet) (*entities.FleetView, error) {
+	children, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{"fleet_id": fleet.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	view := &entities.FleetView{
+		Fleet:       *fleet,
+		Statuses:    map[entities.DeploymentStatus]int{},
+		Deployments: children,
+	}
+	if view.Deployments == nil {
+		view.Deployments = []*entities.Deployment{}
+	}
+
+	for _, child := range children {
+		view.Statuses[child.Status]++
+
+		metrics := child.Metrics
+		view.Metrics.ActivePods += metrics.ActivePods
+		view.Metrics.DesiredPods += metrics.DesiredPods
+		view.Metrics.ReadyPods += metrics.ReadyPods
+		view.Metrics.CPUUsage += metrics.CPUUsage
+		view.Metrics.MemoryUsage += metrics.MemoryUsage
+		view.Metrics.NetworkIn += metrics.NetworkIn
+		view.Metrics.NetworkOut += metrics.NetworkOut
+		view.Metrics.RequestsPerMin += metrics.RequestsPerMin
+		view.Metrics.ErrorRate += metrics.ErrorRate
+		view.Metrics.LastRestartCount += metrics.LastRestartCount
+	}
+	if len(children) > 0 {
+		view.Metrics.ErrorRate /= float64(len(children))
+	}
+
+	view.Status = fleetStatus(view.Statuses, len(children))
+	return view, nil
+}
+
+// fleetStatus sums up the statuses of a fleet's children
+func fleetStatus(statuses map[entities.DeploymentStatus]int, total int) entities.FleetStatus {
+	rollingOut := statuses[entities.DeploymentStatusPending] + statuses[entities.DeploymentStatusBuilding] +
+		statuses[entities.DeploymentStatusDeploying] + statuses[entities.DeploymentStatusUpdating]
+	running := statuses[entities.DeploymentStatusRunning]
+
+	switch {
+	case statuses[entities.DeploymentStatusDeleting] > 0:
+		return entities.FleetStatusDeleting
+	case total > 0 && running == total:
+		return entities.FleetStatusRunning
+	case rollingOut > 0:
+		return entities.FleetStatusProgressing
+	case running == 0:
+		return entities.FleetStatusFailed
+	}
+	return entities.FleetStatusDegraded
+}
+
+func validateFleetTarget(target *entities.FleetTarget) error {
+	switch target.Type {
+	case entities.FleetTargetNearest:
+		if target.Near == nil {
+			return errors.New("nearest targets need a location")
+		}
+		if target.Count <= 0 {
+			return errors.New("nearest targets need a positive count")
+		}
+		return validateCoordinates(*target.Near)
+	case entities.FleetTargetRegion:
+		if target.Area != nil {
+			if target.Area.Type != "Polygon" && target.Area.Type != "MultiPolygon" {
+				return fmt.Errorf("area must be a GeoJSON Polygon or MultiPolygon, not %q", target.Area.Type)
+			}
+			return nil
+		}
+		if target.Region == "" {
+			return errors.New("region targets need a region or an area")
+		}
+		return nil
+	case entities.FleetTargetSelector:
+		if len(target.NodeSelector) == 0 {
+			return errors.New("selector targets need at least one label")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported fleet target: %q", target.Type)
+}
+
+// cloneSpec copies a deployment request so creating one child cannot change the next
+func cloneSpec(spec *entities.DeploymentRequest) (*entities.DeploymentRequest, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var clone entities.DeploymentRequest
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
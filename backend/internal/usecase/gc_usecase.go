@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// gcMinAge protects objects of deployments that are still being rolled out,
+// whose names are only written to Mongo once creation finishes
+const gcMinAge = 10 * time.Minute
+
+type GCUseCase interface {
+	Start(ctx context.Context, interval time.Duration, dryRun bool)
+	Run(ctx context.Context, dryRun bool) (*entities.GCReport, error)
+	GetLastReport() *entities.GCReport
+}
+
+type gcUseCase struct {
+	deploymentRepo repository.DeploymentRepository
+	addonRepo      repository.AddonRepository
+	nodeRepo       repository.NodeRepository
+	clusters       *k8s.ClusterManager
+
+	mu         sync.Mutex
+	lastReport *entities.GCReport
+}
+
+func NewGCUseCase(
+	deploymentRepo repository.DeploymentRepository,
+	addonRepo repository.AddonRepository,
+	nodeRepo repository.NodeRepository,
+	clusters *k8s.ClusterManager,
+) GCUseCase {
+	return &gcUseCase{
+		deploymentRepo: deploymentRepo,
+		addonRepo:      addonRepo,
+		nodeRepo:       nodeRepo,
+		clusters:       clusters,
+	}
+}
+
+// Start runs a collection pass on every tick until ctx is cancelled
+func (uc *gcUseCase) Start(ctx context.Context, interval time.Duration, dryRun bool) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := uc.Run(ctx, dryRun)
+				if err != nil {
+					log.Printf("GC: %v", err)
+					continue
+				}
+				if len(report.Orphans) > 0 {
+					log.Printf("GC: found %d orphaned objects, deleted %d", len(report.Orphans), report.Deleted)
+				}
+			}
+		}
+	}()
+}
+
+// Run lists managed objects in every cluster, matches them against the Mongo records of
+// that cluster's nodes and deletes orphans unless dryRun is set
+func (uc *gcUseCase) Run(ctx context.Context, dryRun bool) (*entities.GCReport, error) {
+	report := &entities.GCReport{
+		DryRun:    dryRun,
+		Orphans:   []entities.ManagedObject{},
+		Errors:    []string{},
+		StartedAt: time.Now(),
+	}
+
+	clusters, err := uc.ownedObjects(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+
+	for k8sClient, owned := range clusters {
+		objects, err := k8sClient.ListManagedObjects(ctx)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		report.Scanned += len(objects)
+
+		for _, object := range objects {
+			if owned[objectKey(object.Kind, object.Namespace, object.Name)] {
+				continue
+			}
+			if time.Since(object.CreatedAt) < gcMinAge {
+				continue
+			}
+
+			report.Orphans = append(report.Orphans, object)
+			if dryRun {
+				continue
+			}
+
+			if err := k8sClient.DeleteManagedObject(ctx, object); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s/%s/%s: %v", object.Kind, object.Namespace, object.Name, err))
+				continue
+			}
+			report.Deleted++
+		}
+	}
+
+	report.Duration = time.Since(report.StartedAt).String()
+
+	uc.mu.Lock()
+	uc.lastReport = report
+	uc.mu.Unlock()
+
+	return report, nil
+}
+
+func (uc *gcUseCase) GetLastReport() *entities.GCReport {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	return uc.lastReport
+}
+
+// ownedObjects returns, for each cluster, the keys of every object referenced by a
+// deployment or add-on record on it. Clusters that cannot be reached are left out so
+// nothing in them is collected, and so are those behind a node's agent.
+func (uc *gcUseCase) ownedObjects(ctx context.Context, report *entities.GCReport) (map[*k8s.Client]map[string]bool, error) {
+	agentManaged, err := agentNodes(ctx, uc.nodeRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := map[*k8s.Client]map[string]bool{uc.clusters.Default(): {}}
+	nodes := make(map[primitive.ObjectID]map[string]bool, len(agentManaged))
+	for nodeID := range agentManaged {
+		nodes[nodeID] = nil
+	}
+	ownedBy := func(nodeID primitive.ObjectID) map[string]bool {
+		if owned, ok := nodes[nodeID]; ok {
+			return owned
+		}
+		k8sClient, err := uc.clusters.Client(ctx, nodeID)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			nodes[nodeID] = nil
+			return nil
+		}
+		if clusters[k8sClient] == nil {
+			clusters[k8sClient] = make(map[string]bool)
+		}
+		nodes[nodeID] = clusters[k8sClient]
+		return nodes[nodeID]
+	}
+
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments {
+		owned := ownedBy(deployment.NodeID)
+		if owned == nil {
+			continue
+		}
+		info := deployment.KubernetesInfo
+		workloadKind := string(info.WorkloadKind)
+		if workloadKind == "" {
+			workloadKind = string(entities.WorkloadKindDeployment)
+		}
+
+		owned[objectKey(workloadKind, deployment.Namespace, info.DeploymentName)] = true
+		owned[objectKey("Service", deployment.Namespace, info.ServiceName)] = true
+		owned[objectKey("Service", deployment.Namespace, info.HeadlessServiceName)] = true
+		owned[objectKey("Ingress", deployment.Namespace, info.IngressName)] = true
+		owned[objectKey("ConfigMap", deployment.Namespace, info.ConfigMapName)] = true
+		owned[objectKey("HorizontalPodAutoscaler", deployment.Namespace, info.HPAName)] = true
+		owned[objectKey("Secret", deployment.Namespace, info.SecretName)] = true
+
+		for _, secret := range deployment.Configuration.ImagePullSecrets {
+			owned[objectKey("Secret", deployment.Namespace, secret)] = true
+		}
+		for _, object := range deployment.AppliedObjects {
+			owned[objectKey(object.Kind, object.Namespace, object.Name)] = true
+		}
+	}
+
+	addons, err := uc.addonRepo.GetAll(ctx, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	for _, addon := range addons {
+		owned := ownedBy(addon.NodeID)
+		if owned == nil {
+			continue
+		}
+		info := addon.KubernetesInfo
+		owned[objectKey("StatefulSet", addon.Namespace, info.StatefulSetName)] = true
+		owned[objectKey("Service", addon.Namespace, info.ServiceName)] = true
+		owned[objectKey("Service", addon.Namespace, info.HeadlessServiceName)] = true
+		owned[objectKey("Secret", addon.Namespace, info.SecretName)] = true
+	}
+
+	return clusters, nil
+}
+
+func objectKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
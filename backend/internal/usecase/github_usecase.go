@@ -16,6 +16,8 @@ type GitHubUseCase interface {
 	GetBranches(ctx context.Context, userID primitive.ObjectID, owner, repo string) ([]*github.Branch, error)
 	SearchRepositories(ctx context.Context, userID primitive.ObjectID, query string, page, perPage int) ([]*github.Repository, error)
 	GetUser(ctx context.Context, userID primitive.ObjectID) (*github.User, error)
+	ListContents(ctx context.Context, userID primitive.ObjectID, owner, repo, dir, branch string) ([]*github.ContentEntry, error)
+	FindDockerfiles(ctx context.Context, userID primitive.ObjectID, owner, repo, branch string) ([]*github.Dockerfile, bool, error)
 }
 
 type githubUseCase struct {
@@ -83,6 +85,32 @@ func (uc *githubUseCase) GetUser(ctx context.Context, userID primitive.ObjectID)
 	return uc.githubClient.GetAuthenticatedUser(ctx, token)
 }
 
+// ListContents lists a repository directory so a Dockerfile or build context can be picked
+func (uc *githubUseCase) ListContents(ctx context.Context, userID primitive.ObjectID, owner, repo, dir, branch string) ([]*github.ContentEntry, error) {
+	token, err := uc.getToken(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.githubClient.ListDirectory(ctx, token, owner, repo, dir, branch)
+}
+
+// FindDockerfiles lists every Dockerfile in a branch, defaulting to the repository's default branch
+func (uc *githubUseCase) FindDockerfiles(ctx context.Context, userID primitive.ObjectID, owner, repo, branch string) ([]*github.Dockerfile, bool, error) {
+	token, err := uc.getToken(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if branch == "" {
+		if branch, err = uc.githubClient.GetDefaultBranch(ctx, token, owner, repo); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return uc.githubClient.FindDockerfiles(ctx, token, owner, repo, branch)
+}
+
 func (uc *githubUseCase) getToken(ctx context.Context, userID primitive.ObjectID) (string, error) {
 	tokenEntity, err := uc.githubTokenRepo.GetByUserID(ctx, userID)
 	if err != nil {
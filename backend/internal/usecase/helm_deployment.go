@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/github"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkHelmChart verifies that the configured chart directory holds a Chart.yaml
+func (uc *deploymentUseCase) checkHelmChart(ctx context.Context, githubToken string, repo entities.GitHubRepository, source entities.SourceConfig) error {
+	chartFile := path.Join(source.ChartPath, "Chart.yaml")
+
+	_, err := uc.githubClient.GetFileContent(ctx, githubToken, repo.Owner, repo.Name, chartFile, repo.Branch)
+	if errors.Is(err, github.ErrFileNotFound) {
+		return fmt.Errorf("repository must contain a Helm chart at %s", chartFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", chartFile, err)
+	}
+	return nil
+}
+
+// rolloutHelmRelease downloads the chart at the deployment's branch and installs or
+// upgrades its release, recording the result on the deployment
+func (uc *deploymentUseCase) rolloutHelmRelease(ctx context.Context, deployment *entities.Deployment, githubToken string) {
+	uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusBuilding)
+
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+
+	// 1. Fetch the chart
+	chartDir, err := uc.downloadSource(ctx, deployment, githubToken, deployment.Source.ChartPath)
+	if err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+	defer os.RemoveAll(chartDir)
+
+	// 2. Install or upgrade the release
+	uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusDeploying)
+
+	release, err := k8sClient.HelmInstall(ctx, deployment, chartDir)
+	if err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+
+	// 3. Record the new revision
+	update := map[string]interface{}{
+		"helm_release": release,
+		"deployed_at":  time.Now(),
+		"status":       helmDeploymentStatus(release.Status),
+	}
+	uc.deploymentRepo.Update(ctx, deployment.ID, update)
+}
+
+// downloadSource fetches a directory of the deployment's repository at its branch into a
+// temporary directory, which the caller removes
+func (uc *deploymentUseCase) downloadSource(ctx context.Context, deployment *entities.Deployment, githubToken, dir string) (string, error) {
+	dest, err := os.MkdirTemp("", "espaze-source-*")
+	if err != nil {
+		return "", err
+	}
+
+	repo := deployment.GitHubRepo
+	if err := uc.githubClient.DownloadDirectory(ctx, githubToken, repo.Owner, repo.Name, repo.Branch, dir, dest); err != nil {
+		os.RemoveAll(dest)
+		return "", err
+	}
+	return dest, nil
+}
+
+// upgradeHelmRelease rolls the stored configuration out to a Helm release in the
+// background, using the GitHub token saved for the deployment's owner
+func (uc *deploymentUseCase) upgradeHelmRelease(ctx context.Context, deployment *entities.Deployment) error {
+	token, err := uc.githubTokenRepo.GetByUserID(ctx, deployment.UserID)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return errors.New("a GitHub token is required to upgrade a Helm release")
+	}
+
+	if err := uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusUpdating); err != nil {
+		return err
+	}
+
+	go uc.rolloutHelmRelease(context.Background(), deployment, token.Token)
+	return nil
+}
+
+// uninstallHelmRelease uninstalls a release in the background and removes the record
+// once Helm reports its resources are gone
+func (uc *deploymentUseCase) uninstallHelmRelease(deployment *entities.Deployment, k8sClient *k8s.Client) {
+	go func() {
+		finalizeCtx := context.Background()
+
+		if err := k8sClient.HelmUninstall(finalizeCtx, deployment); err != nil {
+			uc.recordDeletionError(finalizeCtx, deployment.ID, err)
+			return
+		}
+
+		uc.deploymentRepo.Delete(finalizeCtx, deployment.ID)
+	}()
+}
+
+// RollbackDeployment rolls a Helm deployment back to an earlier revision
+func (uc *deploymentUseCase) RollbackDeployment(ctx context.Context, id primitive.ObjectID, revision int) error {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deployment.Source.Type != entities.SourceTypeHelm {
+		return errors.New("rollback is only supported for Helm deployments")
+	}
+	if deployment.Status == entities.DeploymentStatusDeleting {
+		return errors.New("deployment is being deleted")
+	}
+	if revision < 0 {
+		return errors.New("revision must not be negative")
+	}
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.deploymentRepo.UpdateStatus(ctx, id, entities.DeploymentStatusUpdating); err != nil {
+		return err
+	}
+
+	// Helm waits for the rolled back resources, so finish in the background
+	go func() {
+		rollbackCtx := context.Background()
+		if err := k8sClient.HelmRollback(rollbackCtx, deployment, revision); err != nil {
+			uc.deploymentRepo.UpdateStatus(rollbackCtx, id, entities.DeploymentStatusFailed)
+			return
+		}
+
+		status, err := k8sClient.HelmStatus(rollbackCtx, deployment.Namespace, k8s.HelmReleaseName(deployment))
+		if err != nil {
+			uc.deploymentRepo.UpdateStatus(rollbackCtx, id, entities.DeploymentStatusFailed)
+			return
+		}
+
+		update := map[string]interface{}{
+			"helm_release": status,
+			"deployed_at":  time.Now(),
+			"status":       helmDeploymentStatus(status.Status),
+		}
+		uc.deploymentRepo.Update(rollbackCtx, id, update)
+	}()
+
+	return nil
+}
+
+// GetReleaseStatus returns the live state and history of a Helm deployment's release
+// and refreshes the stored status from it
+func (uc *deploymentUseCase) GetReleaseStatus(ctx context.Context, id primitive.ObjectID) (*entities.HelmReleaseStatus, error) {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Source.Type != entities.SourceTypeHelm {
+		return nil, errors.New("deployment is not a Helm deployment")
+	}
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	release := k8s.HelmReleaseName(deployment)
+	status, err := k8sClient.HelmStatus(ctx, deployment.Namespace, release)
+	if err != nil {
+		return nil, err
+	}
+	history, err := k8sClient.HelmHistory(ctx, deployment.Namespace, release)
+	if err != nil {
+		return nil, err
+	}
+
+	update := map[string]interface{}{
+		"helm_release": status,
+		"status":       helmDeploymentStatus(status.Status),
+	}
+	if err := uc.deploymentRepo.Update(ctx, id, update); err != nil {
+		return nil, err
+	}
+
+	return &entities.HelmReleaseStatus{
+		Release: *status,
+		History: history,
+	}, nil
+}
+
+// helmDeploymentStatus maps a Helm release status onto the deployment lifecycle
+func helmDeploymentStatus(status string) entities.DeploymentStatus {
+	switch status {
+	case "deployed":
+		return entities.DeploymentStatusRunning
+	case "pending-install":
+		return entities.DeploymentStatusDeploying
+	case "pending-upgrade", "pending-rollback":
+		return entities.DeploymentStatusUpdating
+	case "uninstalling":
+		return entities.DeploymentStatusDeleting
+	case "uninstalled", "superseded":
+		return entities.DeploymentStatusStopped
+	default:
+		return entities.DeploymentStatusFailed
+	}
+}
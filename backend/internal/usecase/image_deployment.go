@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/registry"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// createImageDeployment stores and rolls out a deployment of a prebuilt image.
+// Nothing is read from GitHub.
+func (uc *deploymentUseCase) createImageDeployment(
+	ctx context.Context,
+	userID, nodeID primitive.ObjectID,
+	req *entities.DeploymentRequest,
+) (*entities.Deployment, error) {
+	deployment := &entities.Deployment{
+		NodeID:           nodeID,
+		UserID:           userID,
+		Name:             req.Name,
+		ContextPath:      req.ContextPath,
+		Namespace:        req.Namespace,
+		Status:           entities.DeploymentStatusPending,
+		Source:           req.Source,
+		Configuration:    req.Configuration,
+		ConfigOverrides:  req.Configuration,
+		ExplicitSettings: req.Explicit,
+		FleetID:          fleetOf(req),
+	}
+	if deployment.Namespace == "" {
+		deployment.Namespace = "espaze-node-deployer-apps"
+	}
+
+	if err := uc.resolveImage(ctx, deployment); err != nil {
+		return nil, err
+	}
+	applyConfigDefaults(&deployment.Configuration, deployment.GitHubRepo, deployment.ExplicitSettings)
+	deployment.Metrics.DesiredPods = int(deployment.Configuration.Replicas)
+	if err := uc.place(ctx, deployment, req.Placement); err != nil {
+		return nil, err
+	}
+
+	if err := uc.deploymentRepo.Create(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	go uc.rolloutImage(context.Background(), deployment)
+
+	return deployment, nil
+}
+
+// redeployImage re-resolves the image source and rolls the result out in the background
+func (uc *deploymentUseCase) redeployImage(ctx context.Context, deployment *entities.Deployment) error {
+	if err := uc.resolveImage(ctx, deployment); err != nil {
+		return err
+	}
+
+	update := map[string]interface{}{
+		"source":        deployment.Source,
+		"configuration": deployment.Configuration,
+		"image_digest":  deployment.ImageDigest,
+		"status":        entities.DeploymentStatusUpdating,
+	}
+	if err := uc.deploymentRepo.Update(ctx, deployment.ID, update); err != nil {
+		return err
+	}
+
+	go uc.rolloutImage(context.Background(), deployment)
+
+	deployment.Status = entities.DeploymentStatusUpdating
+	return nil
+}
+
+// resolveImage points the deployment's container at its image source. A tag is resolved
+// to the digest it currently names, so a moved tag changes the pod spec and rolls the
+// pods. When the registry cannot be asked, the tag is deployed as is and pulled on
+// every start instead.
+func (uc *deploymentUseCase) resolveImage(ctx context.Context, deployment *entities.Deployment) error {
+	ref, err := registry.ParseReference(deployment.Source.Image)
+	if err != nil {
+		return err
+	}
+	build := &deployment.Configuration.BuildConfig
+	build.ImageName = ref.Name()
+
+	creds, err := uc.registryCredentials(ctx, deployment.UserID, ref.Registry)
+	if err != nil {
+		return err
+	}
+
+	digest, err := uc.registryClient.ResolveDigest(ctx, ref, creds)
+	if errors.Is(err, registry.ErrImageNotFound) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Image source: deploying %s by tag: %v", ref, err)
+		build.ImageTag = ref.Tag
+		deployment.Configuration.ImagePullPolicy = "Always"
+		deployment.ImageDigest = ""
+		return nil
+	}
+
+	build.ImageTag = digest
+	deployment.ImageDigest = digest
+	if deployment.ConfigOverrides.ImagePullPolicy == "" {
+		deployment.Configuration.ImagePullPolicy = "IfNotPresent"
+	}
+	return nil
+}
+
+// rolloutImage applies the deployment's objects and records the result
+func (uc *deploymentUseCase) rolloutImage(ctx context.Context, deployment *entities.Deployment) {
+	uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusDeploying)
+
+	if uc.agentManaged(ctx, deployment.NodeID) {
+		uc.rolloutViaAgent(ctx, deployment)
+		return
+	}
+
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+	// Until a rollout succeeded the objects' names are only assumed; make sure they are
+	// free or already the deployment's before applying over them
+	if deployment.KubernetesInfo.DeploymentName == "" {
+		if err := k8sClient.CheckApplicationOwner(ctx, deployment); err != nil {
+			uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+			return
+		}
+	}
+	if err := uc.syncPullSecrets(ctx, deployment); err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+	if err := k8sClient.ApplyApplication(ctx, deployment); err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+
+	// An unresolved tag leaves the pod spec unchanged, so restart to pull it again
+	if deployment.ImageDigest == "" {
+		if err := k8sClient.RestartDeployment(ctx, deployment.Namespace, deployment.KubernetesInfo.DeploymentName); err != nil {
+			uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+			return
+		}
+	}
+
+	update := map[string]interface{}{
+		"kubernetes_info":                  deployment.KubernetesInfo,
+		"configuration.image_pull_secrets": deployment.Configuration.ImagePullSecrets,
+		"deployed_at":                      time.Now(),
+		"status":                           entities.DeploymentStatusRunning,
+	}
+	uc.deploymentRepo.Update(ctx, deployment.ID, update)
+}
+
+// StartImageWatcher runs CheckImageUpdates on every tick until ctx is cancelled
+func (uc *deploymentUseCase) StartImageWatcher(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := uc.CheckImageUpdates(ctx); err != nil {
+					log.Printf("Image watcher: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// CheckImageUpdates redeploys every auto-updating image deployment whose tag now
+// resolves to a different digest
+func (uc *deploymentUseCase) CheckImageUpdates(ctx context.Context) error {
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{
+		"source.type":        entities.SourceTypeImage,
+		"source.auto_update": true,
+		"status":             entities.DeploymentStatusRunning,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, deployment := range deployments {
+		ref, err := registry.ParseReference(deployment.Source.Image)
+		if err != nil || ref.Pinned() {
+			continue
+		}
+
+		creds, err := uc.registryCredentials(ctx, deployment.UserID, ref.Registry)
+		if err != nil {
+			log.Printf("Image watcher: deployment %s: %v", deployment.ID.Hex(), err)
+			continue
+		}
+
+		digest, err := uc.registryClient.ResolveDigest(ctx, ref, creds)
+		if err != nil {
+			log.Printf("Image watcher: deployment %s: %v", deployment.ID.Hex(), err)
+			continue
+		}
+		if digest == deployment.ImageDigest {
+			continue
+		}
+
+		log.Printf("Image watcher: %s moved to %s, redeploying %s", ref, digest, deployment.ID.Hex())
+		if err := uc.redeployImage(ctx, deployment); err != nil {
+			log.Printf("Image watcher: deployment %s: %v", deployment.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ImportUseCase interface {
+	ListCandidates(ctx context.Context, nodeID primitive.ObjectID, namespace string) ([]entities.ImportCandidate, error)
+	ImportDeployment(ctx context.Context, userID, nodeID primitive.ObjectID, req *entities.ImportRequest) (*entities.Deployment, error)
+}
+
+type importUseCase struct {
+	deploymentRepo repository.DeploymentRepository
+	clusters       *k8s.ClusterManager
+}
+
+func NewImportUseCase(deploymentRepo repository.DeploymentRepository, clusters *k8s.ClusterManager) ImportUseCase {
+	return &importUseCase{
+		deploymentRepo: deploymentRepo,
+		clusters:       clusters,
+	}
+}
+
+func (uc *importUseCase) ListCandidates(ctx context.Context, nodeID primitive.ObjectID, namespace string) ([]entities.ImportCandidate, error) {
+	if namespace == "" {
+		return nil, errors.New("namespace is required")
+	}
+	if k8s.IsSystemNamespace(namespace) {
+		return nil, fmt.Errorf("namespace %s belongs to the cluster and cannot be imported from", namespace)
+	}
+	k8sClient, err := uc.clusters.Client(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return k8sClient.ListUnmanagedDeployments(ctx, namespace)
+}
+
+// ImportDeployment adopts an existing Kubernetes Deployment so it is managed like a native one
+func (uc *importUseCase) ImportDeployment(ctx context.Context, userID, nodeID primitive.ObjectID, req *entities.ImportRequest) (*entities.Deployment, error) {
+	if req.Namespace == "" || req.DeploymentName == "" {
+		return nil, errors.New("namespace and deploymentName are required")
+	}
+	if k8s.IsSystemNamespace(req.Namespace) {
+		return nil, fmt.Errorf("namespace %s belongs to the cluster and cannot be imported from", req.Namespace)
+	}
+
+	k8sClient, err := uc.clusters.Client(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse to adopt the same workload twice
+	existing, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{
+		"node_id":                         nodeID,
+		"namespace":                       req.Namespace,
+		"kubernetes_info.deployment_name": req.DeploymentName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("deployment %s is already managed", req.DeploymentName)
+	}
+
+	deployment, err := k8sClient.InspectDeployment(ctx, req.Namespace, req.DeploymentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect deployment: %w", err)
+	}
+
+	// Adoption keeps the live objects, and everything after it finds them by the
+	// deployment's name, so the name cannot change on import
+	if req.Name != "" && req.Name != deployment.Name {
+		return nil, fmt.Errorf("an imported deployment keeps its Kubernetes name %s and cannot be renamed", deployment.Name)
+	}
+	if req.ContextPath != "" {
+		deployment.ContextPath = req.ContextPath
+	}
+	deployment.NodeID = nodeID
+	deployment.UserID = userID
+	deployment.Status = entities.DeploymentStatusRunning
+	deployment.DeployedAt = time.Now()
+
+	if err := k8sClient.AdoptApplication(ctx, req.Namespace, deployment.KubernetesInfo); err != nil {
+		return nil, err
+	}
+
+	if err := uc.deploymentRepo.Create(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
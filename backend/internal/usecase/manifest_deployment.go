@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/github"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkManifestDir verifies that the configured directory holds manifests or a kustomization
+func (uc *deploymentUseCase) checkManifestDir(ctx context.Context, githubToken string, repo entities.GitHubRepository, source entities.SourceConfig) error {
+	dir := source.ManifestDir
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := uc.githubClient.ListDirectory(ctx, githubToken, repo.Owner, repo.Name, source.ManifestDir, repo.Branch)
+	if errors.Is(err, github.ErrFileNotFound) {
+		return fmt.Errorf("repository has no manifest directory %s", dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if k8s.IsKustomization(entry.Name) || k8s.IsManifestFile(entry.Name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s contains no YAML manifests or kustomization", dir)
+}
+
+// rolloutManifests downloads the manifest directory at the deployment's branch, applies
+// every object in it and records the applied set on the deployment
+func (uc *deploymentUseCase) rolloutManifests(ctx context.Context, deployment *entities.Deployment, githubToken string) {
+	uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusBuilding)
+
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+
+	// 1. Fetch and render the manifests
+	dir, err := uc.downloadSource(ctx, deployment, githubToken, deployment.Source.ManifestDir)
+	if err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	objects, err := k8sClient.LoadManifests(ctx, dir)
+	if err != nil {
+		uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusFailed)
+		return
+	}
+
+	// 2. Apply them, pruning objects dropped since the last rollout
+	uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, entities.DeploymentStatusDeploying)
+
+	applied, err := k8sClient.ApplyManifests(ctx, deployment, objects)
+
+	// 3. Record what now exists, even after a partial failure, so deletion can find it
+	update := map[string]interface{}{
+		"applied_objects": mergeAppliedObjects(applied, deployment.AppliedObjects),
+	}
+	if err != nil {
+		update["status"] = entities.DeploymentStatusFailed
+	} else {
+		update["applied_objects"] = applied
+		update["deployed_at"] = time.Now()
+		update["status"] = entities.DeploymentStatusRunning
+	}
+	uc.deploymentRepo.Update(ctx, deployment.ID, update)
+}
+
+// deleteManifests deletes every applied object and removes the record once the cluster
+// confirms they are gone
+func (uc *deploymentUseCase) deleteManifests(ctx context.Context, deployment *entities.Deployment, k8sClient *k8s.Client) error {
+	deleted, err := k8sClient.DeleteManifests(ctx, deployment, deployment.AppliedObjects)
+	if err != nil {
+		uc.recordDeletionError(ctx, deployment.ID, err)
+		return fmt.Errorf("failed to delete from Kubernetes: %w", err)
+	}
+
+	go func() {
+		finalizeCtx := context.Background()
+
+		if err := k8sClient.WaitForManifestsDeletion(finalizeCtx, deleted, deletionTimeout); err != nil {
+			uc.recordDeletionError(finalizeCtx, deployment.ID, err)
+			return
+		}
+
+		uc.deploymentRepo.Delete(finalizeCtx, deployment.ID)
+	}()
+
+	return nil
+}
+
+// restartManifests restarts every Deployment and StatefulSet applied from the manifests
+func (uc *deploymentUseCase) restartManifests(ctx context.Context, deployment *entities.Deployment, k8sClient *k8s.Client) error {
+	restarted := 0
+	for _, object := range deployment.AppliedObjects {
+		if object.Kind != "Deployment" && object.Kind != "StatefulSet" {
+			continue
+		}
+		if err := k8sClient.RestartDeployment(ctx, object.Namespace, object.Name); err != nil {
+			return err
+		}
+		restarted++
+	}
+	if restarted == 0 {
+		return errors.New("manifests contain no Deployment or StatefulSet to restart")
+	}
+	return nil
+}
+
+// GetManifestStatus reports the live state of every object applied from a deployment's manifests
+func (uc *deploymentUseCase) GetManifestStatus(ctx context.Context, id primitive.ObjectID) (*entities.ManifestStatus, error) {
+	deployment, err := uc.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Source.Type != entities.SourceTypeManifests {
+		return nil, errors.New("deployment is not a manifests deployment")
+	}
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := k8sClient.ManifestsStatus(ctx, deployment.AppliedObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &entities.ManifestStatus{Ready: len(objects) > 0, Objects: objects}
+	for _, object := range objects {
+		if !object.Ready {
+			status.Ready = false
+		}
+	}
+	return status, nil
+}
+
+// mergeAppliedObjects returns applied followed by every previous object not in applied
+func mergeAppliedObjects(applied, previous []entities.AppliedObject) []entities.AppliedObject {
+	seen := make(map[entities.AppliedObject]bool, len(applied))
+	merged := make([]entities.AppliedObject, 0, len(applied)+len(previous))
+	for _, object := range applied {
+		seen[object] = true
+		merged = append(merged, object)
+	}
+	for _, object := range previous {
+		if !seen[object] {
+			merged = append(merged, object)
+		}
+	}
+	return merged
+}
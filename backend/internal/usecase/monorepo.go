@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/github"
+)
+
+// locateDockerfile checks the configured Dockerfile, or looks for one in the build
+// context, and fills in whichever of the two was left empty. Both are repository paths.
+// Without a Dockerfile one is generated for the detected stack.
+func (uc *deploymentUseCase) locateDockerfile(ctx context.Context, githubToken string, repo entities.GitHubRepository, config *entities.DeploymentConfig) error {
+	build := &config.BuildConfig
+	buildContext, err := cleanRepoPath(build.BuildContext)
+	if err != nil {
+		return fmt.Errorf("invalid build context: %w", err)
+	}
+
+	// A Dockerfile given by hand is used as it is
+	if build.DockerfileContent != "" && build.Stack == "" {
+		build.Dockerfile = ""
+		build.BuildContext = buildContext
+		return nil
+	}
+
+	if build.Dockerfile != "" {
+		dockerfile, err := cleanRepoPath(build.Dockerfile)
+		if err != nil {
+			return fmt.Errorf("invalid Dockerfile path: %w", err)
+		}
+
+		_, err = uc.githubClient.GetFileContent(ctx, githubToken, repo.Owner, repo.Name, dockerfile, repo.Branch)
+		if errors.Is(err, github.ErrFileNotFound) {
+			return fmt.Errorf("Dockerfile %s not found on branch %s", dockerfile, repo.Branch)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", dockerfile, err)
+		}
+		build.Dockerfile = dockerfile
+	} else {
+		found, dockerfile, err := uc.githubClient.CheckDockerfile(ctx, githubToken, repo.Owner, repo.Name, repo.Branch, buildContext)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return uc.generateDockerfile(ctx, githubToken, repo, config, buildContext)
+		}
+		build.Dockerfile = dockerfile
+	}
+	build.DockerfileContent = ""
+	build.Stack = ""
+
+	// The context defaults to the Dockerfile's directory, or the one above .docker/
+	if build.BuildContext == "" {
+		buildContext = path.Dir(build.Dockerfile)
+		if path.Base(buildContext) == ".docker" {
+			buildContext = path.Dir(buildContext)
+		}
+	}
+	build.BuildContext = buildContext
+	return nil
+}
+
+// branchHead returns the commit a repository branch points to, or "" if it cannot be read
+func (uc *deploymentUseCase) branchHead(ctx context.Context, githubToken string, repo entities.GitHubRepository) string {
+	if githubToken == "" || repo.Owner == "" {
+		return ""
+	}
+	commit, err := uc.githubClient.GetCommit(ctx, githubToken, repo.Owner, repo.Name, repo.Branch)
+	if err != nil {
+		return ""
+	}
+	return commit.SHA
+}
+
+// HandlePush redeploys the deployments of a pushed branch whose source paths changed,
+// so deployments sharing a monorepo only rebuild for their own directory
+func (uc *deploymentUseCase) HandlePush(ctx context.Context, push *entities.PushEvent) ([]*entities.Deployment, error) {
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{
+		"github_repo.full_name": push.Repository,
+		"github_repo.branch":    push.Branch,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	redeployed := []*entities.Deployment{}
+	for _, deployment := range deployments {
+		if deployment.Source.Type == entities.SourceTypeImage || deployment.Status == entities.DeploymentStatusDeleting {
+			continue
+		}
+		if deployment.DeployedCommit == push.After {
+			continue
+		}
+
+		token, err := uc.githubTokenRepo.GetByUserID(ctx, deployment.UserID)
+		if err != nil || token == nil {
+			log.Printf("Push: deployment %s: no GitHub token for its owner", deployment.ID.Hex())
+			continue
+		}
+
+		// Compare against the last deployed commit so pushes that were skipped still count
+		changed := push.Files
+		if deployment.DeployedCommit != "" {
+			repo := deployment.GitHubRepo
+			files, err := uc.githubClient.GetChangedFiles(ctx, token.Token, repo.Owner, repo.Name, deployment.DeployedCommit, push.After)
+			if err == nil {
+				changed = files
+			}
+		}
+		if !touchesPaths(changed, watchPaths(deployment)) {
+			continue
+		}
+
+		updated, err := uc.RedeployDeployment(ctx, deployment.ID, token.Token)
+		if err != nil {
+			log.Printf("Push: deployment %s: %v", deployment.ID.Hex(), err)
+			continue
+		}
+		redeployed = append(redeployed, updated)
+	}
+
+	return redeployed, nil
+}
+
+// watchPaths returns the repository paths a deployment is built from. nil means the
+// whole repository.
+func watchPaths(deployment *entities.Deployment) []string {
+	paths := deployment.Source.WatchPaths
+	if len(paths) == 0 {
+		switch deployment.Source.Type {
+		case entities.SourceTypeHelm:
+			paths = []string{deployment.Source.ChartPath}
+		case entities.SourceTypeManifests:
+			paths = []string{deployment.Source.ManifestDir}
+		default:
+			build := deployment.Configuration.BuildConfig
+			paths = []string{build.BuildContext}
+			if build.Dockerfile != "" {
+				paths = append(paths, build.Dockerfile)
+			}
+		}
+		manifestPath := deployment.ManifestPath
+		if manifestPath == "" {
+			manifestPath = entities.DefaultAppManifestPath
+		}
+		paths = append(paths, manifestPath)
+	}
+
+	cleaned := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p, err := cleanRepoPath(p)
+		if err != nil {
+			continue
+		}
+		if p == "." {
+			return nil
+		}
+		cleaned = append(cleaned, p)
+	}
+	return cleaned
+}
+
+// touchesPaths reports whether any changed file is one of paths or lies below one.
+// nil paths match every change.
+func touchesPaths(files, paths []string) bool {
+	if paths == nil {
+		return len(files) > 0
+	}
+	for _, file := range files {
+		for _, p := range paths {
+			if file == p || strings.HasPrefix(file, p+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cleanRepoPath normalises a repository path; "" and "/" mean the root, returned as "."
+func cleanRepoPath(p string) (string, error) {
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("%s leaves the repository", p)
+		}
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if cleaned == "" {
+		return ".", nil
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidBootstrapToken = errors.New("bootstrap token is invalid, expired or used up")
+	ErrInvalidNodeCredential = errors.New("node credential is invalid or has been replaced")
+	ErrNodeRevoked           = errors.New("node has been revoked; delete it before enrolling it again")
+	ErrNodeAlreadyEnrolled   = errors.New("a node with this MAC address is already enrolled; present its current credential or have an admin delete it")
+)
+
+// bootstrapTokenPrefix makes bootstrap tokens recognizable, e.g. in secret scanners
+const bootstrapTokenPrefix = "espaze-bt-"
+
+// defaultBootstrapTokenTTL applies when a token is issued without a TTL
+const defaultBootstrapTokenTTL = 24 * time.Hour
+
+// EnrollNode exchanges a bootstrap token for a node credential. A node that is already
+// known by its MAC address is only enrolled again when the caller also presents the
+// node's current credential; it is then refreshed and gets a new credential, which
+// replaces the one issued before. A bootstrap token alone never takes over a node, since
+// MAC addresses are easy to spoof: without the credential an admin has to delete the
+// node first.
+func (uc *nodeUseCase) EnrollNode(ctx context.Context, bootstrapToken, currentCredential string, req *entities.NodeRegistrationRequest) (*entities.NodeEnrollment, error) {
+	if bootstrapToken == "" {
+		return nil, ErrInvalidBootstrapToken
+	}
+	if req.MacAddress == "" {
+		return nil, errors.New("MAC address is required")
+	}
+
+	node, err := uc.nodeRepo.GetByMacAddress(ctx, req.MacAddress)
+	if err != nil {
+		return nil, err
+	}
+	if node != nil {
+		if node.RevokedAt != nil {
+			return nil, ErrNodeRevoked
+		}
+		if currentCredential == "" {
+			return nil, ErrNodeAlreadyEnrolled
+		}
+		owner, err := uc.AuthenticateNode(ctx, currentCredential)
+		if err != nil || owner.ID != node.ID {
+			return nil, ErrNodeAlreadyEnrolled
+		}
+	}
+
+	token, err := uc.tokenRepo.Use(ctx, auth.HashSecret(bootstrapToken))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidBootstrapToken
+	}
+
+	if node != nil {
+		err = uc.refreshNode(ctx, node, req)
+	} else {
+		node, err = uc.createNode(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	generation, err := uc.nodeRepo.RotateCredential(ctx, node.ID)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := auth.GenerateNodeToken(node.ID.Hex(), generation, uc.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err = uc.GetNode(ctx, node.ID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Node %s (%s) enrolled with bootstrap token %s", node.NodeName, node.ID.Hex(), token.ID.Hex())
+
+	return &entities.NodeEnrollment{Node: node, Credential: credential}, nil
+}
+
+// AuthenticateNode returns the node a credential belongs to, as long as the credential
+// is current and the node has not been revoked
+func (uc *nodeUseCase) AuthenticateNode(ctx context.Context, credential string) (*entities.Node, error) {
+	claims, err := auth.ValidateNodeToken(credential, uc.jwtSecret)
+	if err != nil {
+		return nil, ErrInvalidNodeCredential
+	}
+	nodeID, err := primitive.ObjectIDFromHex(claims.NodeID)
+	if err != nil {
+		return nil, ErrInvalidNodeCredential
+	}
+
+	node, err := uc.GetNode(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node.RevokedAt != nil {
+		return nil, ErrNodeRevoked
+	}
+	if claims.Generation != node.CredentialGeneration {
+		return nil, ErrInvalidNodeCredential
+	}
+	return node, nil
+}
+
+// RevokeNode invalidates the node's credential; the node cannot enroll again until it
+// is deleted
+func (uc *nodeUseCase) RevokeNode(ctx context.Context, id primitive.ObjectID) error {
+	node, err := uc.GetNode(ctx, id)
+	if err != nil {
+		return err
+	}
+	if node.RevokedAt != nil {
+		return nil
+	}
+
+	if err := uc.nodeRepo.Revoke(ctx, id); err != nil {
+		return err
+	}
+	return uc.monitor.SetStatus(ctx, node, entities.NodeStatusRevoked, "credential revoked by admin")
+}
+
+func (uc *nodeUseCase) CreateBootstrapToken(ctx context.Context, userID primitive.ObjectID, req *entities.BootstrapTokenRequest) (*entities.BootstrapTokenResponse, error) {
+	ttl := defaultBootstrapTokenTTL
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			return nil, fmt.Errorf("invalid ttl: %w", err)
+		}
+	}
+	if ttl <= 0 {
+		return nil, errors.New("ttl must be positive")
+	}
+	if req.MaxUses < 0 {
+		return nil, errors.New("maxUses cannot be negative")
+	}
+
+	secret, err := auth.GenerateSecret(bootstrapTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &entities.BootstrapToken{
+		Description: req.Description,
+		TokenHash:   auth.HashSecret(secret),
+		MaxUses:     req.MaxUses,
+		CreatedBy:   userID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := uc.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return &entities.BootstrapTokenResponse{BootstrapToken: *token, Token: secret}, nil
+}
+
+func (uc *nodeUseCase) ListBootstrapTokens(ctx context.Context) ([]*entities.BootstrapToken, error) {
+	return uc.tokenRepo.GetAll(ctx)
+}
+
+func (uc *nodeUseCase) DeleteBootstrapToken(ctx context.Context, id primitive.ObjectID) error {
+	return uc.tokenRepo.Delete(ctx, id)
+}
@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NodeEventHandler is called with every node event; each call runs in its own goroutine
+type NodeEventHandler func(ctx context.Context, event entities.NodeEvent)
+
+type NodeMonitorUseCase interface {
+	Start(ctx context.Context, interval time.Duration)
+	CheckNodes(ctx context.Context) error
+	SetStatus(ctx context.Context, node *entities.Node, status entities.NodeStatus, reason string) error
+	Subscribe(handler NodeEventHandler)
+	GetEvents(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeEvent, error)
+}
+
+type nodeMonitorUseCase struct {
+	nodeRepo       repository.NodeRepository
+	deploymentRepo repository.DeploymentRepository
+	eventRepo      repository.NodeEventRepository
+	degradedAfter  time.Duration
+	offlineAfter   time.Duration
+
+	mu       sync.RWMutex
+	handlers []NodeEventHandler
+}
+
+// NewNodeMonitorUseCase watches heartbeats: a node silent for degradedAfter is degraded
+// and one silent for offlineAfter is offline
+func NewNodeMonitorUseCase(
+	nodeRepo repository.NodeRepository,
+	deploymentRepo repository.DeploymentRepository,
+	eventRepo repository.NodeEventRepository,
+	degradedAfter, offlineAfter time.Duration,
+) NodeMonitorUseCase {
+	return &nodeMonitorUseCase{
+		nodeRepo:       nodeRepo,
+		deploymentRepo: deploymentRepo,
+		eventRepo:      eventRepo,
+		degradedAfter:  degradedAfter,
+		offlineAfter:   offlineAfter,
+	}
+}
+
+// Start runs CheckNodes on every tick until ctx is cancelled
+func (uc *nodeMonitorUseCase) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := uc.CheckNodes(ctx); err != nil {
+					log.Printf("Node monitor: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// CheckNodes moves online and degraded nodes to the status their last heartbeat calls
+// for. Nodes in maintenance or error are left to the admin.
+func (uc *nodeMonitorUseCase) CheckNodes(ctx context.Context) error {
+	nodes, err := uc.nodeRepo.GetAll(ctx, map[string]interface{}{
+		"status": map[string]interface{}{
+			"$in": []entities.NodeStatus{entities.NodeStatusOnline, entities.NodeStatusDegraded, entities.NodeStatusOffline},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		silent := time.Since(node.LastSeenAt)
+
+		status := entities.NodeStatusOnline
+		switch {
+		case silent >= uc.offlineAfter:
+			status = entities.NodeStatusOffline
+		case silent >= uc.degradedAfter:
+			status = entities.NodeStatusDegraded
+		}
+		// An offline node stays offline until it is heard from again
+		if node.Status == entities.NodeStatusOffline && status != entities.NodeStatusOnline {
+			continue
+		}
+
+		reason := "heartbeats resumed"
+		if status != entities.NodeStatusOnline {
+			reason = fmt.Sprintf("no heartbeat for %s", silent.Round(time.Second))
+		}
+		if err := uc.SetStatus(ctx, node, status, reason); err != nil {
+			log.Printf("Node monitor: node %s: %v", node.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+// SetStatus changes a node's status, updates its deployments and emits an event. Going
+// offline, into error or being revoked marks running deployments unreachable, and coming
+// back online marks them running again.
+func (uc *nodeMonitorUseCase) SetStatus(ctx context.Context, node *entities.Node, status entities.NodeStatus, reason string) error {
+	if node.Status == status {
+		return nil
+	}
+
+	if err := uc.nodeRepo.Update(ctx, node.ID, &entities.NodeUpdateRequest{Status: status}); err != nil {
+		return err
+	}
+
+	switch status {
+	case entities.NodeStatusOffline, entities.NodeStatusError, entities.NodeStatusRevoked:
+		if err := uc.setDeploymentStatus(ctx, node.ID, entities.DeploymentStatusRunning, entities.DeploymentStatusUnreachable); err != nil {
+			return err
+		}
+	case entities.NodeStatusOnline:
+		if err := uc.setDeploymentStatus(ctx, node.ID, entities.DeploymentStatusUnreachable, entities.DeploymentStatusRunning); err != nil {
+			return err
+		}
+	}
+
+	event := &entities.NodeEvent{
+		NodeID:     node.ID,
+		NodeName:   node.NodeName,
+		Type:       entities.NodeEventStatusChanged,
+		From:       node.Status,
+		To:         status,
+		Reason:     reason,
+		LastSeenAt: node.LastSeenAt,
+	}
+	node.Status = status
+	log.Printf("Node %s (%s) is now %s: %s", node.NodeName, node.ID.Hex(), status, reason)
+	return uc.publish(ctx, event)
+}
+
+// Subscribe registers a handler for every future node event
+func (uc *nodeMonitorUseCase) Subscribe(handler NodeEventHandler) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.handlers = append(uc.handlers, handler)
+}
+
+func (uc *nodeMonitorUseCase) GetEvents(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeEvent, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	return uc.eventRepo.GetByNodeID(ctx, nodeID, limit)
+}
+
+// publish stores an event and hands it to every subscriber
+func (uc *nodeMonitorUseCase) publish(ctx context.Context, event *entities.NodeEvent) error {
+	if err := uc.eventRepo.Create(ctx, event); err != nil {
+		return err
+	}
+
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	for _, handler := range uc.handlers {
+		go handler(context.Background(), *event)
+	}
+	return nil
+}
+
+// setDeploymentStatus moves a node's deployments from one status to another
+func (uc *nodeMonitorUseCase) setDeploymentStatus(ctx context.Context, nodeID primitive.ObjectID, from, to entities.DeploymentStatus) error {
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{
+		"node_id": nodeID,
+		"status":  from,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, deployment := range deployments {
+		if err := uc.deploymentRepo.UpdateStatus(ctx, deployment.ID, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrOperationNotFound = errors.New("operation not found")
+	ErrOperationTimeout  = errors.New("the node's agent did not report back in time; the operation stays queued")
+)
+
+const (
+	// maxOperationAttempts bounds how often an operation is handed out without a result
+	maxOperationAttempts = 3
+	// operationRecheck makes waiters look at the database now and then, for work queued or
+	// finished on another server instance
+	operationRecheck = 5 * time.Second
+	// maxPollWait caps how long an agent's long poll is held open
+	maxPollWait = time.Minute
+)
+
+// NodeOperationHandler is called once an operation finished; each call runs in its own
+// goroutine
+type NodeOperationHandler func(ctx context.Context, operation entities.NodeOperation)
+
+type NodeOperationUseCase interface {
+	Enqueue(ctx context.Context, operation *entities.NodeOperation) error
+	Poll(ctx context.Context, nodeID primitive.ObjectID, wait time.Duration) (*entities.NodeOperation, error)
+	Complete(ctx context.Context, nodeID, operationID primitive.ObjectID, result *entities.NodeOperationResult) error
+	Wait(ctx context.Context, operationID primitive.ObjectID, timeout time.Duration) (*entities.NodeOperation, error)
+	Subscribe(handler NodeOperationHandler)
+	GetOperations(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeOperation, error)
+}
+
+type nodeOperationUseCase struct {
+	operationRepo repository.NodeOperationRepository
+
+	queued   *signals // keyed by node
+	finished *signals // keyed by operation
+
+	mu       sync.RWMutex
+	handlers []NodeOperationHandler
+}
+
+func NewNodeOperationUseCase(operationRepo repository.NodeOperationRepository) NodeOperationUseCase {
+	return &nodeOperationUseCase{
+		operationRepo: operationRepo,
+		queued:        newSignals(),
+		finished:      newSignals(),
+	}
+}
+
+// Enqueue stores an operation and wakes the node's agent if it is long-polling
+func (uc *nodeOperationUseCase) Enqueue(ctx context.Context, operation *entities.NodeOperation) error {
+	if err := uc.operationRepo.Create(ctx, operation); err != nil {
+		return err
+	}
+	uc.queued.notify(operation.NodeID)
+	return nil
+}
+
+// Poll claims the node's next operation, waiting up to wait for one to be queued. It
+// returns nil if none arrived in time.
+//
+// An agent runs one operation at a time and reports it before polling again, so an
+// operation still running when its node polls was lost on the way: the response of an
+// earlier poll never reached the agent, e.g. because it disconnected while the server
+// waited, or its report failed. Such operations are handed out again right away.
+func (uc *nodeOperationUseCase) Poll(ctx context.Context, nodeID primitive.ObjectID, wait time.Duration) (*entities.NodeOperation, error) {
+	if wait > maxPollWait {
+		wait = maxPollWait
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+
+	for {
+		// Subscribe before claiming so an operation queued in between is not missed
+		woken := uc.queued.wait(nodeID)
+
+		staleBefore := time.Now()
+		if err := uc.operationRepo.FailStale(ctx, nodeID, staleBefore, maxOperationAttempts); err != nil {
+			return nil, err
+		}
+		operation, err := uc.operationRepo.Claim(ctx, nodeID, staleBefore)
+		if err != nil || operation != nil {
+			return operation, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-deadline.C:
+			return nil, nil
+		case <-woken:
+		case <-time.After(operationRecheck):
+		}
+	}
+}
+
+// Complete records the result an agent reported for one of its operations and tells
+// the subscribers
+func (uc *nodeOperationUseCase) Complete(ctx context.Context, nodeID, operationID primitive.ObjectID, result *entities.NodeOperationResult) error {
+	operation, err := uc.operationRepo.GetByID(ctx, operationID)
+	if err != nil {
+		return err
+	}
+	if operation == nil || operation.NodeID != nodeID {
+		return ErrOperationNotFound
+	}
+
+	completed, err := uc.operationRepo.Complete(ctx, operationID, result)
+	if err != nil {
+		return err
+	}
+	// A late report of an operation that already finished changes nothing
+	if !completed {
+		return nil
+	}
+
+	operation, err = uc.operationRepo.GetByID(ctx, operationID)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		log.Printf("Node %s: %s operation %s failed: %s", nodeID.Hex(), operation.Type, operationID.Hex(), result.Error)
+	}
+
+	uc.finished.notify(operationID)
+
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	for _, handler := range uc.handlers {
+		go handler(context.Background(), *operation)
+	}
+	return nil
+}
+
+// Wait blocks until an operation finished and returns it, or fails with
+// ErrOperationTimeout
+func (uc *nodeOperationUseCase) Wait(ctx context.Context, operationID primitive.ObjectID, timeout time.Duration) (*entities.NodeOperation, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	// Drop the signal this waiter created; others waiting on it just look again
+	defer uc.finished.notify(operationID)
+
+	for {
+		woken := uc.finished.wait(operationID)
+
+		operation, err := uc.operationRepo.GetByID(ctx, operationID)
+		if err != nil {
+			return nil, err
+		}
+		if operation == nil {
+			return nil, ErrOperationNotFound
+		}
+		if operation.Status == entities.NodeOperationSucceeded || operation.Status == entities.NodeOperationFailed {
+			return operation, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return nil, ErrOperationTimeout
+		case <-woken:
+		case <-time.After(operationRecheck):
+		}
+	}
+}
+
+// Subscribe registers a handler for every operation that finishes from now on
+func (uc *nodeOperationUseCase) Subscribe(handler NodeOperationHandler) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.handlers = append(uc.handlers, handler)
+}
+
+func (uc *nodeOperationUseCase) GetOperations(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeOperation, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	return uc.operationRepo.GetByNodeID(ctx, nodeID, limit)
+}
+
+// signals wakes everything waiting on a key at once
+type signals struct {
+	mu    sync.Mutex
+	chans map[primitive.ObjectID]chan struct{}
+}
+
+func newSignals() *signals {
+	return &signals{chans: make(map[primitive.ObjectID]chan struct{})}
+}
+
+// wait returns a channel that is closed on the next notify for key
+func (s *signals) wait(key primitive.ObjectID) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.chans[key]
+	if !ok {
+		ch = make(chan struct{})
+		s.chans[key] = ch
+	}
+	return ch
+}
+
+func (s *signals) notify(key primitive.ObjectID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ch, ok := s.chans[key]; ok {
+		close(ch)
+		delete(s.chans, key)
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
+	"log"
+	"math"
 	"os"
 	"runtime"
 	"time"
@@ -12,55 +13,132 @@ import (
 	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
 	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
 	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/encryption"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/hostinfo"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var (
+	// ErrNodeNotFound is returned for nodes that do not exist, e.g. after being deleted
+	ErrNodeNotFound = errors.New("node not found")
+	// ErrNodeInUse is returned when deleting a node that deployments or add-ons still run on
+	ErrNodeInUse = errors.New("node still has deployments or add-ons; delete or move them first")
+)
+
 type NodeUseCase interface {
-	RegisterNode(ctx context.Context, req *entities.NodeRegistrationRequest) (*entities.Node, error)
+	EnrollNode(ctx context.Context, bootstrapToken, currentCredential string, req *entities.NodeRegistrationRequest) (*entities.NodeEnrollment, error)
+	AuthenticateNode(ctx context.Context, credential string) (*entities.Node, error)
+	RegisterNode(ctx context.Context, nodeID primitive.ObjectID, req *entities.NodeRegistrationRequest) (*entities.Node, error)
+	RevokeNode(ctx context.Context, id primitive.ObjectID) error
+	CreateBootstrapToken(ctx context.Context, userID primitive.ObjectID, req *entities.BootstrapTokenRequest) (*entities.BootstrapTokenResponse, error)
+	ListBootstrapTokens(ctx context.Context) ([]*entities.BootstrapToken, error)
+	DeleteBootstrapToken(ctx context.Context, id primitive.ObjectID) error
 	GetNode(ctx context.Context, id primitive.ObjectID) (*entities.Node, error)
 	GetNodeByMac(ctx context.Context, macAddress string) (*entities.Node, error)
 	GetAllNodes(ctx context.Context, filters map[string]interface{}) ([]*entities.Node, error)
 	UpdateNode(ctx context.Context, id primitive.ObjectID, req *entities.NodeUpdateRequest) error
 	DeleteNode(ctx context.Context, id primitive.ObjectID) error
-	UpdateNodeResources(ctx context.Context, id primitive.ObjectID, k8sClient *k8s.Client) error
+	UpdateNodeResources(ctx context.Context, id primitive.ObjectID) error
+	StartResourceRefresh(ctx context.Context, interval time.Duration)
+	RefreshResources(ctx context.Context) error
 	GetNodeStats(ctx context.Context) (map[string]interface{}, error)
-	GetNodesByLocation(ctx context.Context, latitude, longitude, radiusKm float64) ([]*entities.Node, error)
+	GetNodesByLocation(ctx context.Context, latitude, longitude, radiusKm float64, limit int64, filters map[string]interface{}) ([]*entities.NodeDistance, error)
+	GetNodesInRegion(ctx context.Context, query *entities.NodeRegionQuery) ([]*entities.Node, error)
 	GetCurrentNodeInfo() (*entities.NodeRegistrationRequest, error)
 	Heartbeat(ctx context.Context, nodeID primitive.ObjectID) error
+	ReportResources(ctx context.Context, nodeID primitive.ObjectID, resources *entities.NodeResources) error
+	GetNodeEvents(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeEvent, error)
+	SetClusterCredentials(ctx context.Context, nodeID primitive.ObjectID, req *entities.NodeCredentialRequest) (*entities.NodeCredential, error)
+	GetClusterCredentials(ctx context.Context, nodeID primitive.ObjectID) (*entities.NodeCredential, error)
+	DeleteClusterCredentials(ctx context.Context, nodeID primitive.ObjectID) error
 }
 
 type nodeUseCase struct {
-	nodeRepo repository.NodeRepository
+	nodeRepo       repository.NodeRepository
+	credentialRepo repository.NodeCredentialRepository
+	tokenRepo      repository.BootstrapTokenRepository
+	deploymentRepo repository.DeploymentRepository
+	addonRepo      repository.AddonRepository
+	clusters       *k8s.ClusterManager
+	cipher         *encryption.Cipher
+	monitor        NodeMonitorUseCase
+	jwtSecret      string
 }
 
-func NewNodeUseCase(nodeRepo repository.NodeRepository) NodeUseCase {
+func NewNodeUseCase(
+	nodeRepo repository.NodeRepository,
+	credentialRepo repository.NodeCredentialRepository,
+	tokenRepo repository.BootstrapTokenRepository,
+	deploymentRepo repository.DeploymentRepository,
+	addonRepo repository.AddonRepository,
+	clusters *k8s.ClusterManager,
+	cipher *encryption.Cipher,
+	monitor NodeMonitorUseCase,
+	jwtSecret string,
+) NodeUseCase {
 	return &nodeUseCase{
-		nodeRepo: nodeRepo,
+		nodeRepo:       nodeRepo,
+		credentialRepo: credentialRepo,
+		tokenRepo:      tokenRepo,
+		deploymentRepo: deploymentRepo,
+		addonRepo:      addonRepo,
+		clusters:       clusters,
+		cipher:         cipher,
+		monitor:        monitor,
+		jwtSecret:      jwtSecret,
 	}
 }
 
-func (uc *nodeUseCase) RegisterNode(ctx context.Context, req *entities.NodeRegistrationRequest) (*entities.Node, error) {
-	// Check if node with same MAC address already exists
-	existingNode, err := uc.nodeRepo.GetByMacAddress(ctx, req.MacAddress)
+// RegisterNode refreshes an enrolled node's record with what its agent reports
+func (uc *nodeUseCase) RegisterNode(ctx context.Context, nodeID primitive.ObjectID, req *entities.NodeRegistrationRequest) (*entities.Node, error) {
+	node, err := uc.GetNode(ctx, nodeID)
 	if err != nil {
 		return nil, err
 	}
+	if err := uc.refreshNode(ctx, node, req); err != nil {
+		return nil, err
+	}
+	return uc.nodeRepo.GetByID(ctx, nodeID)
+}
 
-	if existingNode != nil {
-		// Update existing node
-		update := &entities.NodeUpdateRequest{
-			Status:      entities.NodeStatusOnline,
-			Location:    &req.Location,
-			Resources:   &req.Resources,
-			ClusterInfo: &req.ClusterInfo,
-		}
-		if err := uc.nodeRepo.Update(ctx, existingNode.ID, update); err != nil {
-			return nil, err
-		}
-		return uc.nodeRepo.GetByID(ctx, existingNode.ID)
+// refreshNode updates an existing node from a registration and marks it as seen
+func (uc *nodeUseCase) refreshNode(ctx context.Context, existingNode *entities.Node, req *entities.NodeRegistrationRequest) error {
+	// Labels and tags set on the server survive re-registration without any
+	metadata := req.Metadata
+	if len(metadata.Labels) == 0 {
+		metadata.Labels = existingNode.Metadata.Labels
+	}
+	if len(metadata.Tags) == 0 {
+		metadata.Tags = existingNode.Metadata.Tags
+	}
+	location := mergeLocation(existingNode.Location, req.Location)
+
+	update := &entities.NodeUpdateRequest{
+		PublicIP:    req.PublicIP,
+		PrivateIP:   req.PrivateIP,
+		Location:    &location,
+		Resources:   &req.Resources,
+		ClusterInfo: &req.ClusterInfo,
+		Metadata:    &metadata,
+		AccessMode:  req.AccessMode,
 	}
+	if err := validateAccessMode(req.AccessMode); err != nil {
+		return err
+	}
+	if err := uc.nodeRepo.Update(ctx, existingNode.ID, update); err != nil {
+		return err
+	}
+	if req.AccessMode != "" && req.AccessMode != existingNode.AccessMode {
+		uc.clusters.Invalidate(existingNode.ID)
+	}
+	if err := uc.nodeRepo.UpdateLastSeen(ctx, existingNode.ID); err != nil {
+		return err
+	}
+	return uc.recover(ctx, existingNode, "node registered again")
+}
 
-	// Create new node
+// createNode stores a node registering for the first time
+func (uc *nodeUseCase) createNode(ctx context.Context, req *entities.NodeRegistrationRequest) (*entities.Node, error) {
 	node := &entities.Node{
 		NodeName:    req.NodeName,
 		MacAddress:  req.MacAddress,
@@ -71,6 +149,10 @@ func (uc *nodeUseCase) RegisterNode(ctx context.Context, req *entities.NodeRegis
 		ClusterInfo: req.ClusterInfo,
 		Resources:   req.Resources,
 		Metadata:    req.Metadata,
+		AccessMode:  req.AccessMode,
+	}
+	if err := validateAccessMode(req.AccessMode); err != nil {
+		return nil, err
 	}
 
 	if err := uc.nodeRepo.Create(ctx, node); err != nil {
@@ -86,7 +168,7 @@ func (uc *nodeUseCase) GetNode(ctx context.Context, id primitive.ObjectID) (*ent
 		return nil, err
 	}
 	if node == nil {
-		return nil, errors.New("node not found")
+		return nil, ErrNodeNotFound
 	}
 	return node, nil
 }
@@ -100,67 +182,187 @@ func (uc *nodeUseCase) GetAllNodes(ctx context.Context, filters map[string]inter
 }
 
 func (uc *nodeUseCase) UpdateNode(ctx context.Context, id primitive.ObjectID, req *entities.NodeUpdateRequest) error {
-	return uc.nodeRepo.Update(ctx, id, req)
+	if err := validateAccessMode(req.AccessMode); err != nil {
+		return err
+	}
+
+	// Status changes go through the monitor so deployments follow and an event is emitted
+	if req.Status != "" {
+		node, err := uc.GetNode(ctx, id)
+		if err != nil {
+			return err
+		}
+		if node.RevokedAt != nil {
+			return ErrNodeRevoked
+		}
+		if req.Status == entities.NodeStatusRevoked {
+			return errors.New("use revoke to revoke a node")
+		}
+		if err := uc.monitor.SetStatus(ctx, node, req.Status, "status set by admin"); err != nil {
+			return err
+		}
+		req.Status = ""
+	}
+	if err := uc.nodeRepo.Update(ctx, id, req); err != nil {
+		return err
+	}
+	// The next use of the node's cluster picks up a changed access mode
+	if req.AccessMode != "" {
+		uc.clusters.Invalidate(id)
+	}
+	return nil
 }
 
+// DeleteNode removes a node and its cluster credentials. A node that deployments or
+// add-ons still reference is refused: their records would otherwise resolve to no
+// cluster, and nothing could reach or clean up their objects.
 func (uc *nodeUseCase) DeleteNode(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := uc.GetNode(ctx, id); err != nil {
+		return err
+	}
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{"node_id": id})
+	if err != nil {
+		return err
+	}
+	addons, err := uc.addonRepo.GetAll(ctx, map[string]interface{}{"node_id": id})
+	if err != nil {
+		return err
+	}
+	if len(deployments) > 0 || len(addons) > 0 {
+		return fmt.Errorf("%w (%d deployments, %d add-ons)", ErrNodeInUse, len(deployments), len(addons))
+	}
+
+	if err := uc.DeleteClusterCredentials(ctx, id); err != nil {
+		return err
+	}
 	return uc.nodeRepo.Delete(ctx, id)
 }
 
-func (uc *nodeUseCase) UpdateNodeResources(ctx context.Context, id primitive.ObjectID, k8sClient *k8s.Client) error {
-	// Get cluster info and resource usage
-	clusterInfo, err := k8sClient.GetClusterInfo(ctx)
+// UpdateNodeResources reads a node's allocatable resources, pod capacity and running
+// pods from its cluster, and CPU and memory usage from metrics-server. Disk usage is
+// only measured by the agent; without it the disk is the nodes' allocatable ephemeral
+// storage, with pressure taken from the node conditions.
+//
+// The totals cover the whole cluster, so only nodes with cluster credentials of their
+// own are measured. Nodes without them share the default cluster, and stamping its
+// totals on each would count its capacity once per node; what their agents report
+// stands instead.
+func (uc *nodeUseCase) UpdateNodeResources(ctx context.Context, id primitive.ObjectID) error {
+	node, err := uc.GetNode(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to get cluster info: %w", err)
+		return err
 	}
-
-	// Get nodes to calculate resources
-	nodes, err := k8sClient.GetNodes(ctx)
+	k8sClient, err := uc.clusters.Client(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to get nodes: %w", err)
+		return err
 	}
-
-	totalCPU := int64(0)
-	totalMemory := int64(0)
-	
-	for _, node := range nodes.Items {
-		cpu := node.Status.Capacity.Cpu().Value()
-		memory := node.Status.Capacity.Memory().Value()
-		totalCPU += cpu
-		totalMemory += memory
+	if k8sClient == uc.clusters.Default() {
+		return errNoClusterOfItsOwn
 	}
 
-	pods, err := k8sClient.GetPods(ctx, "")
+	resources, err := k8sClient.NodeCapacity(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get pods: %w", err)
+		return err
+	}
+
+	// Without metrics-server the usage the agent last reported is kept
+	if cpuMillis, memoryBytes, err := k8sClient.NodeUsage(ctx); err == nil {
+		resources.CPUUsage = percentOf(cpuMillis, int64(resources.CPUCores)*1000)
+		resources.MemoryUsed = memoryBytes
+	} else {
+		resources.CPUUsage = node.Resources.CPUUsage
+		resources.MemoryUsed = node.Resources.MemoryUsed
 	}
+	resources.MemoryUsage = percentOf(resources.MemoryUsed, resources.MemoryTotal)
 
-	resources := &entities.NodeResources{
-		CPUCores:     int(totalCPU),
-		MemoryTotal:  totalMemory,
-		PodsRunning:  len(pods.Items),
-		PodsCapacity: 110 * len(nodes.Items), // Default pod capacity per node
+	if node.Resources.DiskUsed > 0 {
+		resources.DiskTotal = node.Resources.DiskTotal
+		resources.DiskUsed = node.Resources.DiskUsed
+		resources.DiskUsage = node.Resources.DiskUsage
 	}
 
-	// Try to get metrics if available
-	if k8sClient.GetMetricsClientset() != nil {
-		// TODO: Implement metrics collection
+	// Not a heartbeat, so last_seen_at is left alone
+	return uc.nodeRepo.Update(ctx, id, &entities.NodeUpdateRequest{Resources: resources})
+}
+
+// errNoClusterOfItsOwn is returned when measuring a node that runs on the default cluster
+var errNoClusterOfItsOwn = errors.New("node has no cluster credentials of its own; its resources come from its agent")
+
+// StartResourceRefresh runs RefreshResources on every tick until ctx is cancelled
+func (uc *nodeUseCase) StartResourceRefresh(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := uc.RefreshResources(ctx); err != nil {
+					log.Printf("Node resources: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// RefreshResources updates the resources of every node the server can reach through
+// credentials of its own. Nodes behind their agent report their own, and revoked nodes
+// are skipped.
+func (uc *nodeUseCase) RefreshResources(ctx context.Context) error {
+	nodes, err := uc.nodeRepo.GetAll(ctx, map[string]interface{}{})
+	if err != nil {
+		return err
 	}
 
-	return uc.nodeRepo.UpdateResources(ctx, id, resources)
+	for _, node := range nodes {
+		if node.AccessMode == entities.NodeAccessAgent || node.RevokedAt != nil {
+			continue
+		}
+		if err := uc.UpdateNodeResources(ctx, node.ID); err != nil && !errors.Is(err, errNoClusterOfItsOwn) {
+			log.Printf("Node resources: node %s: %v", node.ID.Hex(), err)
+		}
+	}
+	return nil
 }
 
 func (uc *nodeUseCase) GetNodeStats(ctx context.Context) (map[string]interface{}, error) {
 	return uc.nodeRepo.GetNodeStats(ctx)
 }
 
-func (uc *nodeUseCase) GetNodesByLocation(ctx context.Context, latitude, longitude, radiusKm float64) ([]*entities.Node, error) {
-	return uc.nodeRepo.GetNodesByLocation(ctx, latitude, longitude, radiusKm)
+// GetNodesByLocation returns the nodes within radiusKm of a point, nearest first
+func (uc *nodeUseCase) GetNodesByLocation(ctx context.Context, latitude, longitude, radiusKm float64, limit int64, filters map[string]interface{}) ([]*entities.NodeDistance, error) {
+	if err := validateCoordinates(entities.Coordinates{Latitude: latitude, Longitude: longitude}); err != nil {
+		return nil, err
+	}
+	if radiusKm < 0 {
+		return nil, errors.New("radius must not be negative")
+	}
+	return uc.nodeRepo.GetNodesByLocation(ctx, latitude, longitude, radiusKm, limit, filters)
+}
+
+// GetNodesInRegion returns the nodes inside a GeoJSON polygon, e.g. a state's outline
+func (uc *nodeUseCase) GetNodesInRegion(ctx context.Context, query *entities.NodeRegionQuery) ([]*entities.Node, error) {
+	switch query.Geometry.Type {
+	case "Polygon", "MultiPolygon":
+	default:
+		return nil, fmt.Errorf("region must be a GeoJSON Polygon or MultiPolygon, not %q", query.Geometry.Type)
+	}
+	if query.Geometry.Coordinates == nil {
+		return nil, errors.New("region has no coordinates")
+	}
+
+	filters := map[string]interface{}{}
+	if query.Status != "" {
+		filters["status"] = query.Status
+	}
+	return uc.nodeRepo.GetNodesWithin(ctx, &query.Geometry, filters)
 }
 
 func (uc *nodeUseCase) GetCurrentNodeInfo() (*entities.NodeRegistrationRequest, error) {
 	// Get MAC address
-	macAddress, err := getMacAddress()
+	macAddress, err := hostinfo.MacAddress()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get MAC address: %w", err)
 	}
@@ -172,7 +374,7 @@ func (uc *nodeUseCase) GetCurrentNodeInfo() (*entities.NodeRegistrationRequest,
 	}
 
 	// Get private IP
-	privateIP, err := getPrivateIP()
+	privateIP, err := hostinfo.PrivateIP()
 	if err != nil {
 		privateIP = "unknown"
 	}
@@ -192,12 +394,15 @@ func (uc *nodeUseCase) GetCurrentNodeInfo() (*entities.NodeRegistrationRequest,
 		Timezone:  time.Local.String(),
 	}
 
+	kernelVersion, _ := hostinfo.KernelVersion()
+
 	metadata := entities.NodeMetadata{
-		OSType:       runtime.GOOS,
-		Architecture: runtime.GOARCH,
-		Hostname:     hostname,
-		Labels:       make(map[string]string),
-		Tags:         []string{},
+		OSType:        runtime.GOOS,
+		Architecture:  runtime.GOARCH,
+		Hostname:      hostname,
+		KernelVersion: kernelVersion,
+		Labels:        make(map[string]string),
+		Tags:          []string{},
 	}
 
 	req := &entities.NodeRegistrationRequest{
@@ -213,52 +418,79 @@ func (uc *nodeUseCase) GetCurrentNodeInfo() (*entities.NodeRegistrationRequest,
 }
 
 func (uc *nodeUseCase) Heartbeat(ctx context.Context, nodeID primitive.ObjectID) error {
-	return uc.nodeRepo.UpdateLastSeen(ctx, nodeID)
-}
-
-// Helper functions
-
-func getMacAddress() (string, error) {
-	interfaces, err := net.Interfaces()
+	node, err := uc.GetNode(ctx, nodeID)
 	if err != nil {
-		return "", err
+		return err
 	}
+	if err := uc.nodeRepo.UpdateLastSeen(ctx, nodeID); err != nil {
+		return err
+	}
+	return uc.recover(ctx, node, "heartbeat received")
+}
 
-	for _, iface := range interfaces {
-		// Skip loopback and inactive interfaces
-		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
-			continue
-		}
+func (uc *nodeUseCase) GetNodeEvents(ctx context.Context, nodeID primitive.ObjectID, limit int64) ([]*entities.NodeEvent, error) {
+	return uc.monitor.GetEvents(ctx, nodeID, limit)
+}
 
-		mac := iface.HardwareAddr.String()
-		if mac != "" {
-			return mac, nil
-		}
+// mergeLocation keeps the parts of a node's location a registration leaves out. Agents
+// report 0,0 when they do not know where they are, which is not a place to move to.
+func mergeLocation(existing, reported entities.Location) entities.Location {
+	if reported.Latitude == 0 && reported.Longitude == 0 {
+		reported.Latitude, reported.Longitude = existing.Latitude, existing.Longitude
 	}
-
-	return "", errors.New("no MAC address found")
+	if reported.City == "" {
+		reported.City = existing.City
+	}
+	if reported.Country == "" {
+		reported.Country = existing.Country
+	}
+	if reported.Region == "" {
+		reported.Region = existing.Region
+	}
+	if reported.Timezone == "" {
+		reported.Timezone = existing.Timezone
+	}
+	return reported
 }
 
-func getPublicIP() (string, error) {
-	// This is a simplified version - in production, use a proper service
-	// For now, return empty string
-	return "", errors.New("public IP detection not implemented")
+func validateAccessMode(mode entities.NodeAccessMode) error {
+	switch mode {
+	case "", entities.NodeAccessDirect, entities.NodeAccessAgent:
+		return nil
+	}
+	return fmt.Errorf("unsupported access mode: %s", mode)
 }
 
-func getPrivateIP() (string, error) {
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		return "", err
+// recover brings a degraded or offline node back online once it is heard from. The
+// error status is set by admins and only they clear it.
+func (uc *nodeUseCase) recover(ctx context.Context, node *entities.Node, reason string) error {
+	switch node.Status {
+	case entities.NodeStatusDegraded, entities.NodeStatusOffline:
+		return uc.monitor.SetStatus(ctx, node, entities.NodeStatusOnline, reason)
 	}
+	return nil
+}
 
-	for _, address := range addrs {
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				return ipnet.IP.String(), nil
-			}
-		}
+// ReportResources stores resource usage measured on the node itself
+func (uc *nodeUseCase) ReportResources(ctx context.Context, nodeID primitive.ObjectID, resources *entities.NodeResources) error {
+	if _, err := uc.GetNode(ctx, nodeID); err != nil {
+		return err
 	}
+	return uc.nodeRepo.UpdateResources(ctx, nodeID, resources)
+}
 
-	return "", errors.New("no private IP found")
+// Helper functions
+
+// percentOf returns part as a percentage of whole, at most 100
+func percentOf(part, whole int64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return math.Min(float64(part)/float64(whole)*100, 100)
 }
 
+func getPublicIP() (string, error) {
+	// This is a simplified version - in production, use a proper service
+	// For now, return empty string
+	return "", errors.New("public IP detection not implemented")
+}
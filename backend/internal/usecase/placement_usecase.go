@@ -0,0 +1,364 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Weights of the parts of a node's placement score. Without a requested location the
+// proximity weight is shared out over the others.
+const (
+	proximityWeight = 0.5
+	loadWeight      = 0.3
+	spreadWeight    = 0.2
+
+	// proximityScale is the distance at which the proximity score halves
+	proximityScale = 100.0 // km
+	// maxRecordedCandidates bounds the candidates kept on a decision
+	maxRecordedCandidates = 10
+)
+
+// PlacementError is returned when no node can take a deployment; it says why each node
+// was turned down
+type PlacementError struct {
+	Rejected []entities.PlacementRejection
+}
+
+func (e *PlacementError) Error() string {
+	if len(e.Rejected) == 0 {
+		return "no node can take the deployment: no nodes are registered"
+	}
+	reasons := make([]string, 0, len(e.Rejected))
+	for _, rejection := range e.Rejected {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", rejection.NodeName, rejection.Reason))
+	}
+	return "no node can take the deployment: " + strings.Join(reasons, "; ")
+}
+
+type PlacementUseCase interface {
+	Place(ctx context.Context, req *entities.PlacementRequest, deployment *entities.Deployment) (*entities.PlacementDecision, error)
+	Filter(ctx context.Context, nodes []*entities.Node, deployment *entities.Deployment) ([]*entities.Node, []entities.PlacementRejection, error)
+}
+
+type placementUseCase struct {
+	nodeRepo       repository.NodeRepository
+	deploymentRepo repository.DeploymentRepository
+}
+
+func NewPlacementUseCase(nodeRepo repository.NodeRepository, deploymentRepo repository.DeploymentRepository) PlacementUseCase {
+	return &placementUseCase{
+		nodeRepo:       nodeRepo,
+		deploymentRepo: deploymentRepo,
+	}
+}
+
+// resourceDemand is what a deployment asks of a node across all its replicas
+type resourceDemand struct {
+	cpuMillis   int64
+	memoryBytes int64
+	pods        int
+}
+
+func demandOf(config entities.DeploymentConfig) (resourceDemand, error) {
+	demand := resourceDemand{pods: int(config.Replicas)}
+	if config.CPURequest != "" {
+		cpu, err := resource.ParseQuantity(config.CPURequest)
+		if err != nil {
+			return demand, fmt.Errorf("invalid CPU request %q: %w", config.CPURequest, err)
+		}
+		demand.cpuMillis = cpu.MilliValue() * int64(config.Replicas)
+	}
+	if config.MemoryRequest != "" {
+		memory, err := resource.ParseQuantity(config.MemoryRequest)
+		if err != nil {
+			return demand, fmt.Errorf("invalid memory request %q: %w", config.MemoryRequest, err)
+		}
+		demand.memoryBytes = memory.Value() * int64(config.Replicas)
+	}
+	return demand, nil
+}
+
+func (d *resourceDemand) add(other resourceDemand) {
+	d.cpuMillis += other.cpuMillis
+	d.memoryBytes += other.memoryBytes
+	d.pods += other.pods
+}
+
+// nodeLoad is what a node already carries: its reported usage plus deployments that are
+// placed on it but not running yet, which the report does not show
+type nodeLoad struct {
+	reserved    resourceDemand
+	deployments int
+}
+
+// Place picks the node for a deployment: online nodes with room for its requests that
+// match the requested architecture and labels are scored by proximity, load after
+// placement and the number of deployments they already run
+func (uc *placementUseCase) Place(ctx context.Context, req *entities.PlacementRequest, deployment *entities.Deployment) (*entities.PlacementDecision, error) {
+	if req == nil {
+		req = &entities.PlacementRequest{}
+	}
+	if req.Near != nil {
+		if err := validateCoordinates(*req.Near); err != nil {
+			return nil, err
+		}
+	}
+	demand, err := demandOf(deployment.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := uc.nodeRepo.GetAll(ctx, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	loads, err := uc.loads(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &entities.PlacementDecision{
+		Request:    *req,
+		Candidates: []entities.PlacementCandidate{},
+		DecidedAt:  time.Now(),
+	}
+	for _, node := range nodes {
+		load := loads[node.ID]
+		if reason := uc.reject(node, req, deployment, demand, load); reason != "" {
+			decision.Rejected = append(decision.Rejected, entities.PlacementRejection{
+				NodeID:   node.ID,
+				NodeName: node.NodeName,
+				Reason:   reason,
+			})
+			continue
+		}
+		decision.Candidates = append(decision.Candidates, score(node, req, demand, load))
+	}
+
+	if len(decision.Candidates) == 0 {
+		return nil, &PlacementError{Rejected: decision.Rejected}
+	}
+
+	sort.SliceStable(decision.Candidates, func(i, j int) bool {
+		return decision.Candidates[i].Score > decision.Candidates[j].Score
+	})
+	best := decision.Candidates[0]
+	decision.NodeID = best.NodeID
+	decision.NodeName = best.NodeName
+	decision.Score = best.Score
+	decision.Reason = explain(best, len(decision.Candidates), loads[best.NodeID])
+	if len(decision.Candidates) > maxRecordedCandidates {
+		decision.Candidates = decision.Candidates[:maxRecordedCandidates]
+	}
+
+	return decision, nil
+}
+
+// Filter keeps the nodes that could take the deployment, in their order, and says why
+// the others cannot
+func (uc *placementUseCase) Filter(ctx context.Context, nodes []*entities.Node, deployment *entities.Deployment) ([]*entities.Node, []entities.PlacementRejection, error) {
+	demand, err := demandOf(deployment.Configuration)
+	if err != nil {
+		return nil, nil, err
+	}
+	loads, err := uc.loads(ctx, nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eligible := []*entities.Node{}
+	rejected := []entities.PlacementRejection{}
+	for _, node := range nodes {
+		if reason := uc.reject(node, &entities.PlacementRequest{}, deployment, demand, loads[node.ID]); reason != "" {
+			rejected = append(rejected, entities.PlacementRejection{
+				NodeID:   node.ID,
+				NodeName: node.NodeName,
+				Reason:   reason,
+			})
+			continue
+		}
+		eligible = append(eligible, node)
+	}
+	return eligible, rejected, nil
+}
+
+// loads sums up the deployments each node runs
+func (uc *placementUseCase) loads(ctx context.Context, nodes []*entities.Node) (map[primitive.ObjectID]*nodeLoad, error) {
+	loads := make(map[primitive.ObjectID]*nodeLoad, len(nodes))
+	ids := make(bson.A, 0, len(nodes))
+	for _, node := range nodes {
+		loads[node.ID] = &nodeLoad{}
+		ids = append(ids, node.ID)
+	}
+
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{
+		"node_id": bson.M{"$in": ids},
+		"status":  bson.M{"$nin": bson.A{entities.DeploymentStatusFailed, entities.DeploymentStatusDeleting}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, deployment := range deployments {
+		load, ok := loads[deployment.NodeID]
+		if !ok {
+			continue
+		}
+		load.deployments++
+
+		switch deployment.Status {
+		case entities.DeploymentStatusPending, entities.DeploymentStatusBuilding, entities.DeploymentStatusDeploying:
+			// Malformed requests of old deployments are left out rather than failing placement
+			if demand, err := demandOf(deployment.Configuration); err == nil {
+				load.reserved.add(demand)
+			}
+		}
+	}
+	return loads, nil
+}
+
+// reject returns why a node cannot take the deployment, or "" if it can
+func (uc *placementUseCase) reject(node *entities.Node, req *entities.PlacementRequest, deployment *entities.Deployment, demand resourceDemand, load *nodeLoad) string {
+	if node.Status != entities.NodeStatusOnline {
+		return fmt.Sprintf("node is %s", node.Status)
+	}
+	for _, excluded := range req.ExcludeNodes {
+		if node.ID == excluded {
+			return "node shares the cluster of the deployment being replaced"
+		}
+	}
+	if req.Architecture != "" && node.Metadata.Architecture != req.Architecture {
+		return fmt.Sprintf("architecture is %s, not %s", orUnknown(node.Metadata.Architecture), req.Architecture)
+	}
+	for key, value := range req.NodeSelector {
+		actual, ok := node.Metadata.Labels[key]
+		if !ok {
+			return fmt.Sprintf("label %s is not set", key)
+		}
+		if actual != value {
+			return fmt.Sprintf("label %s is %s, not %s", key, actual, value)
+		}
+	}
+	if node.AccessMode == entities.NodeAccessAgent && !agentSupports(deployment.Source.Type) {
+		return fmt.Sprintf("node is reached through its agent, which cannot run %s deployments", deployment.Source.Type)
+	}
+
+	resources := node.Resources
+	if resources.CPUCores == 0 || resources.MemoryTotal == 0 {
+		return "node has not reported its resources"
+	}
+	if resources.DiskPressure {
+		return "node is under disk pressure"
+	}
+	if free := freeCPUMillis(resources) - load.reserved.cpuMillis; demand.cpuMillis > free {
+		return fmt.Sprintf("needs %s CPU, %s free", formatCPU(demand.cpuMillis), formatCPU(max(free, 0)))
+	}
+	if free := resources.MemoryTotal - resources.MemoryUsed - load.reserved.memoryBytes; demand.memoryBytes > free {
+		return fmt.Sprintf("needs %s memory, %s free", formatBytes(demand.memoryBytes), formatBytes(max(free, 0)))
+	}
+	if resources.PodsCapacity > 0 {
+		if free := resources.PodsCapacity - resources.PodsRunning - load.reserved.pods; demand.pods > free {
+			return fmt.Sprintf("needs room for %d pods, %d free", demand.pods, max(free, 0))
+		}
+	}
+	return ""
+}
+
+// score rates an eligible node; every part and the total are between 0 and 1
+func score(node *entities.Node, req *entities.PlacementRequest, demand resourceDemand, load *nodeLoad) entities.PlacementCandidate {
+	resources := node.Resources
+	cpuTotal := float64(resources.CPUCores * 1000)
+	cpuAfter := (cpuTotal - float64(freeCPUMillis(resources)) + float64(load.reserved.cpuMillis+demand.cpuMillis)) / cpuTotal
+	memoryAfter := float64(resources.MemoryUsed+load.reserved.memoryBytes+demand.memoryBytes) / float64(resources.MemoryTotal)
+
+	candidate := entities.PlacementCandidate{
+		NodeID:   node.ID,
+		NodeName: node.NodeName,
+		Load:     clamp(1 - math.Max(cpuAfter, memoryAfter)),
+		Spread:   1 / float64(1+load.deployments),
+	}
+
+	weights := [3]float64{0, loadWeight, spreadWeight}
+	if req.Near != nil {
+		distance := distanceKm(*req.Near, entities.Coordinates{
+			Latitude:  node.Location.Latitude,
+			Longitude: node.Location.Longitude,
+		})
+		candidate.DistanceKm = &distance
+		candidate.Proximity = proximityScale / (proximityScale + distance)
+		weights[0] = proximityWeight
+	}
+
+	total := weights[0] + weights[1] + weights[2]
+	candidate.Score = (weights[0]*candidate.Proximity + weights[1]*candidate.Load + weights[2]*candidate.Spread) / total
+	return candidate
+}
+
+// explain describes why the best candidate won
+func explain(best entities.PlacementCandidate, eligible int, load *nodeLoad) string {
+	parts := []string{}
+	if best.DistanceKm != nil {
+		parts = append(parts, fmt.Sprintf("%.0f km from the requested location", *best.DistanceKm))
+	}
+	parts = append(parts, fmt.Sprintf("%.0f%% of CPU or memory in use after placement", (1-best.Load)*100))
+	parts = append(parts, fmt.Sprintf("%d deployments already on the node", load.deployments))
+
+	return fmt.Sprintf("highest score (%.2f) of %d eligible nodes: %s", best.Score, eligible, strings.Join(parts, ", "))
+}
+
+// freeCPUMillis estimates the CPU a node has left from its reported usage
+func freeCPUMillis(resources entities.NodeResources) int64 {
+	total := float64(resources.CPUCores * 1000)
+	return int64(total * (1 - resources.CPUUsage/100))
+}
+
+// distanceKm is the great-circle distance between two points
+func distanceKm(a, b entities.Coordinates) float64 {
+	const earthRadiusKm = 6371.0
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func validateCoordinates(point entities.Coordinates) error {
+	if point.Latitude < -90 || point.Latitude > 90 {
+		return fmt.Errorf("latitude %g is out of range", point.Latitude)
+	}
+	if point.Longitude < -180 || point.Longitude > 180 {
+		return fmt.Errorf("longitude %g is out of range", point.Longitude)
+	}
+	return nil
+}
+
+func formatCPU(millis int64) string {
+	return resource.NewMilliQuantity(millis, resource.DecimalSI).String()
+}
+
+func formatBytes(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
+
+func clamp(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
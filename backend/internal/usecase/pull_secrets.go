@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/registry"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/encryption"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// syncPullSecrets writes a pull Secret for each registry the deployment's image comes
+// from that its owner has credentials for, and sets the pod's imagePullSecrets to those
+// plus any given explicitly
+func (uc *deploymentUseCase) syncPullSecrets(ctx context.Context, deployment *entities.Deployment) error {
+	secrets := append([]string{}, deployment.ConfigOverrides.ImagePullSecrets...)
+
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return err
+	}
+	for _, host := range imageRegistries(deployment.Configuration) {
+		credential, err := uc.credentialRepo.GetByRegistry(ctx, deployment.UserID, host)
+		if err != nil {
+			return err
+		}
+		if credential == nil {
+			continue
+		}
+
+		config, err := dockerConfig(uc.cipher, credential)
+		if err != nil {
+			return err
+		}
+		name := pullSecretName(credential)
+		if err := k8sClient.ApplyPullSecret(ctx, deployment, name, credential.ID.Hex(), config); err != nil {
+			return err
+		}
+		if !contains(secrets, name) {
+			secrets = append(secrets, name)
+		}
+	}
+
+	deployment.Configuration.ImagePullSecrets = secrets
+	return nil
+}
+
+// registryCredentials returns a user's decrypted credentials for a registry, or nil
+// when they have none
+func (uc *deploymentUseCase) registryCredentials(ctx context.Context, userID primitive.ObjectID, host string) (*registry.Credentials, error) {
+	credential, err := uc.credentialRepo.GetByRegistry(ctx, userID, host)
+	if err != nil || credential == nil {
+		return nil, err
+	}
+	return decryptCredential(uc.cipher, credential)
+}
+
+// imageRegistries returns the registries a deployment's image may be pulled from
+func imageRegistries(config entities.DeploymentConfig) []string {
+	hosts := []string{}
+	if ref, err := registry.ParseReference(config.BuildConfig.ImageName); err == nil {
+		hosts = append(hosts, ref.Registry)
+	}
+	if host := registry.NormalizeHost(config.BuildConfig.RegistryURL); host != "" && !contains(hosts, host) {
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// pullSecretName is the Secret a registry credential is synced to in each namespace
+func pullSecretName(credential *entities.RegistryCredential) string {
+	return "espaze-registry-" + credential.ID.Hex()
+}
+
+func decryptCredential(cipher *encryption.Cipher, credential *entities.RegistryCredential) (*registry.Credentials, error) {
+	password, err := cipher.Decrypt(credential.Password)
+	if err != nil {
+		return nil, err
+	}
+	return &registry.Credentials{Username: credential.Username, Password: password}, nil
+}
+
+// dockerConfig decrypts a credential into the contents of its pull Secret
+func dockerConfig(cipher *encryption.Cipher, credential *entities.RegistryCredential) ([]byte, error) {
+	creds, err := decryptCredential(cipher, credential)
+	if err != nil {
+		return nil, err
+	}
+	return registry.DockerConfigJSON(credential.Registry, *creds)
+}
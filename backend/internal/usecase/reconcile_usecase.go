@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReconcileUseCase interface {
+	Start(ctx context.Context, interval time.Duration)
+	ReconcileAll(ctx context.Context) error
+	CheckDeployment(ctx context.Context, id primitive.ObjectID) (*entities.DriftReport, error)
+	RepairDeployment(ctx context.Context, id primitive.ObjectID) (*entities.DriftReport, error)
+	GetDriftedDeployments(ctx context.Context) ([]*entities.Deployment, error)
+}
+
+type reconcileUseCase struct {
+	deploymentRepo repository.DeploymentRepository
+	nodeRepo       repository.NodeRepository
+	clusters       *k8s.ClusterManager
+}
+
+func NewReconcileUseCase(deploymentRepo repository.DeploymentRepository, nodeRepo repository.NodeRepository, clusters *k8s.ClusterManager) ReconcileUseCase {
+	return &reconcileUseCase{
+		deploymentRepo: deploymentRepo,
+		nodeRepo:       nodeRepo,
+		clusters:       clusters,
+	}
+}
+
+// Start runs ReconcileAll on every tick until ctx is cancelled
+func (uc *reconcileUseCase) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := uc.ReconcileAll(ctx); err != nil {
+					log.Printf("Reconciler: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// ReconcileAll checks every running deployment, and those whose workload went missing,
+// and applies its reconcile policy. Deployments on nodes behind their agent are left
+// out; the server cannot reach their cluster.
+func (uc *reconcileUseCase) ReconcileAll(ctx context.Context) error {
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{
+		"status": bson.M{"$in": bson.A{entities.DeploymentStatusRunning, entities.DeploymentStatusMissing}},
+	})
+	if err != nil {
+		return err
+	}
+	agentManaged, err := agentNodes(ctx, uc.nodeRepo)
+	if err != nil {
+		return err
+	}
+
+	for _, deployment := range deployments {
+		if deployment.Configuration.ReconcilePolicy == entities.ReconcilePolicyOff || agentManaged[deployment.NodeID] {
+			continue
+		}
+		// Objects from charts and repository manifests are not rendered by the deployer
+		if !rendersObjects(deployment.Source) {
+			continue
+		}
+
+		repair := deployment.Configuration.ReconcilePolicy == entities.ReconcilePolicyAutoRepair
+		if _, err := uc.reconcile(ctx, deployment, repair); err != nil {
+			log.Printf("Reconciler: deployment %s: %v", deployment.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+func (uc *reconcileUseCase) CheckDeployment(ctx context.Context, id primitive.ObjectID) (*entities.DriftReport, error) {
+	deployment, err := uc.getDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return uc.reconcile(ctx, deployment, false)
+}
+
+func (uc *reconcileUseCase) RepairDeployment(ctx context.Context, id primitive.ObjectID) (*entities.DriftReport, error) {
+	deployment, err := uc.getDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return uc.reconcile(ctx, deployment, true)
+}
+
+func (uc *reconcileUseCase) GetDriftedDeployments(ctx context.Context) ([]*entities.Deployment, error) {
+	return uc.deploymentRepo.GetAll(ctx, map[string]interface{}{"drift.drifted": true})
+}
+
+// reconcile detects drift, optionally repairs it, and stores the resulting report
+func (uc *reconcileUseCase) reconcile(ctx context.Context, deployment *entities.Deployment, repair bool) (*entities.DriftReport, error) {
+	if !rendersObjects(deployment.Source) {
+		return nil, fmt.Errorf("drift detection is not supported for %s deployments", deployment.Source.Type)
+	}
+	if deployment.KubernetesInfo.DeploymentName == "" {
+		return nil, errors.New("deployment has not been rolled out yet")
+	}
+
+	k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := k8sClient.DetectDrift(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &entities.DriftReport{
+		Drifted:   len(items) > 0,
+		Items:     items,
+		CheckedAt: time.Now(),
+	}
+
+	update := map[string]interface{}{}
+
+	if report.Drifted && repair {
+		if err := k8sClient.ReconcileApplication(ctx, deployment); err != nil {
+			report.Error = err.Error()
+		} else {
+			report.Drifted = false
+			report.Repaired = true
+			report.RepairedAt = time.Now()
+		}
+	}
+
+	// A running record whose workload is gone is no longer running, but it is checked
+	// again, and runs again once the workload is back or repaired
+	missing := report.Drifted && workloadMissing(deployment, items)
+	switch {
+	case missing && deployment.Status == entities.DeploymentStatusRunning:
+		update["status"] = entities.DeploymentStatusMissing
+	case !missing && deployment.Status == entities.DeploymentStatusMissing:
+		update["status"] = entities.DeploymentStatusRunning
+	}
+
+	update["drift"] = report
+	if err := uc.deploymentRepo.Update(ctx, deployment.ID, update); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (uc *reconcileUseCase) getDeployment(ctx context.Context, id primitive.ObjectID) (*entities.Deployment, error) {
+	deployment, err := uc.deploymentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deployment == nil {
+		return nil, errors.New("deployment not found")
+	}
+	return deployment, nil
+}
+
+func workloadMissing(deployment *entities.Deployment, items []entities.DriftItem) bool {
+	for _, item := range items {
+		if item.Type != entities.DriftTypeMissing {
+			continue
+		}
+		if item.Object == "Deployment/"+deployment.KubernetesInfo.DeploymentName ||
+			item.Object == "StatefulSet/"+deployment.KubernetesInfo.DeploymentName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/espazeindia/espazeNodeDeployer/internal/domain/entities"
+	"github.com/espazeindia/espazeNodeDeployer/internal/k8s"
+	"github.com/espazeindia/espazeNodeDeployer/internal/registry"
+	"github.com/espazeindia/espazeNodeDeployer/internal/repository"
+	"github.com/espazeindia/espazeNodeDeployer/pkg/encryption"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrRegistryCredentialNotFound is returned for credentials that do not exist or belong to another user
+var ErrRegistryCredentialNotFound = errors.New("registry credential not found")
+
+type RegistryCredentialUseCase interface {
+	CreateCredential(ctx context.Context, userID primitive.ObjectID, req *entities.RegistryCredentialRequest) (*entities.RegistryCredential, error)
+	GetCredentials(ctx context.Context, userID primitive.ObjectID) ([]*entities.RegistryCredential, error)
+	UpdateCredential(ctx context.Context, userID, id primitive.ObjectID, req *entities.RegistryCredentialRequest) (*entities.RegistryCredential, error)
+	DeleteCredential(ctx context.Context, userID, id primitive.ObjectID) error
+	VerifyCredential(ctx context.Context, userID, id primitive.ObjectID) (*entities.RegistryCredential, error)
+}
+
+type registryCredentialUseCase struct {
+	credentialRepo repository.RegistryCredentialRepository
+	deploymentRepo repository.DeploymentRepository
+	clusters       *k8s.ClusterManager
+	registryClient *registry.Client
+	cipher         *encryption.Cipher
+}
+
+func NewRegistryCredentialUseCase(
+	credentialRepo repository.RegistryCredentialRepository,
+	deploymentRepo repository.DeploymentRepository,
+	clusters *k8s.ClusterManager,
+	registryClient *registry.Client,
+	cipher *encryption.Cipher,
+) RegistryCredentialUseCase {
+	return &registryCredentialUseCase{
+		credentialRepo: credentialRepo,
+		deploymentRepo: deploymentRepo,
+		clusters:       clusters,
+		registryClient: registryClient,
+		cipher:         cipher,
+	}
+}
+
+func (uc *registryCredentialUseCase) CreateCredential(ctx context.Context, userID primitive.ObjectID, req *entities.RegistryCredentialRequest) (*entities.RegistryCredential, error) {
+	host := registry.NormalizeHost(req.Registry)
+	if host == "" || req.Username == "" || req.Password == "" {
+		return nil, errors.New("registry, username and password are required")
+	}
+
+	existing, err := uc.credentialRepo.GetByRegistry(ctx, userID, host)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("credentials for %s already exist", host)
+	}
+
+	password, err := uc.cipher.Encrypt(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &entities.RegistryCredential{
+		UserID:   userID,
+		Name:     req.Name,
+		Registry: host,
+		Username: req.Username,
+		Password: password,
+	}
+	if credential.Name == "" {
+		credential.Name = host
+	}
+
+	if err := uc.credentialRepo.Create(ctx, credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (uc *registryCredentialUseCase) GetCredentials(ctx context.Context, userID primitive.ObjectID) ([]*entities.RegistryCredential, error) {
+	return uc.credentialRepo.GetByUserID(ctx, userID)
+}
+
+// UpdateCredential changes a credential and rewrites every pull Secret it was synced to
+func (uc *registryCredentialUseCase) UpdateCredential(ctx context.Context, userID, id primitive.ObjectID, req *entities.RegistryCredentialRequest) (*entities.RegistryCredential, error) {
+	credential, err := uc.getCredential(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	update := map[string]interface{}{}
+	if req.Name != "" {
+		update["name"] = req.Name
+		credential.Name = req.Name
+	}
+	if req.Username != "" {
+		update["username"] = req.Username
+		credential.Username = req.Username
+	}
+	if req.Password != "" {
+		password, err := uc.cipher.Encrypt(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		update["password"] = password
+		credential.Password = password
+	}
+	if host := registry.NormalizeHost(req.Registry); host != "" && host != credential.Registry {
+		existing, err := uc.credentialRepo.GetByRegistry(ctx, userID, host)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("credentials for %s already exist", host)
+		}
+		update["registry"] = host
+		credential.Registry = host
+	}
+
+	// Changed credentials have not been checked yet
+	if req.Username != "" || req.Password != "" || update["registry"] != nil {
+		update["verified_at"] = nil
+		credential.VerifiedAt = nil
+	}
+
+	if err := uc.credentialRepo.Update(ctx, id, update); err != nil {
+		return nil, err
+	}
+
+	if err := uc.resyncPullSecrets(ctx, credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// DeleteCredential removes a credential, its pull Secrets and their references from
+// deployment configurations
+func (uc *registryCredentialUseCase) DeleteCredential(ctx context.Context, userID, id primitive.ObjectID) error {
+	credential, err := uc.getCredential(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	name := pullSecretName(credential)
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{
+		"user_id":                          userID,
+		"configuration.image_pull_secrets": name,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, deployment := range deployments {
+		secrets := []string{}
+		for _, secret := range deployment.Configuration.ImagePullSecrets {
+			if secret != name {
+				secrets = append(secrets, secret)
+			}
+		}
+		if err := uc.deploymentRepo.Update(ctx, deployment.ID, map[string]interface{}{
+			"configuration.image_pull_secrets": secrets,
+		}); err != nil {
+			return err
+		}
+		k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+		if err != nil {
+			log.Printf("Registry credential %s: %v", id.Hex(), err)
+			continue
+		}
+		if err := k8sClient.DeletePullSecret(ctx, deployment.Namespace, name); err != nil {
+			log.Printf("Registry credential %s: %v", id.Hex(), err)
+		}
+	}
+
+	return uc.credentialRepo.Delete(ctx, id)
+}
+
+// VerifyCredential logs in to the registry with a credential and records when it last worked
+func (uc *registryCredentialUseCase) VerifyCredential(ctx context.Context, userID, id primitive.ObjectID) (*entities.RegistryCredential, error) {
+	credential, err := uc.getCredential(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := decryptCredential(uc.cipher, credential)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.registryClient.CheckCredentials(ctx, credential.Registry, creds); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := uc.credentialRepo.Update(ctx, id, map[string]interface{}{"verified_at": now}); err != nil {
+		return nil, err
+	}
+	credential.VerifiedAt = &now
+	return credential, nil
+}
+
+// resyncPullSecrets rewrites the pull Secret of a credential in every namespace it was synced to
+func (uc *registryCredentialUseCase) resyncPullSecrets(ctx context.Context, credential *entities.RegistryCredential) error {
+	deployments, err := uc.deploymentRepo.GetAll(ctx, map[string]interface{}{
+		"user_id":                          credential.UserID,
+		"configuration.image_pull_secrets": pullSecretName(credential),
+	})
+	if err != nil {
+		return err
+	}
+	if len(deployments) == 0 {
+		return nil
+	}
+
+	config, err := dockerConfig(uc.cipher, credential)
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments {
+		k8sClient, err := uc.clusters.Client(ctx, deployment.NodeID)
+		if err != nil {
+			return err
+		}
+		if err := k8sClient.ApplyPullSecret(ctx, deployment, pullSecretName(credential), credential.ID.Hex(), config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uc *registryCredentialUseCase) getCredential(ctx context.Context, userID, id primitive.ObjectID) (*entities.RegistryCredential, error) {
+	credential, err := uc.credentialRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil || credential.UserID != userID {
+		return nil, ErrRegistryCredentialNotFound
+	}
+	return credential, nil
+}
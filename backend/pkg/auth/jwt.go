@@ -42,7 +42,8 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Node credentials are signed with the same secret but carry no user
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.UserID != "" {
 		return claims, nil
 	}
 
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// nodeAudience keeps node credentials and user tokens from being accepted in place of
+// each other
+const nodeAudience = "espaze-node"
+
+// NodeClaims identify an enrolled node. Generation must match the node's current
+// credential generation, so bumping it revokes every credential issued before.
+type NodeClaims struct {
+	NodeID     string `json:"nodeId"`
+	Generation int    `json:"generation"`
+	jwt.RegisteredClaims
+}
+
+// GenerateNodeToken issues a node credential. It has no expiry; it stays valid until the
+// node is enrolled again or revoked.
+func GenerateNodeToken(nodeID string, generation int, secret string) (string, error) {
+	claims := NodeClaims{
+		NodeID:     nodeID,
+		Generation: generation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   nodeID,
+			Audience:  jwt.ClaimStrings{nodeAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+func ValidateNodeToken(tokenString, secret string) (*NodeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &NodeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(secret), nil
+	}, jwt.WithAudience(nodeAudience))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*NodeClaims); ok && token.Valid && claims.NodeID != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+// GenerateSecret returns a random URL-safe secret with the given prefix
+func GenerateSecret(prefix string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashSecret returns the SHA-256 of a secret, for storing it without keeping the secret
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
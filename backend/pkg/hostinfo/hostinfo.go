@@ -0,0 +1,94 @@
+// Package hostinfo inspects the machine the process runs on
+package hostinfo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrUnsupported is returned for measurements not implemented on this OS
+var ErrUnsupported = errors.New("not supported on this operating system")
+
+// MacAddress returns the hardware address of the first active, non-loopback interface
+func MacAddress() (string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	for _, iface := range interfaces {
+		// Skip loopback and inactive interfaces
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		mac := iface.HardwareAddr.String()
+		if mac != "" {
+			return mac, nil
+		}
+	}
+
+	return "", errors.New("no MAC address found")
+}
+
+// PrivateIP returns the first non-loopback IPv4 address
+func PrivateIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, address := range addrs {
+		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				return ipnet.IP.String(), nil
+			}
+		}
+	}
+
+	return "", errors.New("no private IP found")
+}
+
+// PublicIP asks an echo service such as https://api.ipify.org for the address the
+// machine reaches the internet from
+func PublicIP(ctx context.Context, echoURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, echoURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if resp.StatusCode != http.StatusOK || ip == nil {
+		return "", errors.New("public IP service returned no address")
+	}
+	return ip.String(), nil
+}
+
+// Usage is the share of a resource in use
+type Usage struct {
+	Total int64   // Bytes
+	Used  int64   // Bytes
+	Usage float64 // Percentage
+}
+
+func newUsage(total, used int64) Usage {
+	usage := Usage{Total: total, Used: used}
+	if total > 0 {
+		usage.Usage = float64(used) / float64(total) * 100
+	}
+	return usage
+}
@@ -0,0 +1,130 @@
+//go:build linux
+
+package hostinfo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// KernelVersion returns the running kernel release
+func KernelVersion() (string, error) {
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(release)), nil
+}
+
+// Memory reports physical memory, counting reclaimable caches as free
+func Memory() (Usage, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return Usage{}, err
+	}
+	defer file.Close()
+
+	values := map[string]int64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// e.g. "MemTotal:       16318480 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = kb * 1024
+	}
+	if err := scanner.Err(); err != nil {
+		return Usage{}, err
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return Usage{}, fmt.Errorf("MemTotal missing from /proc/meminfo")
+	}
+	available, ok := values["MemAvailable"]
+	if !ok {
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return newUsage(total, total-available), nil
+}
+
+// Disk reports the filesystem that path is on
+func Disk(path string) (Usage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return Usage{}, err
+	}
+
+	total := int64(stat.Blocks) * int64(stat.Bsize)
+	free := int64(stat.Bfree) * int64(stat.Bsize)
+	return newUsage(total, total-free), nil
+}
+
+// CPUSampler measures CPU usage between successive calls
+type CPUSampler struct {
+	mu    sync.Mutex
+	idle  uint64
+	total uint64
+}
+
+// Usage returns the percentage of CPU time spent busy since the previous call, or since
+// boot on the first
+func (s *CPUSampler) Usage() (float64, error) {
+	idle, total, err := cpuTimes()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idleDelta, totalDelta := idle-s.idle, total-s.total
+	s.idle, s.total = idle, total
+	if totalDelta == 0 {
+		return 0, nil
+	}
+	return float64(totalDelta-idleDelta) / float64(totalDelta) * 100, nil
+}
+
+// cpuTimes reads the aggregate jiffies from the "cpu" line of /proc/stat
+func cpuTimes() (idle, total uint64, err error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			total += value
+			// idle and iowait
+			if i == 3 || i == 4 {
+				idle += value
+			}
+		}
+		return idle, total, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, fmt.Errorf("cpu line missing from /proc/stat")
+}
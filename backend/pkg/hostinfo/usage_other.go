@@ -0,0 +1,35 @@
+//go:build !linux
+
+package hostinfo
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// KernelVersion returns the running kernel release
+func KernelVersion() (string, error) {
+	out, err := exec.Command("uname", "-r").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Memory reports physical memory
+func Memory() (Usage, error) {
+	return Usage{}, ErrUnsupported
+}
+
+// Disk reports the filesystem that path is on
+func Disk(path string) (Usage, error) {
+	return Usage{}, ErrUnsupported
+}
+
+// CPUSampler measures CPU usage between successive calls
+type CPUSampler struct{}
+
+// Usage returns the percentage of CPU time spent busy since the previous call
+func (s *CPUSampler) Usage() (float64, error) {
+	return 0, ErrUnsupported
+}